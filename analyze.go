@@ -0,0 +1,282 @@
+package gosql
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// ParamInfo 模板引用的参数信息
+type ParamInfo struct {
+	Name     string `json:"name"`
+	Raw      bool   `json:"raw,omitempty"`      // 是否以 @= 直接输出（不参数化）
+	Optional bool   `json:"optional,omitempty"` // 是否只出现在条件控制（@x?）中
+	Type     string `json:"type,omitempty"`     // 模板声明的类型（param: 元数据），没有声明时为空
+}
+
+// templateRefs 模板中引用的参数、函数、define 和 use
+type templateRefs struct {
+	params  []ParamInfo
+	index   map[string]int  // 参数名 -> params 下标
+	calls   map[string]bool // 表达式中调用的函数名
+	locals  map[string]bool // for 循环等在模板内声明的变量
+	defines []string        // define 完整路径（嵌套用 . 连接）
+	uses    []string        // @use 引用的路径
+	imports []string        // @import 引用的路径
+	flags   []string        // @flag 使用的开关名称
+	dynamic bool            // 存在无法静态分析的表达式或代码（调用了哪些函数未知）
+}
+
+// analyzeTemplate 静态分析模板 AST，收集引用信息
+func analyzeTemplate(nodes []Node) *templateRefs {
+	refs := &templateRefs{
+		index:  make(map[string]int),
+		calls:  make(map[string]bool),
+		locals: make(map[string]bool),
+	}
+	refs.walk(nodes, "")
+
+	// 去掉模板内声明的局部变量
+	params := refs.params[:0]
+	for _, p := range refs.params {
+		if !refs.locals[p.Name] {
+			params = append(params, p)
+		}
+	}
+	refs.params = params
+	return refs
+}
+
+// walk 遍历节点
+func (r *templateRefs) walk(nodes []Node, definePrefix string) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *VarNode:
+			r.addParam(n.Name, false, n.Conditional)
+		case *RawNode:
+			r.addParam(n.Name, true, n.Conditional)
+		case *VarExprNode:
+			r.addExpr(n.Expr, false, n.Conditional)
+		case *RowsNode:
+			// 渲染时才知道是批量插入还是函数调用：按函数调用记录（括号中的名字可能是变量），再记录变量本身
+			r.addExpr(n.Expr, false, true)
+			r.addParam(n.Name, false, false)
+		case *RawExprNode:
+			r.addExpr(n.Expr, true, n.Conditional)
+		case *ConditionalLineNode:
+			r.addExpr(n.Condition, false, true)
+			r.walk(n.LineNodes, definePrefix)
+		case *IfNode:
+			r.addExpr(n.Condition, false, false)
+			r.walk(n.Body, definePrefix)
+			for _, ei := range n.ElseIf {
+				r.addExpr(ei.Condition, false, false)
+				r.walk(ei.Body, definePrefix)
+			}
+			if n.Else != nil {
+				r.walk(n.Else.Body, definePrefix)
+			}
+		case *ForNode:
+			r.addFor(n.Expr)
+			r.walk(n.Body, definePrefix)
+		case *ForEachNode:
+			r.addExpr(n.List, false, false)
+			for _, name := range []string{n.Index, n.Item} {
+				if name != "" && name != "_" {
+					r.locals[name] = true
+				}
+			}
+			r.walk(n.Body, definePrefix)
+		case *SwitchNode:
+			r.addExpr(n.Expr, false, false)
+			for _, c := range n.Cases {
+				for _, value := range c.Values {
+					r.addExpr(value, false, false)
+				}
+				r.walk(c.Body, definePrefix)
+			}
+			if n.Default != nil {
+				r.walk(n.Default.Body, definePrefix)
+			}
+		case *SetNode:
+			r.walk(n.Body, definePrefix)
+		case *FlagNode:
+			r.flags = append(r.flags, n.Name)
+			r.walk(n.Body, definePrefix)
+			if n.Else != nil {
+				r.walk(n.Else.Body, definePrefix)
+			}
+		case *TrimNode:
+			r.walk(n.Body, definePrefix)
+		case *FuncBlockNode:
+			if fn := strings.TrimSpace(n.FuncExpr); token.IsIdentifier(fn) {
+				// @Trim { } 形式：不带括号也是函数调用
+				r.calls[fn] = true
+			} else {
+				r.addExpr(n.FuncExpr, false, false)
+			}
+			r.walk(n.Body, definePrefix)
+		case *CodeNode:
+			r.addCode(n.Code)
+		case *DirectiveNode:
+			// 自定义指令可能对任意表达式求值
+			r.dynamic = true
+			r.walk(n.Body, definePrefix)
+		case *UseNode:
+			r.uses = append(r.uses, n.Path)
+			for _, arg := range n.Args {
+				r.addExpr(arg, false, false)
+			}
+			for _, cover := range n.Covers {
+				r.walk(cover.Body, definePrefix)
+			}
+		case *ImportNode:
+			r.imports = append(r.imports, n.Path)
+		case *DefineNode:
+			path := n.Name
+			if definePrefix != "" {
+				path = definePrefix + "." + n.Name
+			}
+			r.defines = append(r.defines, path)
+			for _, p := range n.Params {
+				r.locals[p] = true
+			}
+			r.walk(n.Body, path)
+		case *CoverNode:
+			r.walk(n.Body, definePrefix)
+		}
+	}
+}
+
+// addParam 记录参数引用
+func (r *templateRefs) addParam(name string, raw, optional bool) {
+	if name == "" {
+		return
+	}
+	if i, ok := r.index[name]; ok {
+		// 只要有一处不是条件控制，就认为是必需参数
+		r.params[i].Optional = r.params[i].Optional && optional
+		r.params[i].Raw = r.params[i].Raw || raw
+		return
+	}
+	r.index[name] = len(r.params)
+	r.params = append(r.params, ParamInfo{Name: name, Raw: raw, Optional: optional})
+}
+
+// addExpr 记录表达式中引用的变量和函数
+func (r *templateRefs) addExpr(expr string, raw, optional bool) {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		r.dynamic = true
+		return
+	}
+	r.inspect(e, raw, optional)
+}
+
+// addCode 记录代码块（@{ }）中调用的函数；代码块内的变量可能是局部变量，不计入参数
+func (r *templateRefs) addCode(code string) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+code+"\n}", 0)
+	if err != nil {
+		r.dynamic = true
+		return
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && !isPredeclared(id.Name) {
+				r.calls[id.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// addFor 记录 for 语句中引用的变量，并把循环变量记为局部变量
+func (r *templateRefs) addFor(expr string) {
+	src := "package p\nfunc _() {\nfor " + expr + " {}\n}"
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil || len(file.Decls) == 0 {
+		return
+	}
+	fn, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok || fn.Body == nil || len(fn.Body.List) == 0 {
+		return
+	}
+	switch s := fn.Body.List[0].(type) {
+	case *ast.RangeStmt:
+		if s.Tok == token.DEFINE {
+			for _, e := range []ast.Expr{s.Key, s.Value} {
+				if id, ok := e.(*ast.Ident); ok {
+					r.locals[id.Name] = true
+				}
+			}
+		}
+		r.inspect(s.X, false, false)
+	case *ast.ForStmt:
+		if init, ok := s.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+			for _, e := range init.Lhs {
+				if id, ok := e.(*ast.Ident); ok {
+					r.locals[id.Name] = true
+				}
+			}
+			for _, e := range init.Rhs {
+				r.inspect(e, false, false)
+			}
+		}
+		if s.Cond != nil {
+			r.inspect(s.Cond, false, false)
+		}
+	}
+}
+
+// inspect 收集表达式中的自由标识符与函数调用
+func (r *templateRefs) inspect(e ast.Node, raw, optional bool) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			// 只关心 a.b 中的 a
+			r.inspect(x.X, raw, optional)
+			return false
+		case *ast.CallExpr:
+			if id, ok := x.Fun.(*ast.Ident); ok {
+				if !isPredeclared(id.Name) {
+					r.calls[id.Name] = true
+				}
+			} else {
+				r.inspect(x.Fun, raw, optional)
+			}
+			for _, arg := range x.Args {
+				r.inspect(arg, raw, optional)
+			}
+			return false
+		case *ast.KeyValueExpr:
+			r.inspect(x.Value, raw, optional)
+			return false
+		case *ast.CompositeLit:
+			for _, elt := range x.Elts {
+				r.inspect(elt, raw, optional)
+			}
+			return false
+		case *ast.FuncLit:
+			return false
+		case *ast.Ident:
+			if !isPredeclared(x.Name) && x.Name != "_" {
+				r.addParam(x.Name, raw, optional)
+			}
+		}
+		return true
+	})
+}
+
+// predeclared Go 预声明标识符
+var predeclared = map[string]bool{
+	"true": true, "false": true, "nil": true, "iota": true,
+	"len": true, "cap": true, "append": true, "make": true, "new": true,
+	"string": true, "int": true, "int64": true, "float64": true, "bool": true,
+	"byte": true, "rune": true, "any": true, "error": true,
+}
+
+// isPredeclared 是否为 Go 预声明标识符
+func isPredeclared(name string) bool {
+	return predeclared[name]
+}
@@ -0,0 +1,251 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ArgValidator 自定义的参数校验函数，在渲染之前调用。返回 *ValidationError 时其中的字段错误会与
+// 内置校验的结果合并，返回其它错误时原样（附加 GOSQL021 错误码）返回
+type ArgValidator func(path string, args interface{}) error
+
+// ValidationError 渲染前的参数校验失败，Fields 列出每个缺失或不满足规则的参数
+type ValidationError struct {
+	Path   string       `json:"path"`
+	Fields []FieldError `json:"fields"`
+}
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	Param   string `json:"param"`   // 参数名（模板中引用的名字）
+	Rule    string `json:"rule"`    // 不满足的规则，如 required、min=1
+	Message string `json:"message"` // 说明
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Param + ": " + f.Message
+	}
+	return fmt.Sprintf("invalid args for %s: %s", e.Path, strings.Join(msgs, "; "))
+}
+
+// checkArgs 渲染前校验参数：
+//   - 模板无条件引用的参数（不在 @if / @for / 条件行中的 @var、@=var）必须存在（map 参数有这个 key，结构体参数有这个字段）
+//   - 结构体参数字段上的 validate 标签：required、min=N、max=N、len=N、oneof=a b c
+//     （min / max / len 对数字比较大小，对字符串、切片、map 比较长度）
+//   - WithArgValidation 注册的自定义校验函数
+func (e *Engine) checkArgs(path string, args interface{}) error {
+	verr := &ValidationError{Path: path}
+	if params, ok := e.requiredParams(path); ok {
+		for _, name := range params {
+			if !hasArg(args, name) {
+				verr.Fields = append(verr.Fields, FieldError{Param: name, Rule: "required", Message: "required by template"})
+			}
+		}
+	}
+	rv := reflect.ValueOf(args)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		verr.Fields = append(verr.Fields, checkStructTags(rv)...)
+	}
+	for _, fn := range e.validators {
+		err := fn(path, args)
+		var custom *ValidationError
+		if errors.As(err, &custom) {
+			verr.Fields = append(verr.Fields, custom.Fields...)
+		} else if err != nil {
+			return withCode(CodeValidation, err)
+		}
+	}
+	if len(verr.Fields) == 0 {
+		return nil
+	}
+	return &Error{Code: CodeValidation, Err: verr}
+}
+
+// requiredParams 返回模板（或 define）无条件引用的参数名。模板不存在，或包含 @{ } 代码块
+// （其中声明的变量无法静态分析）时 ok 为 false
+func (e *Engine) requiredParams(path string) (params []string, ok bool) {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return nil, false
+	}
+	ast, ok := e.compiledAST[parts[0]+"."+parts[1]]
+	if !ok || ast.refs == nil {
+		return nil, false
+	}
+	hasCode := false
+	walkNodes(ast.Nodes, func(node Node) {
+		if _, ok := node.(*CodeNode); ok {
+			hasCode = true
+		}
+	})
+	if hasCode {
+		return nil, false
+	}
+	nodes := ast.Nodes
+	if len(parts) > 2 {
+		define := findDefine(nodes, parts[2])
+		if define == nil {
+			return nil, false
+		}
+		nodes = define.Body
+	}
+	seen := make(map[string]bool)
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, node := range nodes {
+			var name string
+			switch n := node.(type) {
+			case *VarNode:
+				if !n.Conditional {
+					name = n.Name
+				}
+			case *RawNode:
+				if !n.Conditional {
+					name = n.Name
+				}
+			case *DefineNode:
+				walk(n.Body)
+			}
+			name, _, _ = strings.Cut(name, ".")
+			if name != "" && !seen[name] && !ast.refs.locals[name] {
+				seen[name] = true
+				params = append(params, name)
+			}
+		}
+	}
+	walk(nodes)
+	return params, true
+}
+
+// hasArg 判断参数中是否有 name：map 有这个 key，结构体有这个字段（首字母小写或原始名）。
+// 其它类型的参数（如 Scope）无法静态判断，视为存在
+func hasArg(args interface{}, name string) bool {
+	rv := reflect.ValueOf(args)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return true
+		}
+		return rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())).IsValid()
+	case reflect.Struct:
+		_, ok := rv.Type().FieldByNameFunc(func(field string) bool {
+			return field == name || toLowerFirst(field) == name
+		})
+		return ok
+	case reflect.Invalid:
+		return false
+	}
+	return true
+}
+
+// checkStructTags 按 validate 标签校验结构体字段（包括嵌入结构体的字段）
+func checkStructTags(rv reflect.Value) []FieldError {
+	var errs []FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		if field.Anonymous {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && field.IsExported() {
+				errs = append(errs, checkStructTags(fv)...)
+			}
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+			if msg := checkRule(fv, rule); msg != "" {
+				errs = append(errs, FieldError{Param: toLowerFirst(field.Name), Rule: rule, Message: msg})
+			}
+		}
+	}
+	return errs
+}
+
+// checkRule 校验单条规则，满足时返回空串。值为 nil 指针时只校验 required
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if v.IsZero() {
+			return "required"
+		}
+		return ""
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch name {
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %q", rule)
+		}
+		value, isLen, ok := measure(v)
+		if !ok {
+			return fmt.Sprintf("rule %q is not supported for %s", rule, v.Type())
+		}
+		what := "value"
+		if isLen {
+			what = "length"
+		}
+		switch {
+		case name == "min" && value < n:
+			return fmt.Sprintf("%s must be >= %s", what, arg)
+		case name == "max" && value > n:
+			return fmt.Sprintf("%s must be <= %s", what, arg)
+		case name == "len" && value != n:
+			return fmt.Sprintf("%s must be %s", what, arg)
+		}
+		return ""
+	case "oneof":
+		s := fmt.Sprint(v)
+		for _, option := range strings.Fields(arg) {
+			if s == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", arg)
+	}
+	return fmt.Sprintf("unknown rule %q", rule)
+}
+
+// measure 返回数字的值，或字符串（按字符）、切片、数组、map 的长度
+func measure(v reflect.Value) (value float64, isLen, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
@@ -0,0 +1,181 @@
+package gosql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// maxHashDepth 计算参数哈希时的最大嵌套层数（超过时视为无法哈希，也用于避免循环引用）
+const maxHashDepth = 32
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// TypeHasher 自定义类型的哈希：返回代表 v 的字节（值相同时必须相同，值不同时应当不同），
+// 返回 false 表示 v 无法哈希（包含它的参数不参与缓存）
+type TypeHasher func(v interface{}) ([]byte, bool)
+
+// ArgsHasher 计算渲染参数的哈希，pure 模板的渲染结果缓存以它为 key，也可以用在自己的缓存中：
+// 值和类型都相同的参数得到相同的哈希，map 与键的顺序无关；
+// 包含函数、通道、带未导出字段的结构体（time.Time 和注册了 TypeHasher 的类型除外）时无法哈希。
+// Register 需要在使用之前完成，之后可以并发调用 Hash
+type ArgsHasher struct {
+	types map[reflect.Type]TypeHasher
+}
+
+// NewArgsHasher 创建参数哈希计算器
+func NewArgsHasher() *ArgsHasher {
+	return &ArgsHasher{types: make(map[reflect.Type]TypeHasher)}
+}
+
+// Register 为 sample 的类型注册自定义哈希（例如带未导出字段的 decimal 类型按字符串哈希），返回 h 本身：
+//
+//	hasher := gosql.NewArgsHasher().Register(decimal.Decimal{}, func(v interface{}) ([]byte, bool) {
+//		return []byte(v.(decimal.Decimal).String()), true
+//	})
+func (h *ArgsHasher) Register(sample interface{}, fn TypeHasher) *ArgsHasher {
+	h.types[reflect.TypeOf(sample)] = fn
+	return h
+}
+
+// Hash 计算参数的哈希，参数无法哈希时返回 false
+func (h *ArgsHasher) Hash(args interface{}) ([sha256.Size]byte, bool) {
+	var sum [sha256.Size]byte
+	w := sha256.New()
+	if !h.write(w, reflect.ValueOf(args), 0) {
+		return sum, false
+	}
+	w.Sum(sum[:0])
+	return sum, true
+}
+
+// defaultArgsHasher 没有注册自定义类型的哈希计算器
+var defaultArgsHasher = NewArgsHasher()
+
+// HashArgs 用默认规则（没有自定义类型）计算参数的哈希，见 ArgsHasher
+func HashArgs(args interface{}) ([sha256.Size]byte, bool) {
+	return defaultArgsHasher.Hash(args)
+}
+
+// write 把 v 的类型和值写入 w
+func (h *ArgsHasher) write(w hash.Hash, v reflect.Value, depth int) bool {
+	if depth > maxHashDepth {
+		return false
+	}
+	if !v.IsValid() {
+		w.Write([]byte{0})
+		return true
+	}
+	t := v.Type()
+	w.Write([]byte(t.PkgPath()))
+	w.Write([]byte(t.String()))
+	w.Write([]byte{1})
+
+	var buf [8]byte
+	writeUint := func(n uint64) {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		w.Write(buf[:])
+	}
+	if fn, ok := h.types[t]; ok {
+		b, ok := fn(v.Interface())
+		if !ok {
+			return false
+		}
+		writeUint(uint64(len(b)))
+		w.Write(b)
+		return true
+	}
+	if t == timeType {
+		tm := v.Interface().(time.Time)
+		writeUint(uint64(tm.UnixNano()))
+		w.Write([]byte(tm.Location().String()))
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		writeUint(math.Float64bits(real(v.Complex())))
+		writeUint(math.Float64bits(imag(v.Complex())))
+	case reflect.String:
+		writeUint(uint64(v.Len()))
+		w.Write([]byte(v.String()))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			w.Write([]byte{0})
+			return true
+		}
+		writeUint(uint64(v.Len()))
+		if t.Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			w.Write(v.Bytes())
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !h.write(w, v.Index(i), depth+1) {
+				return false
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.Write([]byte{0})
+			return true
+		}
+		// 每个键值对单独哈希，按键的哈希排序后写入，与遍历顺序无关
+		entries := make([][2][sha256.Size]byte, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry [2][sha256.Size]byte
+			for i, x := range []reflect.Value{iter.Key(), iter.Value()} {
+				eh := sha256.New()
+				if !h.write(eh, x, depth+1) {
+					return false
+				}
+				eh.Sum(entry[i][:0])
+			}
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i][0][:], entries[j][0][:]) < 0
+		})
+		writeUint(uint64(len(entries)))
+		for _, entry := range entries {
+			w.Write(entry[0][:])
+			w.Write(entry[1][:])
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.Write([]byte{0})
+			return true
+		}
+		w.Write([]byte{1})
+		return h.write(w, v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				return false
+			}
+			if !h.write(w, v.Field(i), depth+1) {
+				return false
+			}
+		}
+	default:
+		// 函数、通道等
+		return false
+	}
+	return true
+}
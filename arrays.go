@@ -0,0 +1,61 @@
+package gosql
+
+import "strings"
+
+// ArrayParam 作为一个数组参数绑定的切片（PostgreSQL 的 = ANY(?)），由 Array 或模板内置函数 array 生成：
+//
+//	where id = ANY(@ids)         -- args: {"ids": gosql.Array(ids)}
+//	where id in (@ array(ids) @) -- 渲染为 id = ANY(?)
+type ArrayParam struct {
+	Value interface{} // 切片或数组
+}
+
+// Array 把切片标记为一个数组参数，不再展开为 ?, ?, ...
+func Array(v interface{}) ArrayParam {
+	return ArrayParam{Value: v}
+}
+
+// arrayWrap 把切片转换为驱动接受的数组参数（WithPostgresArrays）
+type arrayWrap func(interface{}) interface{}
+
+// arrayFunc 内置函数 array(v)：同 Array
+func (ctx *executionContext) arrayFunc() interface{} {
+	return func(v interface{}) ArrayParam {
+		return Array(v)
+	}
+}
+
+// arrayValue 返回要作为数组参数绑定的切片：ArrayParam，或设置了 WithPostgresArrays 时
+// 紧跟在 col in ( 之后的切片
+func (ctx *executionContext) arrayValue(value interface{}) (interface{}, bool) {
+	if a, ok := value.(ArrayParam); ok {
+		return a.Value, true
+	}
+	if ctx.engine.arrays != nil && ctx.engine.sliceLen(value) >= 0 && inListPattern.Match(ctx.sql.buf) {
+		return value, true
+	}
+	return nil, false
+}
+
+// bindArray 把切片作为一个参数绑定（经过 WithPostgresArrays 设置的转换，如 pq.Array），
+// 紧跟在 col in ( 之后时，连同之后的 ) 替换为 col = ANY(?)（not in 为 col <> ALL(?)）
+func (ctx *executionContext) bindArray(value interface{}) {
+	if ctx.engine.arrays != nil {
+		value = ctx.engine.arrays(value)
+	}
+	loc := inListPattern.FindSubmatchIndex(ctx.sql.buf)
+	p := ctx.sql.Len()
+	ctx.bindArg(value)
+	if loc == nil {
+		return
+	}
+	op := " = ANY("
+	if loc[4] >= 0 {
+		op = " <> ALL("
+	}
+	if ctx.sql.buf[loc[6]] == 'i' {
+		op = strings.ToLower(op)
+	}
+	text := string(ctx.sql.buf[loc[2]:loc[3]]) + op + string(ctx.sql.buf[p:]) + ")"
+	ctx.inList = &inListRewrite{start: loc[0], end: ctx.sql.Len(), text: text}
+}
@@ -0,0 +1,146 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assertion 模板作者在描述中声明的不变式（assert: 元数据行），例如：
+//
+//	assert: contains "where"
+//	assert: not contains "select *"
+//	assert: params <= 10
+//
+// 加载时对模板内容做静态校验（contains 检查模板文本，params 检查模板引用的参数个数），
+// DryRun 时对渲染结果再次校验（contains 检查 SQL，params 检查绑定参数个数）
+type Assertion struct {
+	Raw    string // 原始文本
+	Kind   string // contains / params
+	Negate bool   // not contains
+	Text   string // contains 的文本（不区分大小写）
+	Op     string // params 的比较运算符：< <= > >= == !=
+	N      int    // params 的比较值
+}
+
+// assertOps 支持的比较运算符（长的在前，避免 <= 被识别为 <）
+var assertOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// parseAssertion 解析一条 assert 元数据
+func parseAssertion(raw string) (Assertion, error) {
+	a := Assertion{Raw: raw}
+	s := strings.TrimSpace(raw)
+	if rest, ok := cutWord(s, "not"); ok {
+		a.Negate = true
+		s = rest
+	}
+	if rest, ok := cutWord(s, "contains"); ok {
+		text, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return a, codeError(CodeAssertion, "invalid assertion %q: contains expects a quoted string", raw)
+		}
+		a.Kind = "contains"
+		a.Text = text
+		return a, nil
+	}
+	if a.Negate {
+		return a, codeError(CodeAssertion, "invalid assertion %q: not is only supported with contains", raw)
+	}
+	if rest, ok := cutWord(s, "params"); ok {
+		rest = strings.TrimSpace(rest)
+		for _, op := range assertOps {
+			if strings.HasPrefix(rest, op) {
+				n, err := strconv.Atoi(strings.TrimSpace(rest[len(op):]))
+				if err != nil {
+					return a, codeError(CodeAssertion, "invalid assertion %q: params expects an integer", raw)
+				}
+				a.Kind = "params"
+				a.Op = op
+				a.N = n
+				return a, nil
+			}
+		}
+		return a, codeError(CodeAssertion, "invalid assertion %q: params expects a comparison operator", raw)
+	}
+	return a, codeError(CodeAssertion, "invalid assertion %q: unknown assertion", raw)
+}
+
+// cutWord 如果 s 以单词 word 开头，返回剩余部分
+func cutWord(s, word string) (string, bool) {
+	if len(s) < len(word) || !strings.EqualFold(s[:len(word)], word) {
+		return s, false
+	}
+	rest := s[len(word):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"' && rest[0] != '`' && !strings.ContainsRune("<>=!", rune(rest[0])) {
+		return s, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// check 用给定的文本和参数个数校验断言
+func (a Assertion) check(text string, params int) error {
+	switch a.Kind {
+	case "contains":
+		found := strings.Contains(strings.ToLower(text), strings.ToLower(a.Text))
+		if found == a.Negate {
+			return codeError(CodeAssertion, "assertion failed: %s", a.Raw)
+		}
+	case "params":
+		var ok bool
+		switch a.Op {
+		case "<":
+			ok = params < a.N
+		case "<=":
+			ok = params <= a.N
+		case ">":
+			ok = params > a.N
+		case ">=":
+			ok = params >= a.N
+		case "==":
+			ok = params == a.N
+		case "!=":
+			ok = params != a.N
+		}
+		if !ok {
+			return codeError(CodeAssertion, "assertion failed: %s (got %d params)", a.Raw, params)
+		}
+	}
+	return nil
+}
+
+// compileAssertions 解析模板的 assert 元数据并对模板内容做静态校验
+func compileAssertions(tmpl *SQLTemplate, ast *TemplateAST) ([]Assertion, error) {
+	raws := tmpl.Meta["assert"]
+	if len(raws) == 0 {
+		return nil, nil
+	}
+	params := len(ast.refs.params)
+	asserts := make([]Assertion, 0, len(raws))
+	for _, raw := range raws {
+		a, err := parseAssertion(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.check(tmpl.Content, params); err != nil {
+			return nil, err
+		}
+		asserts = append(asserts, a)
+	}
+	return asserts, nil
+}
+
+// DryRun 渲染模板并校验模板声明的断言（assert: 元数据），用于 CI 或启动时的自检。
+// 与 GetSql 不同，DryRun 不计入模板的渲染次数
+func (e *Engine) DryRun(path string, args interface{}) (Query, error) {
+	query, ast, err := e.render(context.Background(), path, args, false)
+	if err != nil {
+		return query, err
+	}
+	for _, a := range ast.Asserts {
+		if err := a.check(query.SQL, len(query.Params)); err != nil {
+			return query, fmt.Errorf("template %s: %w", path, err)
+		}
+	}
+	return query, nil
+}
@@ -1,5 +1,10 @@
 package gosql
 
+import (
+	"crypto/sha256"
+	"time"
+)
+
 // Node 表示 AST 节点
 type Node interface {
 	nodeType() string
@@ -28,6 +33,16 @@ type VarExprNode struct {
 
 func (n *VarExprNode) nodeType() string { return "var_expr" }
 
+// RowsNode 批量插入节点 @rows(a, b)：变量是切片时输出多行 (?, ?), (?, ?)，
+// 否则（例如 rows 是函数）按表达式 Expr 执行
+type RowsNode struct {
+	Name    string   // 变量名
+	Columns []string // 列名（结构体字段或 map 的键）
+	Expr    string   // 原始表达式
+}
+
+func (n *RowsNode) nodeType() string { return "rows" }
+
 // RawNode 直接输出变量节点 @=var
 type RawNode struct {
 	Name        string
@@ -85,6 +100,74 @@ type ForNode struct {
 
 func (n *ForNode) nodeType() string { return "for" }
 
+// ForEachNode foreach 语句节点 @foreach item in list sep ", " open "(" close ")" { }，
+// 每次循环的输出以 Sep 连接，整体以 Open、Close 包围
+type ForEachNode struct {
+	Index string // 下标（map 为键）变量名，可以为空
+	Item  string // 元素变量名
+	List  string // 被遍历的表达式
+	Sep   string
+	Open  string
+	Close string
+	Body  []Node
+}
+
+func (n *ForEachNode) nodeType() string { return "foreach" }
+
+// SwitchNode switch 语句节点 @switch expr { @case "a", "b" { } @default { } }，
+// 表达式只求值一次，执行第一个有值与之相等的 case，都不相等时执行 default
+type SwitchNode struct {
+	Expr    string
+	Cases   []*CaseNode
+	Default *CaseNode // default 分支，nil 表示没有
+}
+
+func (n *SwitchNode) nodeType() string { return "switch" }
+
+// CaseNode switch 的 case 分支（default 分支的 Values 为空）
+type CaseNode struct {
+	Values []string // 候选值的表达式
+	Body   []Node
+
+	consts []interface{} // 候选值是字面量时预先解析的值（与 Values 一一对应，不是字面量时为 nil）
+}
+
+func (n *CaseNode) nodeType() string { return "case" }
+
+// branches 返回所有分支（default 在最后）
+func (n *SwitchNode) branches() []*CaseNode {
+	if n.Default == nil {
+		return n.Cases
+	}
+	return append(n.Cases[:len(n.Cases):len(n.Cases)], n.Default)
+}
+
+// SetNode set 块节点 @set { }，输出 SET 和块内的赋值列表（去掉首尾多余的逗号）
+type SetNode struct {
+	Body []Node
+}
+
+func (n *SetNode) nodeType() string { return "set" }
+
+// FlagNode 功能开关节点 @flag "name" { } @else { }，开关由 WithFlagProvider 设置的 FlagProvider 决定，
+// 打开时执行 Body，否则执行 Else
+type FlagNode struct {
+	Name string // 开关名称
+	Body []Node
+	Else *ElseNode // else 分支，nil 表示没有
+}
+
+func (n *FlagNode) nodeType() string { return "flag" }
+
+// TrimNode trim 块节点 @trim(prefix="AND", suffix=",") { }，去掉块内容开头的 Prefix 和结尾的 Suffix
+type TrimNode struct {
+	Prefix string // 要去掉的开头，多个候选以 | 分隔
+	Suffix string // 要去掉的结尾，多个候选以 | 分隔
+	Body   []Node
+}
+
+func (n *TrimNode) nodeType() string { return "trim" }
+
 // CodeNode 直接 Go 代码节点 @{}
 type CodeNode struct {
 	Code string
@@ -95,15 +178,24 @@ func (n *CodeNode) nodeType() string { return "code" }
 // UseNode use 语句节点
 type UseNode struct {
 	Path   string       // 引用路径（如 a.b 或 a.b.c）
+	Args   []string     // 传给带参数 define 的实参表达式（@use a.b.c(1, name)），没有时为 nil
 	Covers []*CoverNode // cover 覆盖块
 }
 
 func (n *UseNode) nodeType() string { return "use" }
 
+// ImportNode import 语句节点 @import path，加载时展开为被引用模板（或 define）的节点
+type ImportNode struct {
+	Path string // 引用路径（如 a.b 或 a.b.c）
+}
+
+func (n *ImportNode) nodeType() string { return "import" }
+
 // DefineNode define 语句节点
 type DefineNode struct {
-	Name string
-	Body []Node
+	Name   string
+	Params []string // 形参（@define name(a, b)），带参数的 define 只在被 @use 或直接渲染时输出
+	Body   []Node
 }
 
 func (n *DefineNode) nodeType() string { return "define" }
@@ -124,10 +216,37 @@ type FuncBlockNode struct {
 
 func (n *FuncBlockNode) nodeType() string { return "func_block" }
 
+// DirectiveNode RegisterDirective 注册的自定义指令节点 @name(args) { }，渲染时由 Custom 输出
+type DirectiveNode struct {
+	Name   string
+	Args   string     // 括号中的原始参数（没有括号时为空）
+	Block  bool       // 是否带 { } 块
+	Body   []Node     // 块内节点，由 Custom 通过 RenderContext 决定是否执行
+	Custom CustomNode // 注册的解析函数返回的节点
+}
+
+func (n *DirectiveNode) nodeType() string { return "directive" }
+
 // TemplateAST 模板 AST
 type TemplateAST struct {
 	Namespace string
 	Name      string
+	Dialect   Dialect
 	Nodes     []Node
+	Asserts   []Assertion // 模板声明的断言（assert: 元数据）
+
+	contentHash [sha256.Size]byte // 模板内容的哈希（解析缓存的 key）
+	refs        *templateRefs     // 静态分析结果（由解析缓存填充）
+	source      []Node            // 展开 @import 之前的节点（没有 @import 时为 nil）
+	methodCalls map[string]bool   // 渲染时（包括 @use 引用的模板）可能调用的函数名，nil 表示未知
+	scopeNames  map[string]bool   // 渲染时（包括 @use 引用的模板）可能引用的变量名和函数名
+	parseTime   time.Duration     // 首次解析的耗时（内容未变化时复用缓存，耗时不变）
+	paramTypes  map[string]string // 模板声明的参数类型（param: 元数据），参数名 -> 类型
+	pure        bool              // 模板声明渲染结果只取决于参数（pure: true 元数据），可以缓存
+	flagged     bool              // 渲染时（包括 @use 引用的模板）使用 @flag，结果还取决于 FlagProvider
+
+	skeleton        *skeleton            // 整个模板的骨架（加载完成后编译），nil 表示逐个节点执行
+	defineSkeletons map[string]*skeleton // define 名 -> define 块的骨架
+	sizes           sizeHints            // 最近渲染整个模板的 SQL 长度和参数个数，用于预分配
 }
 
@@ -0,0 +1,472 @@
+package gosql
+
+import (
+	"fmt"
+	"go/parser"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// captureNodes 执行节点，返回它们输出的 SQL 和参数（不写入当前输出）。
+// 块内的条件行跳过时只影响块内已输出的内容
+func (ctx *executionContext) captureNodes(nodes []Node) (string, []interface{}, error) {
+	c := ctx.beginCapture()
+	err := ctx.executeNodes(nodes)
+	body, args := ctx.endCapture(c)
+	return body, args, err
+}
+
+// capture beginCapture 保存的当前输出
+type capture struct {
+	sql      sqlBuffer
+	args     []interface{}
+	pos      []int
+	base     int
+	skipRest bool
+	skipMark int
+}
+
+// beginCapture 开始收集输出：之后的输出写入新的缓冲，直到 endCapture
+func (ctx *executionContext) beginCapture() capture {
+	c := capture{sql: ctx.sql, args: ctx.args, pos: ctx.argPos, base: ctx.argBase, skipRest: ctx.skipRest, skipMark: ctx.skipMark}
+	ctx.sql = getSQLBuffer()
+	ctx.args, ctx.argPos = nil, nil
+	ctx.argBase = c.base + len(c.args)
+	ctx.inList, ctx.skipRest = nil, false
+	return c
+}
+
+// endCapture 结束收集，恢复之前的输出，返回收集到的 SQL 和参数
+func (ctx *executionContext) endCapture(c capture) (string, []interface{}) {
+	ctx.endSkip()
+	body, args := ctx.sql.String(), ctx.args
+	putSQLBuffer(ctx.sql)
+	ctx.sql = c.sql
+	ctx.args, ctx.argPos = c.args, c.pos
+	ctx.argBase = c.base
+	ctx.inList, ctx.skipRest, ctx.skipMark = nil, c.skipRest, c.skipMark
+	return body, args
+}
+
+// executeSwitch 执行 switch 语句：表达式只求值一次，按顺序执行第一个有候选值与之相等的 case
+// （== 的规则，数值之间按值比较），都不相等时执行 default
+//
+//	@switch sort {
+//	@case "name", "nick" { order by name }
+//	@case "age" { order by age desc }
+//	@default { order by id }
+//	}
+func (ctx *executionContext) executeSwitch(n *SwitchNode) error {
+	value, err := ctx.evalExpr(n.Expr)
+	if err != nil {
+		return fmt.Errorf("switch expression error: %w", err)
+	}
+	for _, c := range n.Cases {
+		for i, expr := range c.Values {
+			candidate := c.consts[i]
+			if candidate == nil {
+				if candidate, err = ctx.evalExpr(expr); err != nil {
+					return fmt.Errorf("case expression error: %w", err)
+				}
+			}
+			if switchEqual(value, candidate) {
+				ctx.tracef("switch", "switch %s: case %s", n.Expr, expr)
+				return ctx.executeNodes(c.Body)
+			}
+		}
+	}
+	if n.Default != nil {
+		ctx.tracef("switch", "switch %s: default", n.Expr)
+		return ctx.executeNodes(n.Default.Body)
+	}
+	ctx.tracef("switch", "switch %s: no case matched", n.Expr)
+	return nil
+}
+
+// switchEqual 判断 switch 的值与 case 的候选值是否相等，数值类型不同时按值比较（如 int64(1) 与 1）
+func switchEqual(value, candidate interface{}) bool {
+	if reflect.DeepEqual(value, candidate) {
+		return true
+	}
+	if value == nil || candidate == nil {
+		return false
+	}
+	l, r := reflect.ValueOf(value), reflect.ValueOf(candidate)
+	if l.Type() == r.Type() || !isNumberKind(l.Kind()) || !isNumberKind(r.Kind()) {
+		return false
+	}
+	if l.CanFloat() || r.CanFloat() {
+		return numberFloat(l) == numberFloat(r)
+	}
+	if l.CanUint() && r.CanUint() {
+		return l.Uint() == r.Uint()
+	}
+	if l.CanUint() {
+		l, r = r, l
+	}
+	// l 为有符号整数，r 为有符号或无符号整数
+	if r.CanUint() {
+		return l.Int() >= 0 && uint64(l.Int()) == r.Uint()
+	}
+	return l.Int() == r.Int()
+}
+
+// numberFloat 把数值转换为 float64
+func numberFloat(v reflect.Value) float64 {
+	switch {
+	case v.CanFloat():
+		return v.Float()
+	case v.CanUint():
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+// executeSet 执行 set 块：去掉块内容首尾的空白和逗号（被跳过的条件行留下的），不为空时输出 SET 和块内容
+//
+//	update user
+//	@set {
+//	    name = @name?,
+//	    age = @age?,
+//	}
+//	where id = @id
+func (ctx *executionContext) executeSet(n *SetNode) error {
+	body, args, err := ctx.captureNodes(n.Body)
+	if err != nil {
+		return err
+	}
+	ctx.writeSet(body, args)
+	return nil
+}
+
+// writeSet 输出 set 块收集到的内容
+func (ctx *executionContext) writeSet(body string, args []interface{}) {
+	body = trimAffixes(collapseCommas(body), []string{","}, []string{","})
+	if body == "" {
+		ctx.tracef("skip", "@set is empty")
+		return
+	}
+	ctx.writeArgs("SET "+body, args)
+}
+
+// collapseCommas 去掉紧接着另一个逗号（中间只有空白）的逗号，即中间被跳过的赋值留下的逗号：
+// "name = ?,\n,\n    email = ?" 变为 "name = ?,\n    email = ?"。引号中的内容不处理
+func collapseCommas(s string) string {
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ',':
+			j := i + 1
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t' || s[j] == '\r' || s[j] == '\n') {
+				j++
+			}
+			if j < len(s) && s[j] == ',' {
+				i = j - 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// executeTrim 执行 trim 块：去掉块内容首尾的空白、开头的 Prefix 和结尾的 Suffix（不区分大小写，
+// 多个候选以 | 分隔，重复出现时都去掉），不为空时前后各加一个空格输出
+//
+//	where 1 = 1
+//	@trim(prefix="AND|OR") {
+//	    and name = @name?
+//	    and age = @age?
+//	}
+func (ctx *executionContext) executeTrim(n *TrimNode) error {
+	body, args, err := ctx.captureNodes(n.Body)
+	if err != nil {
+		return err
+	}
+	ctx.writeTrim(n.Prefix, n.Suffix, body, args)
+	return nil
+}
+
+// writeTrim 输出 trim 块收集到的内容
+func (ctx *executionContext) writeTrim(prefix, suffix, body string, args []interface{}) {
+	body = trimAffixes(body, strings.Split(prefix, "|"), strings.Split(suffix, "|"))
+	if body == "" {
+		ctx.tracef("skip", "@trim is empty")
+		return
+	}
+	ctx.writeArgs(" "+body+" ", args)
+}
+
+// trimAffixes 去掉 s 首尾的空白，以及重复出现在开头的 prefixes 和结尾的 suffixes（不区分大小写）。
+// 以字母、数字或下划线结尾的 prefix（如 AND）只在单词边界处匹配，不会去掉 ANDROID 的开头
+func trimAffixes(s string, prefixes, suffixes []string) string {
+	for {
+		trimmed := strings.TrimSpace(s)
+		for _, prefix := range prefixes {
+			prefix = strings.TrimSpace(prefix)
+			if prefix == "" || len(trimmed) < len(prefix) || !strings.EqualFold(trimmed[:len(prefix)], prefix) {
+				continue
+			}
+			if rest := trimmed[len(prefix):]; rest != "" && isWordByte(prefix[len(prefix)-1]) && isWordByte(rest[0]) {
+				continue
+			}
+			trimmed = strings.TrimSpace(trimmed[len(prefix):])
+		}
+		for _, suffix := range suffixes {
+			suffix = strings.TrimSpace(suffix)
+			if suffix == "" || len(trimmed) < len(suffix) || !strings.EqualFold(trimmed[len(trimmed)-len(suffix):], suffix) {
+				continue
+			}
+			if rest := trimmed[:len(trimmed)-len(suffix)]; rest != "" && isWordByte(suffix[0]) && isWordByte(rest[len(rest)-1]) {
+				continue
+			}
+			trimmed = strings.TrimSpace(trimmed[:len(trimmed)-len(suffix)])
+		}
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// isWordByte 判断是否是标识符字符（字母、数字、下划线）
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// parseTrimArgs 解析 trim 块的参数，如 trim(prefix="AND", suffix=",")。
+// 只有参数全部是 prefix / suffix 命名参数时才是 trim 块，否则返回 false（按普通函数块处理）
+func parseTrimArgs(funcExpr string) (*TrimNode, bool, error) {
+	expr := strings.TrimSpace(funcExpr)
+	if !strings.HasPrefix(expr, "trim(") || !strings.HasSuffix(expr, ")") {
+		return nil, false, nil
+	}
+	args := strings.TrimSpace(expr[len("trim(") : len(expr)-1])
+	if args == "" {
+		return nil, false, nil
+	}
+	node := &TrimNode{}
+	for _, arg := range splitArgs(args) {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, false, nil
+		}
+		name = strings.TrimSpace(name)
+		if name != "prefix" && name != "suffix" {
+			return nil, false, nil
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, false, codeError(CodeSyntax, "@trim: %s must be a quoted string, got %s", name, strings.TrimSpace(value))
+		}
+		if name == "prefix" {
+			node.Prefix = s
+		} else {
+			node.Suffix = s
+		}
+	}
+	return node, true, nil
+}
+
+// splitArgs 按顶层的逗号拆分参数列表（忽略字符串中的逗号）
+func splitArgs(s string) []string {
+	var args []string
+	var state literalState
+	runes := []rune(s)
+	start := 0
+	for i, ch := range runes {
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if !state.feed(ch, next, true) && ch == ',' {
+			args = append(args, string(runes[start:i]))
+			start = i + 1
+		}
+	}
+	return append(args, string(runes[start:]))
+}
+
+// foreachOption foreach 头部末尾的选项，如 sep ", "
+var foreachOption = regexp.MustCompile("\\s(sep|open|close)\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)\\s*$")
+
+// parseForEachHeader 解析 foreach 头部：[index,] item in list [sep "..."] [open "..."] [close "..."]
+func parseForEachHeader(header string) (*ForEachNode, error) {
+	node := &ForEachNode{}
+	vars, rest, ok := strings.Cut(header, " in ")
+	if !ok {
+		return nil, codeError(CodeSyntax, "invalid foreach %q, expected \"item in list\"", header)
+	}
+	names := strings.Split(vars, ",")
+	if len(names) > 2 {
+		return nil, codeError(CodeSyntax, "invalid foreach %q, expected \"item in list\" or \"index, item in list\"", header)
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+		if !isIdentifier(names[i]) {
+			return nil, codeError(CodeSyntax, "invalid foreach variable %q", names[i])
+		}
+	}
+	node.Item = names[len(names)-1]
+	if len(names) == 2 {
+		node.Index = names[0]
+	}
+
+	seen := make(map[string]bool)
+	for {
+		m := foreachOption.FindStringSubmatchIndex(rest)
+		if m == nil {
+			break
+		}
+		name := rest[m[2]:m[3]]
+		if seen[name] {
+			return nil, codeError(CodeSyntax, "invalid foreach %q: duplicate %s", header, name)
+		}
+		seen[name] = true
+		value, err := strconv.Unquote(rest[m[4]:m[5]])
+		if err != nil {
+			return nil, codeError(CodeSyntax, "invalid foreach %q: %s: %v", header, name, err)
+		}
+		switch name {
+		case "sep":
+			node.Sep = value
+		case "open":
+			node.Open = value
+		case "close":
+			node.Close = value
+		}
+		rest = rest[:m[0]]
+	}
+	node.List = strings.TrimSpace(rest)
+	if _, err := parser.ParseExpr(node.List); err != nil {
+		return nil, codeError(CodeSyntax, "invalid foreach %q: bad list expression %q", header, node.List)
+	}
+	return node, nil
+}
+
+// isIdentifier 判断是否是合法的变量名
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isWordByte(s[i]) || i == 0 && s[i] >= '0' && s[i] <= '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// executeForEach 执行 foreach：逐个元素执行块内容，去掉首尾空白后以 Sep 连接（输出为空的元素跳过），
+// 整体以 Open、Close 包围；列表为空（或 nil）时什么也不输出
+//
+//	where id in @foreach id in ids sep ", " open "(" close ")" { @id }
+//	values @foreach r in rows sep ", " { (@ r.Name @, @ r.Age @) }
+func (ctx *executionContext) executeForEach(n *ForEachNode) error {
+	loop, err := ctx.newForEachLoop(n)
+	if err != nil {
+		return err
+	}
+	for loop.Next() {
+		if err := ctx.executeNodes(n.Body); err != nil {
+			loop.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachLoop foreach 的循环状态：每次 Next 收集上一个元素的输出并设置下一个元素的变量，
+// 元素都执行完后把收集到的内容写回（执行器和编译后的程序共用）
+type forEachLoop struct {
+	ctx   *executionContext
+	n     *ForEachNode
+	rv    reflect.Value
+	keys  []reflect.Value // map 的键（遍历顺序）
+	count int
+	next  int
+	outer capture
+	parts []string
+	args  []interface{}
+}
+
+// newForEachLoop 求值被遍历的表达式，开始收集输出
+func (ctx *executionContext) newForEachLoop(n *ForEachNode) (*forEachLoop, error) {
+	value, err := ctx.evalExpr(n.List)
+	if err != nil {
+		return nil, fmt.Errorf("foreach expression error: %w", err)
+	}
+	l := &forEachLoop{ctx: ctx, n: n, rv: reflect.ValueOf(value)}
+	switch l.rv.Kind() {
+	case reflect.Invalid:
+		// nil：没有元素
+	case reflect.Slice, reflect.Array:
+		l.count = l.rv.Len()
+		ctx.tracef("foreach", "foreach %s: %d iterations", n.List, l.count)
+	case reflect.Map:
+		l.keys = l.rv.MapKeys()
+		if ctx.engine.deterministic {
+			sortMapKeys(l.keys)
+		}
+		l.count = len(l.keys)
+		ctx.tracef("foreach", "foreach %s: %d iterations", n.List, l.count)
+	default:
+		return nil, fmt.Errorf("cannot foreach over %s", l.rv.Kind())
+	}
+	// 每次循环的输出单独收集，结束后再写回
+	l.outer = ctx.beginCapture()
+	return l, nil
+}
+
+// Next 收集上一个元素的输出（为空时跳过），还有元素时设置循环变量并返回 true，
+// 否则写回收集到的内容并返回 false
+func (l *forEachLoop) Next() bool {
+	ctx, n := l.ctx, l.n
+	if l.next > 0 {
+		ctx.endSkip()
+		if body := strings.TrimSpace(ctx.sql.String()); body != "" {
+			l.parts = append(l.parts, body)
+			l.args = append(l.args, ctx.args...)
+		}
+	}
+	if l.next == l.count {
+		l.close()
+		if len(l.parts) > 0 {
+			ctx.writeArgs(n.Open+strings.Join(l.parts, n.Sep)+n.Close, l.args)
+		}
+		return false
+	}
+	var index, item interface{}
+	if l.keys != nil {
+		index, item = l.keys[l.next].Interface(), l.rv.MapIndex(l.keys[l.next]).Interface()
+	} else {
+		index, item = l.next, l.rv.Index(l.next).Interface()
+	}
+	l.next++
+	if n.Index != "" && n.Index != "_" {
+		ctx.scope[n.Index] = index
+	}
+	if n.Item != "_" {
+		ctx.scope[n.Item] = item
+	}
+	ctx.sql.Reset()
+	ctx.args, ctx.argPos = nil, nil
+	ctx.argBase = l.outer.base + len(l.outer.args) + len(l.args)
+	ctx.inList, ctx.skipRest = nil, false
+	return true
+}
+
+// close 结束收集，恢复之前的输出
+func (l *forEachLoop) close() {
+	l.ctx.endCapture(l.outer)
+}
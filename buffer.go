@@ -0,0 +1,87 @@
+package gosql
+
+import (
+	"bytes"
+	"sync"
+	"unsafe"
+)
+
+// sqlBuffer 渲染 SQL 用的字节缓冲：渲染过程中追加写入，渲染结束时 take 直接把字节作为结果的 SQL（不再复制一次）。
+// 方法与 strings.Builder 相同，但 String 总是复制，只有 take 之后缓冲与结果共享内存
+type sqlBuffer struct {
+	buf []byte
+}
+
+// WriteString 追加字符串
+func (b *sqlBuffer) WriteString(s string) {
+	b.buf = append(b.buf, s...)
+}
+
+// Len 返回已写入的字节数
+func (b *sqlBuffer) Len() int {
+	return len(b.buf)
+}
+
+// Grow 保证还能再写入 n 个字节而不重新分配
+func (b *sqlBuffer) Grow(n int) {
+	if cap(b.buf)-len(b.buf) < n {
+		buf := make([]byte, len(b.buf), len(b.buf)+n)
+		copy(buf, b.buf)
+		b.buf = buf
+	}
+}
+
+// Reset 清空内容（保留容量）
+func (b *sqlBuffer) Reset() {
+	b.buf = b.buf[:0]
+}
+
+// String 返回内容的副本
+func (b *sqlBuffer) String() string {
+	return string(b.buf)
+}
+
+// truncateLine 删除最后一个换行符之后的内容（没有换行符时清空）
+func (b *sqlBuffer) truncateLine() {
+	b.buf = b.buf[:bytes.LastIndexByte(b.buf, '\n')+1]
+}
+
+// take 返回内容并放弃缓冲：返回的字符串直接使用缓冲的内存，之后的写入使用新的缓冲
+func (b *sqlBuffer) take() string {
+	if len(b.buf) == 0 {
+		b.buf = nil
+		return ""
+	}
+	s := unsafe.String(&b.buf[0], len(b.buf))
+	b.buf = nil
+	return s
+}
+
+// sqlBufferPool 临时缓冲（收集块内容等，用完即归还）的对象池
+var sqlBufferPool sync.Pool
+
+// maxPooledBuffer 超过这个容量的临时缓冲不放回对象池，避免偶尔的大查询长期占用内存
+const maxPooledBuffer = 64 << 10
+
+// getSQLBuffer 从对象池取出空的临时缓冲
+func getSQLBuffer() sqlBuffer {
+	if p, ok := sqlBufferPool.Get().(*[]byte); ok {
+		return sqlBuffer{buf: (*p)[:0]}
+	}
+	return sqlBuffer{}
+}
+
+// putSQLBuffer 归还临时缓冲（调用方之后不能再使用它，也不能保留 String 之外的引用）
+func putSQLBuffer(b sqlBuffer) {
+	if b.buf == nil || cap(b.buf) > maxPooledBuffer {
+		return
+	}
+	buf := b.buf[:0]
+	sqlBufferPool.Put(&buf)
+}
+
+// SQLBytes 返回 SQL 的字节，与 SQL 共享内存、不复制，适合直接写入网络连接等只读的场景，
+// 调用方不能修改返回的切片
+func (q Query) SQLBytes() []byte {
+	return unsafe.Slice(unsafe.StringData(q.SQL), len(q.SQL))
+}
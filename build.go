@@ -0,0 +1,72 @@
+package gosql
+
+import (
+	"sort"
+	"time"
+)
+
+// BuildReport 已加载模板的构建情况，可以记录到日志或在健康检查接口中展示
+type BuildReport struct {
+	Templates int               `json:"templates"`  // 已加载的模板数
+	Files     int               `json:"files"`      // 通过 LoadFile / LoadSource 等加载的文件数
+	LoadedAt  time.Time         `json:"loaded_at"`  // 最近一次成功加载的时间
+	ParseTime time.Duration     `json:"parse_time"` // 所有模板的解析耗时之和
+	Slowest   []TemplateTiming  `json:"slowest"`    // 解析最慢的模板（最多 10 个，按耗时降序）
+	Warnings  []string          `json:"warnings"`   // 不影响渲染的问题，如没有被引用的 define
+	Issues    []string          `json:"issues"`     // 依赖问题：@use 引用的模板、define 或 @cover 的目标不存在，@use 循环引用
+	Codes     map[ErrorCode]int `json:"codes"`      // Issues 按错误码统计
+}
+
+// TemplateTiming 模板的解析耗时
+type TemplateTiming struct {
+	Path     string        `json:"path"`
+	Duration time.Duration `json:"duration"`
+}
+
+// OK 没有依赖问题时返回 true
+func (r BuildReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// slowestTemplates BuildReport 中列出的最慢模板数
+const slowestTemplates = 10
+
+// BuildReport 返回已加载模板的构建情况。依赖问题按 Validate 的规则检查（所有模板加载完成后才准确），
+// 使用 WithoutReferenceValidation 时也会检查
+func (e *Engine) BuildReport() BuildReport {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	report := BuildReport{
+		Templates: len(e.compiledAST),
+		Files:     len(e.files),
+		LoadedAt:  e.loadedAt,
+		Slowest:   []TemplateTiming{},
+		Warnings:  []string{},
+		Issues:    []string{},
+		Codes:     map[ErrorCode]int{},
+	}
+	timings := make([]TemplateTiming, 0, len(e.compiledAST))
+	for key, ast := range e.compiledAST {
+		report.ParseTime += ast.parseTime
+		timings = append(timings, TemplateTiming{Path: key, Duration: ast.parseTime})
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Duration != timings[j].Duration {
+			return timings[i].Duration > timings[j].Duration
+		}
+		return timings[i].Path < timings[j].Path
+	})
+	if len(timings) > slowestTemplates {
+		timings = timings[:slowestTemplates]
+	}
+	report.Slowest = append(report.Slowest, timings...)
+
+	for _, err := range e.refErrors(true, 0) {
+		report.Issues = append(report.Issues, err.Error())
+		report.Codes[CodeOf(err)]++
+	}
+	for _, define := range e.analyzeDefines().Unreferenced {
+		report.Warnings = append(report.Warnings, "unreferenced define: "+define)
+	}
+	return report
+}
@@ -0,0 +1,148 @@
+package gosql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// builtinNames 内置模板函数，渲染时只绑定模板中实际调用到的；用户用 RegisterFunc 注册的同名函数优先
+var builtinNames = []string{"render", "ident", "qualify", "fn", "uuid", "nowUTC", "seq", "array"}
+
+// bindBuiltins 绑定内置函数
+func (ctx *executionContext) bindBuiltins() {
+	for _, name := range builtinNames {
+		if _, exists := ctx.engine.funcs[name]; exists {
+			continue
+		}
+		if ctx.calls != nil && !ctx.calls[name] {
+			continue
+		}
+		ctx.scope[name] = ctx.builtin(name)
+	}
+}
+
+// builtin 创建绑定到当前执行上下文的内置函数
+func (ctx *executionContext) builtin(name string) interface{} {
+	switch name {
+	case "render":
+		return ctx.renderFunc()
+	case "ident":
+		return ctx.identFunc()
+	case "qualify":
+		return ctx.qualifyFunc()
+	case "fn":
+		return ctx.fnFunc()
+	case "uuid", "nowUTC", "seq":
+		return ctx.generatedFunc(name)
+	case "array":
+		return ctx.arrayFunc()
+	}
+	return nil
+}
+
+// Ident 经过校验的标识符或函数表达式，由 ident / qualify / fn 内置函数生成，
+// 可以安全地用 @= @ 直接输出（GROUP BY、ORDER BY、窗口函数等不能参数化的位置）
+type Ident string
+
+// identFunc 内置函数 ident(name)：校验并返回单个标识符（列名、表名、别名）
+func (ctx *executionContext) identFunc() interface{} {
+	return func(name string) Ident {
+		if err := validIdent(name); err != nil {
+			ctx.fail(fmt.Errorf("ident: %w", err))
+			return ""
+		}
+		return Ident(name)
+	}
+}
+
+// qualifyFunc 内置函数 qualify(table, col)：返回 table.col，col 可以是 *
+func (ctx *executionContext) qualifyFunc() interface{} {
+	return func(table, col string) Ident {
+		if err := validIdent(table); err != nil {
+			ctx.fail(fmt.Errorf("qualify: %w", err))
+			return ""
+		}
+		if col != "*" {
+			if err := validIdent(col); err != nil {
+				ctx.fail(fmt.Errorf("qualify: %w", err))
+				return ""
+			}
+		}
+		return Ident(table + "." + col)
+	}
+}
+
+// fnFunc 内置函数 fn(name, args...)：返回函数调用表达式 name(arg1, arg2)。
+// 参数可以是 ident / qualify / fn 的结果、数字、bool，或者会被当作（可带表名的）标识符校验的字符串。
+// （func 是 Go 关键字，不能作为函数名，所以叫 fn）
+func (ctx *executionContext) fnFunc() interface{} {
+	return func(name string, args ...interface{}) Ident {
+		if err := validIdent(name); err != nil {
+			ctx.fail(fmt.Errorf("fn: %w", err))
+			return ""
+		}
+		parts := make([]string, 0, len(args))
+		for _, arg := range args {
+			part, err := fnArg(arg)
+			if err != nil {
+				ctx.fail(fmt.Errorf("fn %s: %w", name, err))
+				return ""
+			}
+			parts = append(parts, part)
+		}
+		return Ident(name + "(" + strings.Join(parts, ", ") + ")")
+	}
+}
+
+// fnArg 把 fn 的参数转换为 SQL 文本
+func fnArg(arg interface{}) (string, error) {
+	switch v := arg.(type) {
+	case Ident:
+		return string(v), nil
+	case string:
+		if v == "*" {
+			return v, nil
+		}
+		for _, part := range strings.Split(v, ".") {
+			if err := validIdent(part); err != nil {
+				return "", err
+			}
+		}
+		return v, nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	switch reflect.ValueOf(arg).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(arg), nil
+	}
+	return "", fmt.Errorf("unsupported argument %v (%T)", arg, arg)
+}
+
+// validIdent 校验标识符：字母或下划线开头，只包含字母、数字、下划线
+func validIdent(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty identifier")
+	}
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return fmt.Errorf("invalid identifier %q", name)
+	}
+	return nil
+}
+
+// fail 记录内置函数执行时的错误（表达式求值结束后返回）
+func (ctx *executionContext) fail(err error) {
+	if ctx.err == nil {
+		ctx.err = err
+	}
+}
@@ -0,0 +1,150 @@
+package gosql
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// bundleManifestName 模板包中清单文件的名字（总是第一个条目）
+const bundleManifestName = "manifest.json"
+
+// BundleManifest 模板包清单：记录包中每个文件的哈希（以及可选的签名），加载时逐一校验
+type BundleManifest struct {
+	Version int           `json:"version"`
+	Files   []BundleEntry `json:"files"`
+}
+
+// BundleEntry 模板包中的一个文件
+type BundleEntry struct {
+	Name      string `json:"name"`
+	Size      int    `json:"size"`
+	SHA256    string `json:"sha256"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// WriteBundle 把多个 markdown 文件打包为单个模板包（gzip 压缩的 tar，第一个条目为清单），
+// 文件按名字排序以保证相同输入得到相同的包
+func WriteBundle(w io.Writer, files []NamedContent) error {
+	files = append([]NamedContent(nil), files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	manifest := BundleManifest{Version: 1}
+	for i, f := range files {
+		if i > 0 && files[i-1].Name == f.Name {
+			return fmt.Errorf("bundle: duplicate file %s", f.Name)
+		}
+		sum := sha256.Sum256([]byte(f.Content))
+		manifest.Files = append(manifest.Files, BundleEntry{
+			Name:      f.Name,
+			Size:      len(f.Content),
+			SHA256:    hex.EncodeToString(sum[:]),
+			Signature: f.Signature,
+		})
+	}
+	bs, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	write := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := write(bundleManifestName, bs); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := write(f.Name, []byte(f.Content)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadBundle 读取模板包并校验清单：每个文件的大小和哈希必须与清单一致，
+// 清单之外的文件或清单中缺失的文件都会报错
+func ReadBundle(r io.Reader) ([]NamedContent, *BundleManifest, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bundle: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	var manifest *BundleManifest
+	entries := make(map[string]BundleEntry)
+	var files []NamedContent
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("bundle: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bundle: %s: %w", hdr.Name, err)
+		}
+
+		if manifest == nil {
+			if hdr.Name != bundleManifestName {
+				return nil, nil, fmt.Errorf("bundle: missing %s", bundleManifestName)
+			}
+			manifest = &BundleManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("bundle: %s: %w", bundleManifestName, err)
+			}
+			for _, entry := range manifest.Files {
+				entries[entry.Name] = entry
+			}
+			continue
+		}
+
+		entry, ok := entries[hdr.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("bundle: %s: not listed in manifest", hdr.Name)
+		}
+		delete(entries, hdr.Name)
+		sum := sha256.Sum256(data)
+		if len(data) != entry.Size || hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("bundle: %s: hash mismatch", hdr.Name)
+		}
+		files = append(files, NamedContent{Name: hdr.Name, Content: string(data), Signature: entry.Signature})
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("bundle: missing %s", bundleManifestName)
+	}
+	for name := range entries {
+		return nil, nil, fmt.Errorf("bundle: %s: listed in manifest but missing", name)
+	}
+	return files, manifest, nil
+}
+
+// LoadBundle 加载 WriteBundle（或 gosql pack）生成的模板包。
+// 包中的文件先按清单校验哈希，配置了 WithBundleVerifier 时还会校验签名，然后按 LoadSource 的规则加载
+func (e *Engine) LoadBundle(r io.Reader) ([]ReloadEvent, error) {
+	files, _, err := ReadBundle(r)
+	if err != nil {
+		e.notifyReload(nil, err)
+		return nil, err
+	}
+	return e.LoadSource(SourceFunc(func() ([]NamedContent, error) {
+		return files, nil
+	}))
+}
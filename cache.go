@@ -1,9 +1,11 @@
 package gosql
 
 import (
+	"crypto/sha256"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // TypeCache 类型反射缓存
@@ -230,3 +232,66 @@ func putScope(m map[string]interface{}) {
 	scopePool.Put(m)
 }
 
+
+// parseCache 模板解析缓存，key 为模板内容的哈希
+// 重复加载相同内容（热加载、测试）时直接复用已解析的节点，避免重新解析
+type parseCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*parsedTemplate
+}
+
+// parsedTemplate 缓存的解析结果
+type parsedTemplate struct {
+	nodes     []Node
+	refs      *templateRefs // 静态分析结果（引用的参数、函数、use 等）
+	parseTime time.Duration // 解析和分析耗时
+}
+
+// newParseCache 创建解析缓存
+func newParseCache() *parseCache {
+	return &parseCache{
+		entries: make(map[[sha256.Size]byte]*parsedTemplate),
+	}
+}
+
+// parse 解析模板内容，命中缓存时复用已解析的节点（节点在执行时只读，可以共享）
+func (c *parseCache) parse(content string) (*TemplateAST, error) {
+	hash := sha256.Sum256([]byte(content))
+
+	c.mu.Lock()
+	entry, ok := c.entries[hash]
+	c.mu.Unlock()
+	if ok {
+		return &TemplateAST{Nodes: entry.nodes, refs: entry.refs, contentHash: hash, parseTime: entry.parseTime}, nil
+	}
+
+	start := time.Now()
+	ast, err := ParseTemplate(content)
+	if err != nil {
+		return nil, err
+	}
+	ast.contentHash = hash
+	ast.refs = analyzeTemplate(ast.Nodes)
+	ast.parseTime = time.Since(start)
+
+	c.mu.Lock()
+	c.entries[hash] = &parsedTemplate{nodes: ast.Nodes, refs: ast.refs, parseTime: ast.parseTime}
+	c.mu.Unlock()
+	return ast, nil
+}
+
+// retain 只保留当前仍在使用的模板的缓存，避免缓存无限增长
+func (c *parseCache) retain(asts map[string]*TemplateAST) {
+	used := make(map[[sha256.Size]byte]bool, len(asts))
+	for _, ast := range asts {
+		used[ast.contentHash] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for hash := range c.entries {
+		if !used[hash] {
+			delete(c.entries, hash)
+		}
+	}
+}
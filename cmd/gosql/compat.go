@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/llyb120/gosql"
+)
+
+// baselineEntry 基线中一个模板在一组示例参数下的渲染结果
+type baselineEntry struct {
+	Args   map[string]interface{} `json:"args"`
+	SQL    string                 `json:"sql,omitempty"`
+	Params int                    `json:"params"`          // 参数个数
+	Error  string                 `json:"error,omitempty"` // 渲染失败时的错误信息
+}
+
+// runCompat gosql compat：把当前模板的渲染结果与记录的基线（每个模板、每组示例参数）比较，
+// SQL 结构（规范化后的 SQL、参数个数）变化时报错，用于防止共享模板库的行为被意外改变
+func runCompat(args []string) error {
+	fset := flag.NewFlagSet("compat", flag.ExitOnError)
+	baselineFile := fset.String("baseline", "", "基线 JSON 文件")
+	samplesFile := fset.String("args", "", "示例参数 JSON 文件（-update 时使用，格式同 gosql diff）")
+	update := fset.Bool("update", false, "用当前的渲染结果重新生成基线")
+	paths := parseFlags(fset, args)
+
+	if *baselineFile == "" {
+		return fmt.Errorf("compat: missing -baseline file")
+	}
+	engine, err := loadEngine(paths)
+	if err != nil {
+		return err
+	}
+
+	if *update {
+		samples, err := loadSamples(*samplesFile)
+		if err != nil {
+			return err
+		}
+		baseline := make(map[string][]baselineEntry)
+		for key := range templateKeys(engine) {
+			for _, sample := range samplesFor(samples, key) {
+				baseline[key] = append(baseline[key], renderEntry(engine, key, sample))
+			}
+		}
+		bs, err := json.MarshalIndent(baseline, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*baselineFile, append(bs, '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("recorded %d templates into %s\n", len(baseline), *baselineFile)
+		return nil
+	}
+
+	bs, err := os.ReadFile(*baselineFile)
+	if err != nil {
+		return err
+	}
+	var baseline map[string][]baselineEntry
+	if err := json.Unmarshal(bs, &baseline); err != nil {
+		return fmt.Errorf("%s: %w", *baselineFile, err)
+	}
+
+	current := templateKeys(engine)
+	keys := make([]string, 0, len(baseline))
+	for key := range baseline {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := 0
+	for _, key := range keys {
+		if !current[key] {
+			fmt.Printf("%s: removed\n", key)
+			changed++
+			continue
+		}
+		for i, want := range baseline[key] {
+			got := renderEntry(engine, key, want.Args)
+			switch {
+			case want.Error != "" || got.Error != "":
+				if (want.Error == "") != (got.Error == "") {
+					fmt.Printf("%s (sample %d): error changed\n  baseline: %s\n  current:  %s\n", key, i+1, entryText(want), entryText(got))
+					changed++
+				}
+			case gosql.NormalizeSQL(want.SQL) != gosql.NormalizeSQL(got.SQL) || want.Params != got.Params:
+				fmt.Printf("%s (sample %d): SQL changed\n  baseline: %s\n  current:  %s\n", key, i+1, entryText(want), entryText(got))
+				changed++
+			}
+		}
+	}
+	var added []string
+	for key := range current {
+		if _, ok := baseline[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		fmt.Printf("%s: not in baseline\n", key)
+	}
+
+	if changed > 0 {
+		return fmt.Errorf("compat: %d incompatible changes", changed)
+	}
+	fmt.Println("compatible")
+	return nil
+}
+
+// renderEntry 渲染模板，记录规范化后的 SQL 和参数个数
+func renderEntry(engine *gosql.Engine, key string, args map[string]interface{}) baselineEntry {
+	entry := baselineEntry{Args: args}
+	q, err := engine.GetSql(key, args)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.SQL = gosql.NormalizeSQL(q.SQL)
+	entry.Params = len(q.Params)
+	return entry
+}
+
+// entryText 单行描述渲染结果
+func entryText(entry baselineEntry) string {
+	if entry.Error != "" {
+		return "error: " + entry.Error
+	}
+	return fmt.Sprintf("%s (%d params)", entry.SQL, entry.Params)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/llyb120/gosql"
+)
+
+// runDefines gosql defines：列出没有被任何 @use / @import / @cover 引用的 define，以及目标不存在的 @cover
+func runDefines(args []string) error {
+	fset := flag.NewFlagSet("defines", flag.ExitOnError)
+	asJSON := fset.Bool("json", false, "以 JSON 输出结果")
+	paths := parseFlags(fset, args)
+
+	engine, err := loadEngine(paths, gosql.WithoutReferenceValidation())
+	if err != nil {
+		return err
+	}
+	report := engine.AnalyzeDefines()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	for _, define := range report.Unreferenced {
+		fmt.Printf("unreferenced define: %s\n", define)
+	}
+	for _, c := range report.DanglingCovers {
+		fmt.Printf("dangling cover: %s: @use %s { @cover %s }\n", c.Template, c.Use, c.Cover)
+	}
+	return nil
+}
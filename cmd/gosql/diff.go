@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// runDiff gosql diff：用示例参数分别渲染新旧两个版本的模板，输出 SQL 层面的差异，
+// 便于评审时看到实际生效的 SQL 变化，而不是模板语法上的改动
+func runDiff(args []string) error {
+	fset := flag.NewFlagSet("diff", flag.ExitOnError)
+	samplesFile := fset.String("args", "", "示例参数 JSON 文件：{\"模板路径\": 参数对象或参数对象数组}")
+	paths := parseFlags(fset, args)
+
+	if len(paths) != 2 {
+		return fmt.Errorf("diff: expected <old> <new>")
+	}
+	samples, err := loadSamples(*samplesFile)
+	if err != nil {
+		return err
+	}
+	oldEngine, err := loadEngine(paths[:1])
+	if err != nil {
+		return err
+	}
+	newEngine, err := loadEngine(paths[1:])
+	if err != nil {
+		return err
+	}
+
+	oldKeys := templateKeys(oldEngine)
+	newKeys := templateKeys(newEngine)
+	keys := make([]string, 0, len(newKeys))
+	for key := range oldKeys {
+		keys = append(keys, key)
+	}
+	for key := range newKeys {
+		if !oldKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changed := 0
+	for _, key := range keys {
+		switch {
+		case !newKeys[key]:
+			fmt.Printf("--- %s: removed\n\n", key)
+			changed++
+			continue
+		case !oldKeys[key]:
+			fmt.Printf("+++ %s: added\n\n", key)
+			changed++
+			continue
+		}
+		for i, sample := range samplesFor(samples, key) {
+			before := renderSample(oldEngine, key, sample)
+			after := renderSample(newEngine, key, sample)
+			if gosql.NormalizeSQL(before) == gosql.NormalizeSQL(after) {
+				continue
+			}
+			changed++
+			fmt.Printf("@@ %s (sample %d)\n", key, i+1)
+			for _, line := range diffLines(strings.Split(before, "\n"), strings.Split(after, "\n")) {
+				fmt.Println(line)
+			}
+			fmt.Println()
+		}
+	}
+	if changed == 0 {
+		fmt.Println("no SQL changes")
+	}
+	return nil
+}
+
+// loadSamples 读取示例参数文件，每个模板对应一个参数对象或参数对象数组。file 为空时返回空集合
+func loadSamples(file string) (map[string][]map[string]interface{}, error) {
+	samples := make(map[string][]map[string]interface{})
+	if file == "" {
+		return samples, nil
+	}
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	for key, msg := range raw {
+		var list []map[string]interface{}
+		if err := json.Unmarshal(msg, &list); err != nil {
+			var one map[string]interface{}
+			if err := json.Unmarshal(msg, &one); err != nil {
+				return nil, fmt.Errorf("%s: %s: expected an object or an array of objects", file, key)
+			}
+			list = []map[string]interface{}{one}
+		}
+		samples[key] = list
+	}
+	return samples, nil
+}
+
+// samplesFor 返回模板的示例参数，没有示例时用空参数渲染一次
+func samplesFor(samples map[string][]map[string]interface{}, key string) []map[string]interface{} {
+	if list := samples[key]; len(list) > 0 {
+		return list
+	}
+	return []map[string]interface{}{{}}
+}
+
+// templateKeys 返回引擎中所有模板的路径
+func templateKeys(engine *gosql.Engine) map[string]bool {
+	keys := make(map[string]bool)
+	for _, info := range engine.Templates() {
+		keys[info.Path] = true
+	}
+	return keys
+}
+
+// renderSample 渲染模板并格式化为多行 SQL，参数附在末尾；渲染失败时返回错误信息
+func renderSample(engine *gosql.Engine, key string, args map[string]interface{}) string {
+	q, err := engine.GetSql(key, args)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	out := q.Pretty(gosql.DialectDefault).SQL
+	if len(q.Params) > 0 {
+		out += fmt.Sprintf("\n-- params: %v", q.Params)
+	}
+	return out
+}
+
+// diffLines 基于最长公共子序列的逐行差异，删除的行以 "-" 开头，新增的行以 "+" 开头，相同的行以空格开头
+func diffLines(a, b []string) []string {
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, " "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			out = append(out, "+"+b[j])
+			j++
+		default:
+			out = append(out, "-"+a[i])
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runFind gosql find：按名称、标签、描述、SQL 文本搜索模板
+func runFind(args []string) error {
+	fset := flag.NewFlagSet("find", flag.ExitOnError)
+	asJSON := fset.Bool("json", false, "以 JSON 输出结果")
+	limit := fset.Int("n", 20, "最多输出的结果数（0 表示不限制）")
+	fset.Parse(args)
+
+	if fset.NArg() < 1 {
+		return fmt.Errorf("find: missing query")
+	}
+
+	engine, err := loadEngine(fset.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	results := engine.Search(fset.Arg(0))
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	for _, r := range results {
+		desc := strings.SplitN(r.Template.Description, "\n", 2)[0]
+		fmt.Printf("%-40s %4d  %s\n", r.Template.Path, r.Score, desc)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// runGraph gosql graph：输出模板之间的 @use / @import 引用关系，
+// -template 指定时只输出与该模板相关的部分（它引用的模板和引用它的模板）
+func runGraph(args []string) error {
+	fset := flag.NewFlagSet("graph", flag.ExitOnError)
+	dot := fset.Bool("dot", false, "以 Graphviz DOT 格式输出")
+	focus := fset.String("template", "", "只输出与该模板相关的引用")
+	paths := parseFlags(fset, args)
+
+	engine, err := loadEngine(paths)
+	if err != nil {
+		return err
+	}
+	edges := engine.DependencyEdges()
+	if *focus != "" {
+		related := map[string]bool{templateOf(*focus): true}
+		for _, key := range engine.Dependencies(*focus) {
+			related[key] = true
+		}
+		for _, key := range engine.Dependents(*focus) {
+			related[key] = true
+		}
+		filtered := edges[:0]
+		for _, dep := range edges {
+			if related[dep.From] && related[templateOf(dep.To)] {
+				filtered = append(filtered, dep)
+			}
+		}
+		edges = filtered
+	}
+
+	if !*dot {
+		for _, dep := range edges {
+			fmt.Printf("%s -> %s (%s)\n", dep.From, dep.To, dep.Kind)
+		}
+		return nil
+	}
+	fmt.Println("digraph gosql {")
+	fmt.Println("  rankdir=LR;")
+	fmt.Println("  node [shape=box];")
+	if *focus != "" {
+		fmt.Printf("  %s [style=bold];\n", strconv.Quote(templateOf(*focus)))
+	}
+	for _, dep := range edges {
+		var attrs []string
+		if dep.Kind == "import" {
+			attrs = append(attrs, "style=dashed")
+		}
+		if to := templateOf(dep.To); to != dep.To {
+			attrs = append(attrs, "label="+strconv.Quote(dep.To[len(to)+1:]))
+		}
+		attr := ""
+		if len(attrs) > 0 {
+			attr = " [" + strings.Join(attrs, ", ") + "]"
+		}
+		fmt.Printf("  %s -> %s%s;\n", strconv.Quote(dep.From), strconv.Quote(templateOf(dep.To)), attr)
+	}
+	fmt.Println("}")
+	return nil
+}
+
+// templateOf 返回引用路径对应的模板路径（去掉 define 部分）
+func templateOf(path string) string {
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return path
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,620 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// runLSP gosql lsp：通过标准输入输出提供精简的 LSP 服务（诊断、@use 跳转定义、模板路径和变量补全），
+// 与运行时使用同一个解析器
+func runLSP(args []string) error {
+	fset := flag.NewFlagSet("lsp", flag.ExitOnError)
+	parseFlags(fset, args)
+
+	s := &lspServer{
+		docs: make(map[string]string),
+		good: make(map[string]string),
+		in:   bufio.NewReader(os.Stdin),
+		out:  os.Stdout,
+	}
+	return s.serve()
+}
+
+// lspServer LSP 服务状态：工作区中所有 markdown 文件的内容（打开的文件以编辑器中的内容为准）
+type lspServer struct {
+	docs     map[string]string // URI -> 内容
+	good     map[string]string // URI -> 最近一次能成功加载的内容，文件有错误时用于补全和跳转
+	in       *bufio.Reader
+	out      io.Writer
+	shutdown bool
+}
+
+// lspMessage JSON-RPC 消息
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+// lspError JSON-RPC 错误
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspPosition 文档中的位置（行、列均从 0 开始）
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange 文档中的范围
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspLocation 文档中的位置
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspDiagnostic 诊断信息
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"` // 1 error
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// lspCompletionItem 补全项
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"` // 6 variable, 9 module
+	Detail string `json:"detail,omitempty"`
+}
+
+// lspTextDocumentPosition 请求中的文档和位置
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// serve 循环处理请求，直到收到 exit 或输入结束
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp: exit without shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		resp := &lspMessage{JSONRPC: "2.0", ID: msg.ID, Result: result}
+		if err != nil {
+			resp.Result = nil
+			resp.Error = &lspError{Code: -32603, Message: err.Error()}
+		} else if result == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// read 读取一条带 Content-Length 头的消息
+func (s *lspServer) read() (*lspMessage, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("lsp: invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("lsp: missing Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	msg := &lspMessage{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("lsp: %w", err)
+	}
+	return msg, nil
+}
+
+// write 写出一条消息
+func (s *lspServer) write(msg *lspMessage) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// notify 发送通知
+func (s *lspServer) notify(method string, params interface{}) error {
+	bs, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&lspMessage{Method: method, Params: bs})
+}
+
+// handle 处理一条请求或通知，返回请求的结果
+func (s *lspServer) handle(msg *lspMessage) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			RootURI string `json:"rootUri"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if root := uriToPath(params.RootURI); root != "" {
+			s.loadWorkspace(root)
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // 全量同步
+				"definitionProvider": true,
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{"@", ".", "\""}},
+			},
+			"serverInfo": map[string]string{"name": "gosql"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics()
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+		}
+		return nil, s.publishDiagnostics()
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		// 关闭后以磁盘上的内容为准
+		if bs, err := os.ReadFile(uriToPath(params.TextDocument.URI)); err == nil {
+			s.docs[params.TextDocument.URI] = string(bs)
+		} else {
+			delete(s.docs, params.TextDocument.URI)
+		}
+		return nil, s.publishDiagnostics()
+	case "textDocument/definition":
+		var params lspTextDocumentPosition
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params.TextDocument.URI, params.Position), nil
+	case "textDocument/completion":
+		var params lspTextDocumentPosition
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params.TextDocument.URI, params.Position), nil
+	}
+	if msg.ID != nil && !strings.HasPrefix(msg.Method, "$/") {
+		return nil, fmt.Errorf("method not supported: %s", msg.Method)
+	}
+	return nil, nil
+}
+
+// loadWorkspace 读取工作区中所有 markdown 文件
+func (s *lspServer) loadWorkspace(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		if bs, err := os.ReadFile(path); err == nil {
+			s.docs[pathToURI(path)] = string(bs)
+		}
+		return nil
+	})
+}
+
+// build 用工作区中的所有文件构建引擎，返回每个文件的加载错误。
+// 加载失败的文件不影响其它文件，并退回到该文件最近一次能成功加载的内容
+func (s *lspServer) build() (*gosql.Engine, map[string]error) {
+	uris := make([]string, 0, len(s.docs))
+	for uri := range s.docs {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	engine := gosql.New()
+	errs := make(map[string]error)
+	load := func(uri, content string) error {
+		_, err := engine.LoadSource(gosql.SourceFunc(func() ([]gosql.NamedContent, error) {
+			return []gosql.NamedContent{{Name: uri, Content: content}}, nil
+		}))
+		return err
+	}
+	for _, uri := range uris {
+		if err := load(uri, s.docs[uri]); err != nil {
+			errs[uri] = err
+			if good, ok := s.good[uri]; ok {
+				load(uri, good)
+			}
+			continue
+		}
+		s.good[uri] = s.docs[uri]
+	}
+	return engine, errs
+}
+
+// publishDiagnostics 重新检查工作区，为每个文件发布诊断信息（没有问题的文件发布空列表以清除旧的诊断）
+func (s *lspServer) publishDiagnostics() error {
+	engine, errs := s.build()
+	templates := make(map[string]*gosql.TemplateInfo)
+	for _, info := range engine.Templates() {
+		templates[info.Path] = info
+	}
+	files := engine.Files()
+
+	uris := make([]string, 0, len(s.docs))
+	for uri := range s.docs {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		doc := scanDoc(s.docs[uri])
+		diags := []lspDiagnostic{}
+		if err := errs[uri]; err != nil {
+			diags = append(diags, lspDiagnostic{
+				Range:    lineRange(doc.errorLine(err)),
+				Severity: 1,
+				Code:     string(gosql.CodeOf(err)),
+				Source:   "gosql",
+				Message:  strings.TrimPrefix(err.Error(), uri+": "),
+			})
+		}
+		for _, key := range files[uri] {
+			info := templates[key]
+			if info == nil {
+				continue
+			}
+			for _, use := range info.Uses {
+				if msg := checkUse(templates, use); msg != "" {
+					diags = append(diags, lspDiagnostic{
+						Range:    doc.find(key, use),
+						Severity: 1,
+						Source:   "gosql",
+						Message:  msg,
+					})
+				}
+			}
+		}
+		if err := s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri":         uri,
+			"diagnostics": diags,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUse 检查 @use 路径能否找到对应的模板和 define，找不到时返回错误信息
+func checkUse(templates map[string]*gosql.TemplateInfo, path string) string {
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return "invalid use path: " + path
+	}
+	info, ok := templates[parts[0]+"."+parts[1]]
+	if !ok {
+		return "template not found: " + parts[0] + "." + parts[1]
+	}
+	if len(parts) == 3 {
+		for _, define := range info.Defines {
+			if define == parts[2] || strings.HasSuffix(define, "."+parts[2]) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("define %s not found in template %s", parts[2], info.Path)
+	}
+	return ""
+}
+
+// definition 跳转到光标处 @use / @import / @include 路径引用的模板（或 define）
+func (s *lspServer) definition(uri string, pos lspPosition) interface{} {
+	doc := scanDoc(s.docs[uri])
+	if pos.Line >= len(doc.lines) {
+		return nil
+	}
+	line := doc.lines[pos.Line]
+	if !strings.Contains(line, "@use") && !strings.Contains(line, "@import") && !strings.Contains(line, "@include") {
+		return nil
+	}
+	path := wordAt(line, pos.Character)
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+	key := parts[0] + "." + parts[1]
+	for target, content := range s.docs {
+		d := scanDoc(content)
+		t, ok := d.templates[key]
+		if !ok {
+			continue
+		}
+		line := t.heading
+		if len(parts) == 3 {
+			if l, ok := d.define(key, parts[2]); ok {
+				line = l
+			}
+		}
+		return lspLocation{URI: target, Range: lineRange(line)}
+	}
+	return nil
+}
+
+// completion 补全 @use / @import / @include 之后的模板路径，以及 @ 之后的变量名
+func (s *lspServer) completion(uri string, pos lspPosition) interface{} {
+	doc := scanDoc(s.docs[uri])
+	if pos.Line >= len(doc.lines) {
+		return []lspCompletionItem{}
+	}
+	line := doc.lines[pos.Line]
+	if pos.Character < len(line) {
+		line = line[:pos.Character]
+	}
+	engine, _ := s.build()
+	items := []lspCompletionItem{}
+
+	if m := usePrefix.FindStringSubmatch(line); m != nil {
+		for _, info := range engine.Templates() {
+			items = append(items, lspCompletionItem{Label: info.Path, Kind: 9, Detail: firstLine(info.Description)})
+			for _, define := range info.Defines {
+				items = append(items, lspCompletionItem{Label: info.Path + "." + define[strings.LastIndex(define, ".")+1:], Kind: 9, Detail: "define"})
+			}
+		}
+		return items
+	}
+	if !varPrefix.MatchString(line) {
+		return items
+	}
+	key := doc.templateAt(pos.Line)
+	for _, info := range engine.Templates() {
+		if info.Path != key {
+			continue
+		}
+		for _, p := range info.Params {
+			items = append(items, lspCompletionItem{Label: p.Name, Kind: 6, Detail: "param of " + key})
+		}
+	}
+	return items
+}
+
+var (
+	// usePrefix 光标位于 @use / @import / @include 之后的路径中
+	usePrefix = regexp.MustCompile(`@(use|import|include)\s*\(?\s*"?[\w.]*$`)
+	// varPrefix 光标位于 @ 之后的变量名中
+	varPrefix = regexp.MustCompile(`@=?\w*$`)
+	// lineNumber 错误信息中的行号
+	lineNumber = regexp.MustCompile(`(?:template (\S+): )?line (\d+)`)
+	// defineDecl define 声明
+	defineDecl = regexp.MustCompile(`@define\s*\(?\s*"?(\w+)`)
+)
+
+// lspDoc 文档中模板的位置
+type lspDoc struct {
+	lines     []string
+	templates map[string]*lspTemplate
+	order     []*lspTemplate // 按出现顺序
+}
+
+// lspTemplate 模板在文档中的位置（行号从 0 开始）
+type lspTemplate struct {
+	key     string
+	heading int // ## 标题所在行
+	sql     int // SQL 代码块第一行
+	end     int // 下一个模板标题之前的最后一行
+}
+
+// scanDoc 按 markdown 标题和代码块定位模板（只用于编辑器定位，解析仍以 gosql.ParseMarkdown 为准）
+func scanDoc(content string) *lspDoc {
+	doc := &lspDoc{lines: strings.Split(content, "\n"), templates: make(map[string]*lspTemplate)}
+	namespace := ""
+	var current *lspTemplate
+	inFence := false
+	for i, line := range doc.lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if !inFence && current != nil && current.sql < 0 {
+				current.sql = i + 1
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "# "):
+			namespace = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "## "):
+			if current != nil {
+				current.end = i - 1
+			}
+			current = &lspTemplate{key: namespace + "." + strings.TrimSpace(line[3:]), heading: i, sql: -1}
+			doc.templates[current.key] = current
+			doc.order = append(doc.order, current)
+		}
+	}
+	if current != nil {
+		current.end = len(doc.lines) - 1
+	}
+	return doc
+}
+
+// templateAt 返回行所在的模板
+func (d *lspDoc) templateAt(line int) string {
+	for _, t := range d.order {
+		if line >= t.heading && line <= t.end {
+			return t.key
+		}
+	}
+	return ""
+}
+
+// errorLine 把错误信息中的行号映射为文档中的行（模板错误的行号相对于 SQL 代码块）
+func (d *lspDoc) errorLine(err error) int {
+	m := lineNumber.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[2])
+	if m[1] == "" {
+		return n - 1
+	}
+	if t, ok := d.templates[m[1]]; ok && t.sql >= 0 {
+		return t.sql + n - 1
+	}
+	return 0
+}
+
+// find 返回模板中第一次出现 text 的范围，找不到时返回模板标题
+func (d *lspDoc) find(key, text string) lspRange {
+	t, ok := d.templates[key]
+	if !ok {
+		return lineRange(0)
+	}
+	for i := t.heading; i <= t.end && i < len(d.lines); i++ {
+		if col := strings.Index(d.lines[i], text); col >= 0 {
+			return lspRange{Start: lspPosition{i, col}, End: lspPosition{i, col + len(text)}}
+		}
+	}
+	return lineRange(t.heading)
+}
+
+// define 返回模板中 define 声明所在的行
+func (d *lspDoc) define(key, name string) (int, bool) {
+	t := d.templates[key]
+	for i := t.heading; i <= t.end && i < len(d.lines); i++ {
+		for _, m := range defineDecl.FindAllStringSubmatch(d.lines[i], -1) {
+			if m[1] == name {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// lineRange 整行的范围
+func lineRange(line int) lspRange {
+	if line < 0 {
+		line = 0
+	}
+	return lspRange{Start: lspPosition{line, 0}, End: lspPosition{line + 1, 0}}
+}
+
+// wordAt 返回光标处由字母、数字、_ 和 . 组成的单词
+func wordAt(line string, col int) string {
+	isWord := func(c byte) bool {
+		return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	if col > len(line) {
+		col = len(line)
+	}
+	start, end := col, col
+	for start > 0 && isWord(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	return strings.Trim(line[start:end], ".")
+}
+
+// firstLine 返回文本的第一行
+func firstLine(s string) string {
+	return strings.SplitN(strings.TrimSpace(s), "\n", 2)[0]
+}
+
+// uriToPath 把 file:// URI 转为本地路径
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathToURI 把本地路径转为 file:// URI
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
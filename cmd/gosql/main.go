@@ -0,0 +1,127 @@
+// gosql 命令行工具：对 markdown SQL 模板进行检索、分析等操作
+//
+// 用法：
+//
+//	gosql <command> [flags] [args]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// command 子命令
+type command struct {
+	usage string // 用法说明
+	run   func(args []string) error
+}
+
+// commands 所有子命令
+var commands = map[string]*command{
+	"compat":  {usage: "compat -baseline rendered.json [-update -args samples.json] [path...]  与基线比较渲染结果", run: runCompat},
+	"defines": {usage: "defines [-json] [path...]  列出未被引用的 define 和目标不存在的 @cover", run: runDefines},
+	"diff":    {usage: "diff [-args samples.json] <old> <new>  比较两个版本的模板渲染出的 SQL", run: runDiff},
+	"find":    {usage: "find [-json] [-n limit] <query> [path...]  搜索模板", run: runFind},
+	"graph":   {usage: "graph [-dot] [-template path] [path...]  输出模板之间的引用关系", run: runGraph},
+	"lsp":     {usage: "lsp  通过标准输入输出提供 LSP 服务（诊断、跳转定义、补全）", run: runLSP},
+	"pack":    {usage: "pack [-o templates.bundle] [-sign key] [dir...]  打包模板", run: runPack},
+	"serve":   {usage: "serve [-addr :8080] [-token t] [-rpc :9090] [path...]  以 HTTP 服务提供模板渲染", run: runServe},
+	"unused":  {usage: "unused -usage usage.json [path...]  列出从未被渲染过的模板", run: runUnused},
+	"verify":  {usage: "verify [-args samples.json] [path...]  比较解释执行与编译执行的渲染结果", run: runVerify},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gosql: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "gosql:", err)
+		os.Exit(1)
+	}
+}
+
+// usage 输出帮助信息
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: gosql <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+}
+
+// parseFlags 解析参数，允许标志出现在位置参数之后（如 gosql diff old.md new.md -args samples.json），
+// 返回所有位置参数
+func parseFlags(fset *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fset.Parse(args)
+		args = fset.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			// -- 之后全部视为位置参数
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// loadEngine 加载路径（文件或目录）下的所有 markdown 模板，未指定路径时使用当前目录
+func loadEngine(paths []string, opts ...gosql.Option) (*gosql.Engine, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files []string
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	engine := gosql.New(opts...)
+	for _, file := range files {
+		bs, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := engine.LoadMarkdown(string(bs)); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if err := engine.Validate(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// runPack gosql pack：把目录下的 markdown 模板打包为单个模板包（带清单和哈希），
+// 服务端用 Engine.LoadBundle 加载
+func runPack(args []string) error {
+	fset := flag.NewFlagSet("pack", flag.ExitOnError)
+	output := fset.String("o", "templates.bundle", "输出文件")
+	keyFile := fset.String("sign", "", "ed25519 私钥文件（base64 编码的 seed 或私钥），指定时为每个文件签名")
+	roots := parseFlags(fset, args)
+
+	var key ed25519.PrivateKey
+	if *keyFile != "" {
+		var err error
+		if key, err = readPrivateKey(*keyFile); err != nil {
+			return err
+		}
+	}
+
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	var files []gosql.NamedContent
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+				return nil
+			}
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(root, path)
+			if err != nil || name == "." {
+				name = filepath.Base(path)
+			}
+			file := gosql.NamedContent{Name: filepath.ToSlash(name), Content: string(bs)}
+			if key != nil {
+				file.Signature = ed25519.Sign(key, bs)
+			}
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 打包前先确认所有模板都能加载
+	engine := gosql.New()
+	if _, err := engine.LoadSource(gosql.SourceFunc(func() ([]gosql.NamedContent, error) {
+		return files, nil
+	})); err != nil {
+		return err
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := gosql.WriteBundle(out, files); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("packed %d files into %s\n", len(files), *output)
+	return nil
+}
+
+// readPrivateKey 读取 base64 编码的 ed25519 seed（32 字节）或私钥（64 字节）
+func readPrivateKey(file string) (ed25519.PrivateKey, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(bs)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("%s: invalid ed25519 key size %d", file, len(raw))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/llyb120/gosql/gosqlrpc"
+	"github.com/llyb120/gosql/gosqlweb"
+	"google.golang.org/grpc"
+)
+
+// runServe gosql serve：以 HTTP 服务的形式提供模板渲染（GET /templates、POST /render/{path}），
+// 供非 Go 服务和 BI 工具使用同一套模板
+func runServe(args []string) error {
+	fset := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fset.String("addr", ":8080", "监听地址")
+	token := fset.String("token", os.Getenv("GOSQL_TOKEN"), "要求请求带有 Authorization: Bearer <token>（默认读取环境变量 GOSQL_TOKEN，为空时不鉴权）")
+	rpcAddr := fset.String("rpc", "", "同时在该地址提供 gRPC 服务（见 gosqlrpc，设置了 -token 时同样要求 authorization: Bearer <token>）")
+	paths := parseFlags(fset, args)
+
+	engine, err := loadEngine(paths)
+	if err != nil {
+		return err
+	}
+
+	var opts []gosqlweb.APIOption
+	if *token != "" {
+		opts = append(opts, gosqlweb.WithAuth(bearerAuth(*token)))
+	}
+	if *rpcAddr != "" {
+		lis, err := net.Listen("tcp", *rpcAddr)
+		if err != nil {
+			return err
+		}
+		defer lis.Close()
+		var rpcOpts []grpc.ServerOption
+		if *token != "" {
+			rpcOpts = append(rpcOpts, grpc.UnaryInterceptor(gosqlrpc.TokenAuth(*token)))
+		}
+		go func() {
+			if err := gosqlrpc.Serve(lis, engine, rpcOpts...); err != nil {
+				fmt.Fprintln(os.Stderr, "gosql: rpc:", err)
+			}
+		}()
+		fmt.Printf("serving rpc on %s\n", *rpcAddr)
+	}
+	fmt.Printf("serving %d templates on %s\n", len(engine.Templates()), *addr)
+	return http.ListenAndServe(*addr, gosqlweb.API(engine, opts...))
+}
+
+// bearerAuth 校验 Authorization: Bearer <token>
+func bearerAuth(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return errors.New("invalid or missing bearer token")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runUnused gosql unused：结合服务导出的 Engine.Usage() 统计，列出从未被渲染过的模板
+// 可以传入多个实例导出的统计文件，任一实例渲染过的模板都视为已使用
+func runUnused(args []string) error {
+	fset := flag.NewFlagSet("unused", flag.ExitOnError)
+	var usageFiles stringList
+	fset.Var(&usageFiles, "usage", "Engine.Usage() 导出的 JSON 文件（可重复指定）")
+	fset.Parse(args)
+
+	if len(usageFiles) == 0 {
+		return fmt.Errorf("unused: missing -usage file")
+	}
+
+	used := make(map[string]bool)
+	for _, file := range usageFiles {
+		bs, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var usage map[string]int64
+		if err := json.Unmarshal(bs, &usage); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for key, n := range usage {
+			if n > 0 {
+				used[key] = true
+			}
+		}
+	}
+
+	engine, err := loadEngine(fset.Args())
+	if err != nil {
+		return err
+	}
+	for _, info := range engine.Templates() {
+		if !used[info.Path] {
+			fmt.Println(info.Path)
+		}
+	}
+	return nil
+}
+
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
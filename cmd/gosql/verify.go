@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+// runVerify gosql verify：用示例参数把每个模板分别解释执行和编译执行，输出结果不一致的模板，
+// 用于在切换到编译执行（GetSqlCompiled）之前确认两者等价
+func runVerify(args []string) error {
+	fset := flag.NewFlagSet("verify", flag.ExitOnError)
+	samplesFile := fset.String("args", "", "示例参数 JSON 文件（格式同 gosql diff）")
+	paths := parseFlags(fset, args)
+
+	samples, err := loadSamples(*samplesFile)
+	if err != nil {
+		return err
+	}
+	engine, err := loadEngine(paths)
+	if err != nil {
+		return err
+	}
+
+	corpus := make(map[string][]interface{}, len(samples))
+	for key, list := range samples {
+		for _, sample := range list {
+			corpus[key] = append(corpus[key], sample)
+		}
+	}
+	mismatches := engine.VerifyCompiled(corpus)
+	for _, m := range mismatches {
+		sample, _ := json.Marshal(m.Args)
+		fmt.Printf("%s %s\n%s\n\n", m.Path, sample, m.Diff())
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("verify: %d mismatches between interpreted and compiled rendering", len(mismatches))
+	}
+	fmt.Println("interpreted and compiled rendering agree")
+	return nil
+}
@@ -0,0 +1,221 @@
+package gosql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paramTypes 模板可以声明的参数类型（param: 元数据），值为转换函数
+var paramTypes = map[string]func(v interface{}) (interface{}, error){
+	"int":    coerceInt,
+	"float":  coerceFloat,
+	"bool":   coerceBool,
+	"string": coerceString,
+	"time":   coerceTime,
+}
+
+// compileParamTypes 解析模板描述中声明的参数类型，每行一个参数：
+//
+//	param: id int
+//	param: ids []int
+//	param: since time
+//
+// 支持 int、float、bool、string、time 以及它们的切片（[]int 等）
+func compileParamTypes(tmpl *SQLTemplate) (map[string]string, error) {
+	raws := tmpl.Meta["param"]
+	if len(raws) == 0 {
+		return nil, nil
+	}
+	types := make(map[string]string, len(raws))
+	for _, raw := range raws {
+		fields := strings.Fields(raw)
+		if len(fields) != 2 {
+			return nil, codeError(CodeMarkdown, "invalid param declaration %q, expected \"name type\"", raw)
+		}
+		if _, ok := paramTypes[strings.TrimPrefix(fields[1], "[]")]; !ok {
+			return nil, codeError(CodeMarkdown, "invalid param declaration %q: unknown type %s", raw, fields[1])
+		}
+		types[fields[0]] = fields[1]
+	}
+	return types, nil
+}
+
+// coerceArgs 把 map 参数中声明了类型的参数转换为声明的类型（返回新的 map，不修改调用方的参数）。
+// 只处理 map[string]interface{} 参数（通常来自 JSON），其它参数原样返回
+func (e *Engine) coerceArgs(path string, args interface{}) (interface{}, error) {
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return args, nil
+	}
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return args, nil
+	}
+	ast, ok := e.compiledAST[parts[0]+"."+parts[1]]
+	if !ok || len(ast.paramTypes) == 0 {
+		return args, nil
+	}
+	var out map[string]interface{}
+	for name, typ := range ast.paramTypes {
+		v, ok := m[name]
+		if !ok || v == nil {
+			continue
+		}
+		converted, err := coerceValue(v, typ)
+		if err != nil {
+			return nil, codeError(CodeValidation, "invalid args for %s: %s: %v", path, name, err)
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[name] = converted
+	}
+	if out == nil {
+		return args, nil
+	}
+	return out, nil
+}
+
+// coerceValue 把 v 转换为 typ（切片类型逐个元素转换）
+func coerceValue(v interface{}, typ string) (interface{}, error) {
+	elem, isSlice := strings.CutPrefix(typ, "[]")
+	convert := paramTypes[elem]
+	if !isSlice {
+		return convert(v)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot convert %T to %s", v, typ)
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		item, err := convert(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		out[i] = item
+	}
+	return out, nil
+}
+
+// coerceInt 转换为 int64：整数、没有小数部分的浮点数、数字字符串
+func coerceInt(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case json.Number:
+		return coerceInt(string(x))
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+			return coerceInt(f)
+		}
+		return nil, fmt.Errorf("cannot convert %q to int", x)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%v overflows int", v)
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+			return nil, fmt.Errorf("cannot convert %v to int", v)
+		}
+		return int64(f), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to int", v)
+}
+
+// coerceFloat 转换为 float64：数字、数字字符串
+func coerceFloat(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case json.Number:
+		return coerceFloat(string(x))
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to float", x)
+		}
+		return f, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to float", v)
+}
+
+// coerceBool 转换为 bool：bool、"true" / "false" / "1" / "0" 等字符串、数字 0 / 1
+func coerceBool(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(x))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to bool", x)
+		}
+		return b, nil
+	}
+	if n, err := coerceInt(v); err == nil && (n == int64(0) || n == int64(1)) {
+		return n == int64(1), nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to bool", v)
+}
+
+// coerceString 转换为 string：字符串、数字、bool
+func coerceString(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case json.Number:
+		return string(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32:
+		return fmt.Sprint(v), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to string", v)
+}
+
+// timeLayouts 字符串转换为时间时依次尝试的格式
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// coerceTime 转换为 time.Time：time.Time、RFC 3339 / "2006-01-02 15:04:05" / "2006-01-02" 格式的字符串
+func coerceTime(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(x)); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot convert %q to time", x)
+	}
+	return nil, fmt.Errorf("cannot convert %T to time", v)
+}
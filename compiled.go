@@ -0,0 +1,447 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/llyb120/goscript2/interpreter"
+)
+
+// GetSqlCompiled 同 GetSql，但先用 Compiler 把模板（或 define）编译为 goscript2 程序再执行：
+// 控制结构由程序执行，文本、变量等输出与解释执行共用同一套逻辑，渲染结果与 GetSql 一致。
+// 不使用 Pin 和 pure 模板的渲染结果缓存
+func (e *Engine) GetSqlCompiled(path string, args interface{}) (Query, error) {
+	return e.renderCompiledLocked(path, args, true)
+}
+
+// renderCompiledLocked 加读锁执行模板编译出的程序，record 为 true 时计入模板的渲染次数
+func (e *Engine) renderCompiledLocked(path string, args interface{}, record bool) (Query, error) {
+	if e.limiter != nil {
+		e.limiter.acquire()
+		defer e.limiter.release()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	args, err := e.prepareArgs(path, args)
+	if err != nil {
+		return Query{}, err
+	}
+	return e.renderCompiled(path, args, record)
+}
+
+// renderCompiled 执行模板编译出的程序（首次渲染时编译并缓存）
+func (e *Engine) renderCompiled(path string, args interface{}, record bool) (Query, error) {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return Query{}, codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", path)
+	}
+	key := parts[0] + "." + parts[1]
+	ast, ok := e.compiledAST[key]
+	if !ok {
+		return Query{}, e.templateNotFound(key)
+	}
+	if record {
+		e.recordUsage(key)
+	}
+
+	programKey := key
+	if len(parts) > 2 {
+		programKey += "." + parts[2]
+	}
+	prog, ok := e.programs.get(programKey)
+	if !ok {
+		nodes := ast.Nodes
+		if len(parts) > 2 {
+			define := findDefine(nodes, parts[2])
+			if define == nil {
+				return Query{}, defineNotFound(parts[2], key, nodes)
+			}
+			nodes = define.Body
+		}
+		c := NewCompiler()
+		code, err := c.compileProgram(nodes)
+		if err != nil {
+			return Query{}, err
+		}
+		prog = &compiledProgram{code: code, nodes: c.Nodes()}
+		e.programs.put(programKey, prog)
+	}
+
+	ctx := newExecutionContext(e, args, ast)
+	ctx.placeholders = e.placeholders
+	if ctx.err != nil {
+		return Query{}, ctx.err
+	}
+	if err := ctx.runCompiled(prog.code, prog.nodes); err != nil {
+		return Query{}, err
+	}
+	if ctx.err != nil {
+		return Query{}, ctx.err
+	}
+	ctx.endSkip()
+	query := Query{SQL: ctx.sql.take()}
+	if err := ctx.emptyStatement(query.SQL); err != nil {
+		return Query{}, err
+	}
+	if len(ctx.args) > 0 {
+		query.Params = ctx.args
+	}
+	return e.finishQuery(query), nil
+}
+
+// compiledProgram Compiler 为一个模板（或 define）生成的程序和 __node__ 引用的节点表，执行时只读
+type compiledProgram struct {
+	code  string
+	nodes []Node
+}
+
+// programCache 按模板路径（namespace.name 或 namespace.name.define）缓存编译出的程序，
+// 渲染时持有引擎的读锁，并发渲染通过 mu 保护；模板加载后（linkTemplates）清空
+type programCache struct {
+	mu    sync.RWMutex
+	items map[string]*compiledProgram
+}
+
+// newProgramCache 创建程序缓存
+func newProgramCache() *programCache {
+	return &programCache{items: make(map[string]*compiledProgram)}
+}
+
+// get 返回缓存的程序
+func (c *programCache) get(path string) (*compiledProgram, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prog, ok := c.items[path]
+	return prog, ok
+}
+
+// put 缓存编译出的程序
+func (c *programCache) put(path string, prog *compiledProgram) {
+	c.mu.Lock()
+	c.items[path] = prog
+	c.mu.Unlock()
+}
+
+// reset 清空缓存
+func (c *programCache) reset() {
+	c.mu.Lock()
+	c.items = make(map[string]*compiledProgram)
+	c.mu.Unlock()
+}
+
+// len 返回缓存的程序个数
+func (c *programCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// CompiledMismatch VerifyCompiled 发现的解释执行与编译执行结果不一致的一次渲染
+type CompiledMismatch struct {
+	Path           string      // 模板路径
+	Args           interface{} // 示例参数
+	Interpreted    Query       // GetSql 的结果
+	InterpretedErr error
+	Compiled       Query // GetSqlCompiled 的结果
+	CompiledErr    error
+}
+
+// Diff 返回不一致之处的说明（每行一条）
+func (m CompiledMismatch) Diff() string {
+	var diffs []string
+	if errText(m.InterpretedErr) != errText(m.CompiledErr) {
+		diffs = append(diffs, fmt.Sprintf("error:\n  interpreted: %s\n  compiled:    %s", errText(m.InterpretedErr), errText(m.CompiledErr)))
+	}
+	if m.Interpreted.SQL != m.Compiled.SQL {
+		diffs = append(diffs, fmt.Sprintf("SQL:\n  interpreted: %q\n  compiled:    %q", m.Interpreted.SQL, m.Compiled.SQL))
+	}
+	if !reflect.DeepEqual(m.Interpreted.Params, m.Compiled.Params) {
+		diffs = append(diffs, fmt.Sprintf("params:\n  interpreted: %v\n  compiled:    %v", m.Interpreted.Params, m.Compiled.Params))
+	}
+	return strings.Join(diffs, "\n")
+}
+
+// errText 错误信息，nil 时为空串
+func errText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// VerifyCompiled 把每个模板分别通过解释执行（GetSql）和编译后的程序（GetSqlCompiled）渲染并比较结果
+// （SQL、参数和错误信息都要一致），用于在切换到编译执行之前确认两者等价。
+// samples 的 key 为模板路径（也可以是 "namespace.name.define"），value 为示例参数列表，
+// 没有示例参数的模板以 nil 参数渲染一次；不计入渲染次数。返回的不一致按路径排序
+func (e *Engine) VerifyCompiled(samples map[string][]interface{}) []CompiledMismatch {
+	paths := make(map[string]bool, len(samples))
+	for path := range samples {
+		paths[path] = true
+	}
+	e.mu.RLock()
+	for key := range e.compiledAST {
+		paths[key] = true
+	}
+	e.mu.RUnlock()
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var mismatches []CompiledMismatch
+	for _, path := range sorted {
+		list := samples[path]
+		if len(list) == 0 {
+			list = []interface{}{nil}
+		}
+		for _, args := range list {
+			m := CompiledMismatch{Path: path, Args: args}
+			m.Interpreted, _, m.InterpretedErr = e.render(context.Background(), path, args, false)
+			m.Compiled, m.CompiledErr = e.renderCompiledLocked(path, args, false)
+			if m.Diff() != "" {
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+	return mismatches
+}
+
+// compiledRuntime 编译后的程序调用的运行时函数的状态
+type compiledRuntime struct {
+	ctx      *executionContext   // 当前的执行上下文（函数块内为函数块的上下文）
+	nodes    []Node              // Compiler.Nodes()
+	err      error               // 第一个错误，之后的运行时函数都不再输出
+	captures []capture           // __capture__ 保存的输出
+	outer    []*executionContext // __funcBegin__ 保存的外层上下文
+}
+
+// compiledInterps 执行编译后程序的解释器。每次执行都重新绑定同一组运行时函数、重新声明 main，
+// 覆盖上一次执行留下的全局变量，所以解释器可以复用（执行期间独占，用完放回）
+var compiledInterps = sync.Pool{
+	New: func() interface{} { return interpreter.New() },
+}
+
+// runCompiled 执行 Compiler 生成的程序，输出写入 ctx
+func (ctx *executionContext) runCompiled(code string, nodes []Node) error {
+	rt := &compiledRuntime{ctx: ctx, nodes: nodes}
+	interp := compiledInterps.Get().(*interpreter.Interpreter)
+	rt.bind(interp)
+	_, err := interp.Eval(code)
+	if err == nil {
+		_, err = interp.EvalExpr("main()")
+	}
+	compiledInterps.Put(interp)
+	if rt.err != nil {
+		return rt.err
+	}
+	if err != nil {
+		return fmt.Errorf("compiled template: %w", err)
+	}
+	return nil
+}
+
+// fail 记录第一个错误
+func (rt *compiledRuntime) fail(err error) {
+	if rt.err == nil {
+		rt.err = err
+	}
+}
+
+// bind 把运行时函数绑定到解释器
+func (rt *compiledRuntime) bind(interp *interpreter.Interpreter) {
+	exec := func(fn func(ctx *executionContext) error) {
+		if rt.err == nil {
+			rt.fail(fn(rt.ctx))
+		}
+	}
+	interp.BindFunc("__text__", func(text string) {
+		if rt.err == nil {
+			rt.ctx.writeText(text)
+		}
+	})
+	interp.BindFunc("__var__", func(name string) {
+		exec(func(ctx *executionContext) error { return ctx.executeVarNode(&VarNode{Name: name}) })
+	})
+	interp.BindFunc("__varIf__", func(name string) {
+		exec(func(ctx *executionContext) error { return ctx.executeVarNode(&VarNode{Name: name, Conditional: true}) })
+	})
+	interp.BindFunc("__bind__", func(expr string) {
+		exec(func(ctx *executionContext) error { return ctx.executeVarExprNode(&VarExprNode{Expr: expr}) })
+	})
+	interp.BindFunc("__bindIf__", func(expr string) {
+		exec(func(ctx *executionContext) error {
+			return ctx.executeVarExprNode(&VarExprNode{Expr: expr, Conditional: true})
+		})
+	})
+	interp.BindFunc("__raw__", func(name string) {
+		exec(func(ctx *executionContext) error { return ctx.executeRawNode(&RawNode{Name: name}) })
+	})
+	interp.BindFunc("__rawIf__", func(name string) {
+		exec(func(ctx *executionContext) error { return ctx.executeRawNode(&RawNode{Name: name, Conditional: true}) })
+	})
+	interp.BindFunc("__rawExpr__", func(expr string) {
+		exec(func(ctx *executionContext) error { return ctx.executeRawExprNode(&RawExprNode{Expr: expr}) })
+	})
+	interp.BindFunc("__rawExprIf__", func(expr string) {
+		exec(func(ctx *executionContext) error {
+			return ctx.executeRawExprNode(&RawExprNode{Expr: expr, Conditional: true})
+		})
+	})
+	interp.BindFunc("__node__", func(k int) {
+		exec(func(ctx *executionContext) error { return ctx.executeNode(rt.nodes[k]) })
+	})
+	interp.BindFunc("__cond__", func(condition string) bool {
+		if rt.err != nil {
+			return false
+		}
+		result, err := rt.ctx.evalCondition(condition)
+		rt.fail(err)
+		return result && err == nil
+	})
+	interp.BindFunc("__flag__", func(name string) bool {
+		if rt.err != nil {
+			return false
+		}
+		enabled := rt.ctx.flagEnabled(name)
+		rt.ctx.tracef("flag", "flag %q: %v", name, enabled)
+		return enabled
+	})
+	interp.BindFunc("__for__", func(expr string) *compiledLoop {
+		l := &compiledLoop{rt: rt}
+		if rt.err == nil {
+			loop, err := rt.ctx.newForLoop(expr)
+			rt.fail(err)
+			if err == nil {
+				l.loop = loop
+			}
+		}
+		return l
+	})
+	interp.BindFunc("__foreach__", func(k int) *compiledLoop {
+		l := &compiledLoop{rt: rt}
+		if rt.err == nil {
+			loop, err := rt.ctx.newForEachLoop(rt.nodes[k].(*ForEachNode))
+			rt.fail(err)
+			if err == nil {
+				l.loop = loop
+			}
+		}
+		return l
+	})
+	interp.BindFunc("__switch__", func(expr string) *compiledSwitch {
+		s := &compiledSwitch{rt: rt}
+		if rt.err == nil {
+			value, err := rt.ctx.evalExpr(expr)
+			if err != nil {
+				rt.fail(fmt.Errorf("switch expression error: %w", err))
+			}
+			s.value = value
+		}
+		return s
+	})
+	interp.BindFunc("__capture__", func() {
+		if rt.err == nil {
+			rt.captures = append(rt.captures, rt.ctx.beginCapture())
+		}
+	})
+	interp.BindFunc("__set__", func() {
+		if rt.err == nil {
+			rt.ctx.writeSet(rt.endCapture())
+		}
+	})
+	interp.BindFunc("__trim__", func(prefix, suffix string) {
+		if rt.err == nil {
+			body, args := rt.endCapture()
+			rt.ctx.writeTrim(prefix, suffix, body, args)
+		}
+	})
+	interp.BindFunc("__funcBegin__", func() {
+		if rt.err == nil {
+			rt.outer = append(rt.outer, rt.ctx)
+			rt.ctx = rt.ctx.funcBlockContext()
+		}
+	})
+	interp.BindFunc("__funcEnd__", func(funcExpr string) {
+		if rt.err == nil {
+			sub := rt.ctx
+			rt.ctx = rt.outer[len(rt.outer)-1]
+			rt.outer = rt.outer[:len(rt.outer)-1]
+			sub.endSkip()
+			rt.fail(rt.ctx.applyFuncBlock(funcExpr, sub.sql.take(), sub.args))
+		}
+	})
+	interp.BindFunc("__define__", func(name string) bool {
+		if rt.err != nil {
+			return false
+		}
+		ctx := rt.ctx
+		if _, body, covered := ctx.coverFor(name); covered {
+			// cover 在运行时才确定（@use 传入），由执行器输出
+			rt.fail(ctx.executeNodes(body))
+			return false
+		}
+		ctx.definePath = append(ctx.definePath, name)
+		return true
+	})
+	interp.BindFunc("__defineEnd__", func() {
+		if rt.err == nil {
+			rt.ctx.definePath = rt.ctx.definePath[:len(rt.ctx.definePath)-1]
+		}
+	})
+}
+
+// endCapture 结束最近一次 __capture__
+func (rt *compiledRuntime) endCapture() (string, []interface{}) {
+	c := rt.captures[len(rt.captures)-1]
+	rt.captures = rt.captures[:len(rt.captures)-1]
+	return rt.ctx.endCapture(c)
+}
+
+// compiledLoop 编译后的程序中的 @for / @foreach 循环，出错后不再继续
+type compiledLoop struct {
+	rt   *compiledRuntime
+	loop interface{ Next() bool }
+}
+
+// Next 开始下一次循环
+func (l *compiledLoop) Next() bool {
+	if l.loop == nil || l.rt.err != nil {
+		return false
+	}
+	if l.loop.Next() {
+		return true
+	}
+	if loop, ok := l.loop.(*forLoop); ok && loop.err != nil {
+		l.rt.fail(loop.err)
+	}
+	return false
+}
+
+// compiledSwitch 编译后的程序中的 @switch，保存只求值一次的表达式的值
+type compiledSwitch struct {
+	rt    *compiledRuntime
+	value interface{}
+}
+
+// Case 判断候选值是否与 switch 的值相等
+func (s *compiledSwitch) Case(expr string) bool {
+	if s.rt.err != nil {
+		return false
+	}
+	candidate, err := s.rt.ctx.evalExpr(expr)
+	if err != nil {
+		s.rt.fail(fmt.Errorf("case expression error: %w", err))
+		return false
+	}
+	return switchEqual(s.value, candidate)
+}
+
+// Default 只有 default 分支时使用，没有出错时返回 true
+func (s *compiledSwitch) Default() bool {
+	return s.rt.err == nil
+}
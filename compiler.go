@@ -7,11 +7,15 @@ import (
 	"strings"
 )
 
-// Compiler 将 AST 编译为 Go 代码
+// Compiler 将 AST 编译为 goscript2 程序：@if、@for、条件行等控制结构编译为 Go 语句，
+// 文本、变量等输出编译为对运行时函数（__text__、__var__ 等）的调用，运行时函数由 Engine.GetSqlCompiled 绑定，
+// 与解释执行共用同一套输出逻辑，渲染结果与 GetSql 一致。
+// @use、@import、@rows、@{} 和自定义指令委托给执行器，编译为 __node__(k)，k 为节点在 Nodes() 中的下标
 type Compiler struct {
 	code       strings.Builder
 	indent     int
 	varCounter int
+	nodes      []Node // __node__、__foreach__ 引用的节点
 }
 
 // NewCompiler 创建编译器
@@ -22,70 +26,37 @@ func NewCompiler() *Compiler {
 	}
 }
 
-// Compile 编译 AST 为 Go 代码
+// Compile 编译 AST 为完整的程序（main 函数中按顺序输出整个模板）
 func (c *Compiler) Compile(ast *TemplateAST) (string, error) {
-	c.code.Reset()
+	return c.compileProgram(ast.Nodes)
+}
 
-	// 生成代码头部
+// compileProgram 把节点编译为完整的程序
+func (c *Compiler) compileProgram(nodes []Node) (string, error) {
+	c.reset()
 	c.writeLine("package main")
 	c.writeLine("")
-	c.writeLine("import \"reflect\"")
-	c.writeLine("")
-	
-	// 声明输出变量
-	c.writeLine("var __sql__ strings.Builder")
-	c.writeLine("var __args__ []interface{}")
-	c.writeLine("")
-	
-	// 生成辅助函数
-	c.generateHelperFunctions()
-	c.writeLine("")
-
-	// 编译节点
-	if err := c.compileNodes(ast.Nodes); err != nil {
+	c.writeLine("func main() {")
+	c.indent++
+	if err := c.compileNodes(nodes); err != nil {
 		return "", err
 	}
-
-	return c.code.String(), nil
-}
-
-// generateHelperFunctions 生成辅助函数
-func (c *Compiler) generateHelperFunctions() {
-	// __appendArg__ 函数：添加参数（支持数组展开）
-	c.writeLine("func __appendArg__(v interface{}) {")
-	c.indent++
-	c.writeLine("rv := reflect.ValueOf(v)")
-	c.writeLine("if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {")
-	c.indent++
-	c.writeLine("for i := 0; i < rv.Len(); i++ {")
-	c.indent++
-	c.writeLine("if i > 0 {")
-	c.indent++
-	c.writeLine("__sql__.WriteString(\", \")")
-	c.indent--
-	c.writeLine("}")
-	c.writeLine("__sql__.WriteString(\"?\")")
-	c.writeLine("__args__ = append(__args__, rv.Index(i).Interface())")
-	c.indent--
-	c.writeLine("}")
-	c.indent--
-	c.writeLine("} else {")
-	c.indent++
-	c.writeLine("__sql__.WriteString(\"?\")")
-	c.writeLine("__args__ = append(__args__, v)")
-	c.indent--
-	c.writeLine("}")
 	c.indent--
 	c.writeLine("}")
+	return c.code.String(), nil
+}
 
-	c.writeLine("")
+// Nodes 返回最近一次编译中委托给执行器的节点
+func (c *Compiler) Nodes() []Node {
+	return c.nodes
+}
 
-	// __appendRaw__ 函数：直接添加值
-	c.writeLine("func __appendRaw__(v interface{}) {")
-	c.indent++
-	c.writeLine("__sql__.WriteString(fmt.Sprintf(\"%v\", v))")
-	c.indent--
-	c.writeLine("}")
+// reset 清空上一次编译的结果
+func (c *Compiler) reset() {
+	c.code.Reset()
+	c.indent = 0
+	c.varCounter = 0
+	c.nodes = nil
 }
 
 // compileNodes 编译节点列表
@@ -115,147 +86,232 @@ func (c *Compiler) compileNode(node Node) error {
 		return c.compileIf(n)
 	case *ForNode:
 		return c.compileFor(n)
-	case *CodeNode:
-		return c.compileCode(n)
-	case *UseNode:
-		return c.compileUse(n)
+	case *ForEachNode:
+		return c.compileForEach(n)
+	case *SwitchNode:
+		return c.compileSwitch(n)
+	case *FlagNode:
+		return c.compileFlag(n)
+	case *ConditionalLineNode:
+		return c.compileConditionalLine(n)
+	case *SetNode:
+		return c.compileCapture(n.Body, "__set__()")
+	case *TrimNode:
+		return c.compileCapture(n.Body, fmt.Sprintf("__trim__(%s, %s)", strconv.Quote(n.Prefix), strconv.Quote(n.Suffix)))
+	case *FuncBlockNode:
+		return c.compileFuncBlock(n)
 	case *DefineNode:
 		return c.compileDefine(n)
+	case *CodeNode, *UseNode, *ImportNode, *RowsNode, *DirectiveNode:
+		c.writeLine(fmt.Sprintf("__node__(%d)", c.addNode(node)))
+		return nil
 	default:
 		return fmt.Errorf("unknown node type: %T", node)
 	}
 }
 
+// addNode 把节点加入节点表，返回它的下标
+func (c *Compiler) addNode(node Node) int {
+	c.nodes = append(c.nodes, node)
+	return len(c.nodes) - 1
+}
+
+// nextVar 生成程序内部使用的变量名
+func (c *Compiler) nextVar(prefix string) string {
+	c.varCounter++
+	return fmt.Sprintf("__%s%d__", prefix, c.varCounter)
+}
+
 // compileText 编译文本节点
 func (c *Compiler) compileText(n *TextNode) error {
 	// 转义字符串中的特殊字符
-	escaped := strconv.Quote(n.Text)
-	c.writeLine(fmt.Sprintf("__sql__.WriteString(%s)", escaped))
+	c.writeLine(fmt.Sprintf("__text__(%s)", strconv.Quote(n.Text)))
 	return nil
 }
 
 // compileVar 编译变量节点
 func (c *Compiler) compileVar(n *VarNode) error {
-	c.writeLine(fmt.Sprintf("__appendArg__(%s)", n.Name))
+	c.writeCall("__var__", n.Conditional, n.Name)
 	return nil
 }
 
 // compileVarExpr 编译变量表达式节点
 func (c *Compiler) compileVarExpr(n *VarExprNode) error {
-	c.writeLine(fmt.Sprintf("__appendArg__(%s)", n.Expr))
+	c.writeCall("__bind__", n.Conditional, n.Expr)
 	return nil
 }
 
 // compileRaw 编译直接输出变量节点
 func (c *Compiler) compileRaw(n *RawNode) error {
-	c.writeLine(fmt.Sprintf("__appendRaw__(%s)", n.Name))
+	c.writeCall("__raw__", n.Conditional, n.Name)
 	return nil
 }
 
 // compileRawExpr 编译直接输出表达式节点
 func (c *Compiler) compileRawExpr(n *RawExprNode) error {
-	c.writeLine(fmt.Sprintf("__appendRaw__(%s)", n.Expr))
+	c.writeCall("__rawExpr__", n.Conditional, n.Expr)
 	return nil
 }
 
+// writeCall 输出对运行时函数的调用，conditional 为 true 时调用带 ? 的版本（如 __varIf__）
+func (c *Compiler) writeCall(fn string, conditional bool, arg string) {
+	if conditional {
+		fn = strings.TrimSuffix(fn, "__") + "If__"
+	}
+	c.writeLine(fmt.Sprintf("%s(%s)", fn, strconv.Quote(arg)))
+}
+
 // compileIf 编译 if 节点
 func (c *Compiler) compileIf(n *IfNode) error {
-	c.writeLine(fmt.Sprintf("if %s {", n.Condition))
-	c.indent++
-	if err := c.compileNodes(n.Body); err != nil {
+	c.writeLine(fmt.Sprintf("if __cond__(%s) {", strconv.Quote(n.Condition)))
+	if err := c.compileBlock(n.Body); err != nil {
 		return err
 	}
-	c.indent--
 
 	for _, elseIf := range n.ElseIf {
-		c.writeLine(fmt.Sprintf("} else if %s {", elseIf.Condition))
-		c.indent++
-		if err := c.compileNodes(elseIf.Body); err != nil {
+		c.writeLine(fmt.Sprintf("} else if __cond__(%s) {", strconv.Quote(elseIf.Condition)))
+		if err := c.compileBlock(elseIf.Body); err != nil {
+			return err
+		}
+	}
+
+	if n.Else != nil {
+		c.writeLine("} else {")
+		if err := c.compileBlock(n.Else.Body); err != nil {
 			return err
 		}
-		c.indent--
 	}
 
+	c.writeLine("}")
+	return nil
+}
+
+// compileFlag 编译 @flag 节点
+func (c *Compiler) compileFlag(n *FlagNode) error {
+	c.writeLine(fmt.Sprintf("if __flag__(%s) {", strconv.Quote(n.Name)))
+	if err := c.compileBlock(n.Body); err != nil {
+		return err
+	}
 	if n.Else != nil {
 		c.writeLine("} else {")
-		c.indent++
-		if err := c.compileNodes(n.Else.Body); err != nil {
+		if err := c.compileBlock(n.Else.Body); err != nil {
 			return err
 		}
-		c.indent--
 	}
+	c.writeLine("}")
+	return nil
+}
 
+// compileConditionalLine 编译条件行节点
+func (c *Compiler) compileConditionalLine(n *ConditionalLineNode) error {
+	c.writeLine(fmt.Sprintf("if __cond__(%s) {", strconv.Quote(n.Condition)))
+	if err := c.compileBlock(n.LineNodes); err != nil {
+		return err
+	}
 	c.writeLine("}")
 	return nil
 }
 
-// compileFor 编译 for 节点
+// compileFor 编译 for 节点：循环变量由运行时设置，循环体中的表达式与解释执行一样从 scope 中取值
 func (c *Compiler) compileFor(n *ForNode) error {
-	c.writeLine(fmt.Sprintf("for %s {", n.Expr))
-	c.indent++
-	if err := c.compileNodes(n.Body); err != nil {
+	loop := c.nextVar("loop")
+	c.writeLine(fmt.Sprintf("for %s := __for__(%s); %s.Next(); {", loop, strconv.Quote(n.Expr), loop))
+	if err := c.compileBlock(n.Body); err != nil {
 		return err
 	}
-	c.indent--
 	c.writeLine("}")
 	return nil
 }
 
-// compileCode 编译直接代码节点
-func (c *Compiler) compileCode(n *CodeNode) error {
-	// 直接输出代码
-	lines := strings.Split(n.Code, "\n")
-	for _, line := range lines {
-		c.writeLine(strings.TrimSpace(line))
+// compileForEach 编译 foreach 节点
+func (c *Compiler) compileForEach(n *ForEachNode) error {
+	loop := c.nextVar("loop")
+	c.writeLine(fmt.Sprintf("for %s := __foreach__(%d); %s.Next(); {", loop, c.addNode(n), loop))
+	if err := c.compileBlock(n.Body); err != nil {
+		return err
 	}
+	c.writeLine("}")
 	return nil
 }
 
-// compileUse 编译 use 节点
-func (c *Compiler) compileUse(n *UseNode) error {
-	// use 会被上层处理，这里生成调用代码
-	// 调用 __useTemplate__(path, covers) 函数
-	c.varCounter++
-	coversVar := fmt.Sprintf("__covers_%d__", c.varCounter)
-	
-	c.writeLine(fmt.Sprintf("%s := make(map[string]func())", coversVar))
-	
-	for _, cover := range n.Covers {
-		c.varCounter++
-		funcName := fmt.Sprintf("__cover_func_%d__", c.varCounter)
-		
-		// 生成 cover 函数
-		c.writeLine(fmt.Sprintf("%s := func() {", funcName))
-		c.indent++
-		if err := c.compileNodes(cover.Body); err != nil {
+// compileSwitch 编译 switch 节点：表达式只求值一次，case 按顺序比较
+func (c *Compiler) compileSwitch(n *SwitchNode) error {
+	sw := c.nextVar("switch")
+	c.writeLine(fmt.Sprintf("%s := __switch__(%s)", sw, strconv.Quote(n.Expr)))
+	for i, cs := range n.Cases {
+		conds := make([]string, len(cs.Values))
+		for j, v := range cs.Values {
+			conds[j] = fmt.Sprintf("%s.Case(%s)", sw, strconv.Quote(v))
+		}
+		keyword := "if"
+		if i > 0 {
+			keyword = "} else if"
+		}
+		c.writeLine(fmt.Sprintf("%s %s {", keyword, strings.Join(conds, " || ")))
+		if err := c.compileBlock(cs.Body); err != nil {
+			return err
+		}
+	}
+	if n.Default != nil {
+		if len(n.Cases) == 0 {
+			c.writeLine(fmt.Sprintf("if %s.Default() {", sw))
+		} else {
+			c.writeLine("} else {")
+		}
+		if err := c.compileBlock(n.Default.Body); err != nil {
 			return err
 		}
-		c.indent--
+	}
+	if len(n.Cases) > 0 || n.Default != nil {
 		c.writeLine("}")
-		c.writeLine(fmt.Sprintf("%s[\"%s\"] = %s", coversVar, cover.Name, funcName))
 	}
+	return nil
+}
 
-	c.writeLine(fmt.Sprintf("__useTemplate__(\"%s\", %s)", n.Path, coversVar))
+// compileCapture 编译 set / trim 块：块内容单独收集，结束时由 end 输出
+func (c *Compiler) compileCapture(body []Node, end string) error {
+	c.writeLine("__capture__()")
+	if err := c.compileNodes(body); err != nil {
+		return err
+	}
+	c.writeLine(end)
 	return nil
 }
 
-// compileDefine 编译 define 节点
-func (c *Compiler) compileDefine(n *DefineNode) error {
-	// 检查是否有 cover 覆盖这个 define
-	c.writeLine(fmt.Sprintf("if __cover__, __ok__ := __covers__[\"%s\"]; __ok__ {", n.Name))
-	c.indent++
-	c.writeLine("__cover__()")
-	c.indent--
-	c.writeLine("} else {")
-	c.indent++
+// compileFuncBlock 编译函数块节点：块内容在单独的上下文中收集，结束时调用函数
+func (c *Compiler) compileFuncBlock(n *FuncBlockNode) error {
+	c.writeLine("__funcBegin__()")
 	if err := c.compileNodes(n.Body); err != nil {
 		return err
 	}
+	c.writeLine(fmt.Sprintf("__funcEnd__(%s)", strconv.Quote(n.FuncExpr)))
+	return nil
+}
+
+// compileDefine 编译 define 节点：被 cover 覆盖时运行时输出 cover 的内容并返回 false
+func (c *Compiler) compileDefine(n *DefineNode) error {
+	if len(n.Params) > 0 {
+		// 带参数的 define 相当于函数声明，只在被 @use 或直接渲染时输出
+		return nil
+	}
+	c.writeLine(fmt.Sprintf("if __define__(%s) {", strconv.Quote(n.Name)))
+	if err := c.compileBlock(n.Body); err != nil {
+		return err
+	}
+	c.indent++
+	c.writeLine("__defineEnd__()")
 	c.indent--
 	c.writeLine("}")
 	return nil
 }
 
+// compileBlock 编译缩进一级的语句块
+func (c *Compiler) compileBlock(nodes []Node) error {
+	c.indent++
+	defer func() { c.indent-- }()
+	return c.compileNodes(nodes)
+}
+
 // writeLine 写入一行代码
 func (c *Compiler) writeLine(line string) {
 	for i := 0; i < c.indent; i++ {
@@ -265,13 +321,10 @@ func (c *Compiler) writeLine(line string) {
 	c.code.WriteString("\n")
 }
 
-// CompileForExecution 编译为可执行的完整代码
+// CompileForExecution 编译为 main 函数体中的语句（不含 package 声明和 main 函数）
 func (c *Compiler) CompileForExecution(ast *TemplateAST, hasUse bool) (string, error) {
-	c.code.Reset()
-	c.varCounter = 0
+	c.reset()
 
-	// 不需要 package 声明，goscript2 会自动处理
-	
 	// 编译节点
 	if err := c.compileNodes(ast.Nodes); err != nil {
 		return "", err
@@ -296,21 +349,23 @@ func isSliceOrArray(v interface{}) bool {
 	return kind == reflect.Slice || kind == reflect.Array
 }
 
-// expandArgs 展开数组参数
-func expandArgs(v interface{}) (placeholders string, args []interface{}) {
+// expandArgs 展开数组参数，start 为之前已经绑定的参数个数，p 为 nil 时占位符为 ?
+func expandArgs(v interface{}, p Placeholders, start int) (placeholders string, args []interface{}) {
+	if p == nil {
+		p = PlaceholderFunc(func(int) string { return "?" })
+	}
 	rv := reflect.ValueOf(v)
-	
+
 	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
 		n := rv.Len()
 		ps := make([]string, n)
 		args = make([]interface{}, n)
 		for i := 0; i < n; i++ {
-			ps[i] = "?"
+			ps[i] = p.Placeholder(start + i + 1)
 			args[i] = rv.Index(i).Interface()
 		}
 		return strings.Join(ps, ", "), args
 	}
-	
-	return "?", []interface{}{v}
-}
 
+	return p.Placeholder(start + 1), []interface{}{v}
+}
@@ -0,0 +1,20 @@
+package gosql
+
+import "context"
+
+// engineKey context 中保存 Engine 的 key
+type engineKey struct{}
+
+// WithEngine 返回携带 engine 的 context，供共享的库代码通过 FromContext 取得请求对应的引擎
+// （例如按租户加载的模板、测试中替换的引擎），而不依赖全局变量
+func WithEngine(ctx context.Context, engine *Engine) context.Context {
+	return context.WithValue(ctx, engineKey{}, engine)
+}
+
+// FromContext 返回 WithEngine 放入 context 的引擎，没有时返回 Init 创建的默认引擎（未初始化时为 nil）
+func FromContext(ctx context.Context) *Engine {
+	if e, ok := ctx.Value(engineKey{}).(*Engine); ok && e != nil {
+		return e
+	}
+	return defaultEngine
+}
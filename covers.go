@@ -0,0 +1,52 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetSqlWithCovers 渲染模板，并用 Go 代码提供的内容覆盖模板中的 define 块（效果同 @use 中的 @cover）。
+// covers 的 key 为 define 名称（嵌套的 define 用 . 连接，如 "abc.d"），
+// value 为模板内容（可以使用 @var、@if 等语法，变量来自 args），例如在 Go 中拼好的过滤条件：
+//
+//	engine.GetSqlWithCovers("report.base", args, map[string]string{
+//		"filter": "and status = @status",
+//	})
+func (e *Engine) GetSqlWithCovers(path string, args interface{}, covers map[string]string) (Query, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return Query{}, codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", path)
+	}
+	ast, ok := e.compiledAST[parts[0]+"."+parts[1]]
+	if !ok {
+		return Query{}, e.templateNotFound(parts[0] + "." + parts[1])
+	}
+
+	nodes := make(map[string][]Node, len(covers))
+	for name, content := range covers {
+		if !hasDefine(ast.refs.defines, name) {
+			return Query{}, codeError(CodeDefineNotFound, "define not found: %s in template %s%s", name, parts[0]+"."+parts[1], didYouMean(name, ast.refs.defines))
+		}
+		cover, err := ParseTemplate(content)
+		if err != nil {
+			return Query{}, fmt.Errorf("cover %s: %w", name, err)
+		}
+		nodes[name] = cover.Nodes
+	}
+
+	query, _, err := e.renderAt(context.Background(), path, args, true, 0, nodes, nil)
+	return query, err
+}
+
+// hasDefine 判断 define 列表（完整路径）中是否有 name（完整路径或最后一级名称）
+func hasDefine(defines []string, name string) bool {
+	for _, d := range defines {
+		if d == name || strings.HasSuffix(d, "."+name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefineReport define 引用分析的结果
+type DefineReport struct {
+	// Unreferenced 没有被任何 @use / @import 路径或 @cover 引用的 define（namespace.name.define，已排序）。
+	// Go 代码中通过 GetSql("namespace.name.define") 直接渲染的 define 无法静态发现，也会出现在这里
+	Unreferenced []string `json:"unreferenced"`
+	// DanglingCovers @cover 的目标在被 @use 的模板中不存在（覆盖不会生效）。
+	// 默认加载时就会报错，只有使用 WithoutReferenceValidation 加载时才会出现在这里
+	DanglingCovers []DanglingCover `json:"dangling_covers"`
+}
+
+// DanglingCover 目标不存在的 @cover
+type DanglingCover struct {
+	Template string `json:"template"` // 包含 @use 的模板
+	Use      string `json:"use"`      // @use 的路径
+	Cover    string `json:"cover"`    // @cover 的名字
+}
+
+// AnalyzeDefines 分析已加载的所有模板中 define 的引用情况，找出不再使用的片段和写错名字的 @cover
+func (e *Engine) AnalyzeDefines() DefineReport {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.analyzeDefines()
+}
+
+// analyzeDefines 同 AnalyzeDefines（调用方持有读锁）
+func (e *Engine) analyzeDefines() DefineReport {
+	// 每个模板中被引用的 define 名字；whole 表示通过路径引用（其中嵌套的 define 也会被渲染）
+	type ref struct {
+		name  string
+		whole bool
+	}
+	refs := make(map[string][]ref)
+	report := DefineReport{Unreferenced: []string{}, DanglingCovers: []DanglingCover{}}
+
+	keys := make([]string, 0, len(e.compiledAST))
+	for key := range e.compiledAST {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ast := e.compiledAST[key]
+		nodes := ast.Nodes
+		if ast.source != nil {
+			nodes = ast.source
+		}
+		walkNodes(nodes, func(node Node) {
+			var path string
+			var covers []*CoverNode
+			switch n := node.(type) {
+			case *UseNode:
+				path, covers = n.Path, n.Covers
+			case *ImportNode:
+				path = n.Path
+			default:
+				return
+			}
+			parts := strings.SplitN(path, ".", 3)
+			if len(parts) < 2 {
+				return
+			}
+			target := parts[0] + "." + parts[1]
+			if len(parts) == 3 {
+				refs[target] = append(refs[target], ref{name: parts[2], whole: true})
+			}
+			used, ok := e.compiledAST[target]
+			for _, cover := range covers {
+				refs[target] = append(refs[target], ref{name: cover.Name})
+				if ok && used.refs != nil && !hasDefine(used.refs.defines, cover.Name) {
+					report.DanglingCovers = append(report.DanglingCovers, DanglingCover{Template: key, Use: path, Cover: cover.Name})
+				}
+			}
+		})
+	}
+
+	for _, key := range keys {
+		ast := e.compiledAST[key]
+		if ast.refs == nil {
+			continue
+		}
+		for _, define := range ast.refs.defines {
+			referenced := false
+			for _, r := range refs[key] {
+				if define == r.name || strings.HasSuffix(define, "."+r.name) ||
+					r.whole && hasDefinePrefix(define, r.name) {
+					referenced = true
+					break
+				}
+			}
+			if !referenced {
+				report.Unreferenced = append(report.Unreferenced, key+"."+define)
+			}
+		}
+	}
+	return report
+}
+
+// hasDefinePrefix 判断 define（完整路径）是否嵌套在名为 name 的 define 中
+func hasDefinePrefix(define, name string) bool {
+	parts := strings.Split(define, ".")
+	for _, part := range parts[:len(parts)-1] {
+		if part == name {
+			return true
+		}
+	}
+	return false
+}
+
+// walkNodes 递归遍历节点（包括各种块和 @cover 的内容）
+func walkNodes(nodes []Node, fn func(Node)) {
+	for _, node := range nodes {
+		fn(node)
+		switch n := node.(type) {
+		case *DefineNode:
+			walkNodes(n.Body, fn)
+		case *ForNode:
+			walkNodes(n.Body, fn)
+		case *IfNode:
+			walkNodes(n.Body, fn)
+			for _, ei := range n.ElseIf {
+				walkNodes(ei.Body, fn)
+			}
+			if n.Else != nil {
+				walkNodes(n.Else.Body, fn)
+			}
+		case *CoverNode:
+			walkNodes(n.Body, fn)
+		case *UseNode:
+			for _, cover := range n.Covers {
+				walkNodes(cover.Body, fn)
+			}
+		case *ConditionalLineNode:
+			walkNodes(n.LineNodes, fn)
+		case *FuncBlockNode:
+			walkNodes(n.Body, fn)
+		case *ForEachNode:
+			walkNodes(n.Body, fn)
+		case *SwitchNode:
+			for _, c := range n.branches() {
+				walkNodes(c.Body, fn)
+			}
+		case *SetNode:
+			walkNodes(n.Body, fn)
+		case *FlagNode:
+			walkNodes(n.Body, fn)
+			if n.Else != nil {
+				walkNodes(n.Else.Body, fn)
+			}
+		case *TrimNode:
+			walkNodes(n.Body, fn)
+		case *DirectiveNode:
+			walkNodes(n.Body, fn)
+		}
+	}
+}
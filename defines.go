@@ -0,0 +1,213 @@
+package gosql
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// splitCall 拆分 name(a, b) 形式的调用，返回名字和参数（去掉首尾空白）；没有括号时 args 为 nil
+func splitCall(s string) (string, []string, error) {
+	s = strings.TrimSpace(s)
+	open := strings.IndexByte(s, '(')
+	if open < 0 {
+		return s, nil, nil
+	}
+	if !strings.HasSuffix(s, ")") {
+		return "", nil, codeError(CodeSyntax, "expected ')' at the end of %s", s)
+	}
+	name := strings.TrimSpace(s[:open])
+	inner := strings.TrimSpace(s[open+1 : len(s)-1])
+	args := []string{}
+	if inner == "" {
+		return name, args, nil
+	}
+	for _, arg := range splitArgs(inner) {
+		if arg = strings.TrimSpace(arg); arg == "" {
+			return "", nil, codeError(CodeSyntax, "empty argument in %s", s)
+		}
+		args = append(args, arg)
+	}
+	return name, args, nil
+}
+
+// parseDefineParams 解析 define 的名字和形参，如 fragment(a, b)
+func parseDefineParams(header string) (string, []string, error) {
+	name, params, err := splitCall(header)
+	if err != nil {
+		return "", nil, err
+	}
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !token.IsIdentifier(p) {
+			return "", nil, codeError(CodeSyntax, "define %s: invalid parameter name %q", name, p)
+		}
+		if seen[p] {
+			return "", nil, codeError(CodeSyntax, "define %s: duplicate parameter %s", name, p)
+		}
+		seen[p] = true
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return name, params, nil
+}
+
+// parseUseArgs 解析 @use 的路径和实参，如 ns.tpl.fragment(1, name)，实参只能传给 define
+func parseUseArgs(value string) (string, []string, error) {
+	path, args, err := splitCall(value)
+	if err != nil {
+		return "", nil, err
+	}
+	if args != nil && strings.Count(path, ".") != 2 {
+		return "", nil, codeError(CodeSyntax, "@use %s: arguments can only be passed to a define (namespace.name.define)", path)
+	}
+	return path, args, nil
+}
+
+// checkDefineArgs 校验 @use 传给 define 的实参个数（没有传实参时形参从当前 scope 中取值）
+func checkDefineArgs(define *DefineNode, use *UseNode) error {
+	if use.Args == nil || len(use.Args) == len(define.Params) {
+		return nil
+	}
+	return codeError(CodeSyntax, "define %s expects %d arguments (%s), got %d",
+		define.Name, len(define.Params), strings.Join(define.Params, ", "), len(use.Args))
+}
+
+// bindDefineArgs 在当前 scope 中求值 @use 的实参并绑定到 define 的形参，返回恢复原来变量的函数
+func (ctx *executionContext) bindDefineArgs(define *DefineNode, use *UseNode) (func(), error) {
+	if use.Args == nil {
+		return func() {}, nil
+	}
+	if err := checkDefineArgs(define, use); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(use.Args))
+	for i, arg := range use.Args {
+		v, err := ctx.evalExpr(arg)
+		if err != nil {
+			return nil, fmt.Errorf("@use %s: argument %s: %w", use.Path, define.Params[i], err)
+		}
+		values[i] = v
+	}
+	type saved struct {
+		value  interface{}
+		exists bool
+	}
+	old := make([]saved, len(define.Params))
+	for i, p := range define.Params {
+		old[i].value, old[i].exists = ctx.scope[p]
+		ctx.scope[p] = values[i]
+	}
+	return func() {
+		for i, p := range define.Params {
+			if old[i].exists {
+				ctx.scope[p] = old[i].value
+			} else {
+				delete(ctx.scope, p)
+			}
+		}
+	}, nil
+}
+
+// RenderDefines 把模板中的每个 define 块（包括嵌套的 define）分别渲染为独立的 Query，
+// key 为 define 的完整路径（嵌套用 . 连接，如 "abc.d"）。
+// 便于在 Go 代码中组装窗口函数、CTE 等片段，而 SQL 本身仍然写在 markdown 中
+func (e *Engine) RenderDefines(path string, args interface{}) (map[string]Query, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	parts := strings.Split(path, ".")
+	if len(parts) != 2 {
+		return nil, codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", path)
+	}
+	ast, ok := e.compiledAST[path]
+	if !ok {
+		return nil, e.templateNotFound(path)
+	}
+	e.recordUsage(path)
+
+	ctx := newExecutionContext(e, args, ast)
+	ctx.placeholders = e.placeholders
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	queries := make(map[string]Query)
+	var err error
+	walkDefines(ast.Nodes, nil, func(parents []string, n *DefineNode) bool {
+		ctx.sql.Reset()
+		ctx.args, ctx.argPos = nil, nil
+		ctx.definePath = append(ctx.definePath[:0], parents...)
+		ctx.definePath = append(ctx.definePath, n.Name)
+		if err = ctx.executeNodes(n.Body); err == nil {
+			err = ctx.err
+		}
+		if err != nil {
+			err = fmt.Errorf("define %s: %w", strings.Join(ctx.definePath, "."), err)
+			return false
+		}
+		ctx.endSkip()
+		queries[strings.Join(ctx.definePath, ".")] = e.finishQuery(Query{
+			SQL:    ctx.sql.take(),
+			Params: ctx.args,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// walkDefines 按出现顺序遍历所有 define 节点，parents 为外层 define 的名称，fn 返回 false 时停止
+func walkDefines(nodes []Node, parents []string, fn func(parents []string, n *DefineNode) bool) bool {
+	for _, node := range nodes {
+		var children [][]Node
+		switch n := node.(type) {
+		case *DefineNode:
+			if !fn(parents, n) {
+				return false
+			}
+			if !walkDefines(n.Body, append(parents[:len(parents):len(parents)], n.Name), fn) {
+				return false
+			}
+			continue
+		case *IfNode:
+			children = append(children, n.Body)
+			for _, ei := range n.ElseIf {
+				children = append(children, ei.Body)
+			}
+			if n.Else != nil {
+				children = append(children, n.Else.Body)
+			}
+		case *ForNode:
+			children = append(children, n.Body)
+		case *FuncBlockNode:
+			children = append(children, n.Body)
+		case *ForEachNode:
+			children = append(children, n.Body)
+		case *SwitchNode:
+			for _, c := range n.branches() {
+				children = append(children, c.Body)
+			}
+		case *SetNode:
+			children = append(children, n.Body)
+		case *FlagNode:
+			children = append(children, n.Body)
+			if n.Else != nil {
+				children = append(children, n.Else.Body)
+			}
+		case *TrimNode:
+			children = append(children, n.Body)
+		case *DirectiveNode:
+			children = append(children, n.Body)
+		case *ConditionalLineNode:
+			children = append(children, n.LineNodes)
+		}
+		for _, body := range children {
+			if !walkDefines(body, parents, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// Dependency 模板之间的一条引用：From 通过 @use 或 @import 引用了 To
+type Dependency struct {
+	From string `json:"from"` // 模板路径 namespace.name
+	To   string `json:"to"`   // 引用路径（namespace.name 或 namespace.name.define）
+	Kind string `json:"kind"` // use 或 import
+}
+
+// DependencyEdges 返回所有模板之间的直接引用（按 From、To 排序），
+// 只包含 @use / @import，表达式中的 render(...) 调用无法静态确定，不在其中
+func (e *Engine) DependencyEdges() []Dependency {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var edges []Dependency
+	for key, ast := range e.compiledAST {
+		if ast.refs == nil {
+			continue
+		}
+		seen := make(map[Dependency]bool)
+		for kind, paths := range map[string][]string{"use": ast.refs.uses, "import": ast.refs.imports} {
+			for _, path := range paths {
+				dep := Dependency{From: key, To: path, Kind: kind}
+				if !seen[dep] {
+					seen[dep] = true
+					edges = append(edges, dep)
+				}
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].To != edges[j].To {
+			return edges[i].To < edges[j].To
+		}
+		return edges[i].Kind < edges[j].Kind
+	})
+	return edges
+}
+
+// Dependencies 返回 path 直接或间接引用的所有模板（namespace.name，已排序）
+func (e *Engine) Dependencies(path string) []string {
+	graph := make(map[string][]string)
+	for _, dep := range e.DependencyEdges() {
+		graph[dep.From] = append(graph[dep.From], templateKey(dep.To))
+	}
+	key := templateKey(path)
+	return uniqueSorted(reachable(graph, []string{key}), key)
+}
+
+// Dependents 返回直接或间接引用了 path 的所有模板（namespace.name，已排序），用于评估修改共享片段的影响范围。
+// path 为 namespace.name.define 时，只从引用了该 define（或整个模板）的模板开始计算
+func (e *Engine) Dependents(path string) []string {
+	graph := make(map[string][]string)
+	var start []string
+	key := templateKey(path)
+	for _, dep := range e.DependencyEdges() {
+		to := templateKey(dep.To)
+		graph[to] = append(graph[to], dep.From)
+		if to == key && (path == key || dep.To == path || dep.To == key) {
+			start = append(start, dep.From)
+		}
+	}
+	return uniqueSorted(append(reachable(graph, start), start...), key)
+}
+
+// reachable 返回从 start 出发能到达的所有节点
+func reachable(graph map[string][]string, start []string) []string {
+	visited := make(map[string]bool)
+	var result []string
+	queue := append([]string(nil), start...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range graph[node] {
+			if !visited[next] {
+				visited[next] = true
+				result = append(result, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	return result
+}
+
+// uniqueSorted 去重并排序，同时去掉 exclude
+func uniqueSorted(items []string, exclude string) []string {
+	seen := map[string]bool{exclude: true}
+	result := []string{}
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// templateKey 返回引用路径对应的模板 key（namespace.name）
+func templateKey(path string) string {
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return path
+	}
+	return parts[0] + "." + parts[1]
+}
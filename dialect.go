@@ -0,0 +1,39 @@
+package gosql
+
+import "strings"
+
+// Dialect 数据库方言
+type Dialect string
+
+const (
+	DialectDefault   Dialect = ""          // 未指定方言（```sql）
+	DialectMySQL     Dialect = "mysql"     // MySQL / MariaDB
+	DialectPostgres  Dialect = "postgres"  // PostgreSQL
+	DialectSQLite    Dialect = "sqlite"    // SQLite
+	DialectSQLServer Dialect = "sqlserver" // SQL Server
+	DialectOracle    Dialect = "oracle"    // Oracle
+)
+
+// fenceDialects 代码块语言标记 -> 方言
+// 只有出现在这里的语言标记才会被当作 SQL 模板
+var fenceDialects = map[string]Dialect{
+	"sql":        DialectDefault,
+	"mysql":      DialectMySQL,
+	"mariadb":    DialectMySQL,
+	"postgresql": DialectPostgres,
+	"postgres":   DialectPostgres,
+	"pgsql":      DialectPostgres,
+	"sqlite":     DialectSQLite,
+	"sqlite3":    DialectSQLite,
+	"mssql":      DialectSQLServer,
+	"sqlserver":  DialectSQLServer,
+	"tsql":       DialectSQLServer,
+	"oracle":     DialectOracle,
+	"plsql":      DialectOracle,
+}
+
+// DialectForFence 根据代码块语言标记获取方言，ok 为 false 表示该代码块不是 SQL 模板
+func DialectForFence(lang string) (Dialect, bool) {
+	d, ok := fenceDialects[strings.ToLower(strings.TrimSpace(lang))]
+	return d, ok
+}
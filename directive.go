@@ -0,0 +1,70 @@
+package gosql
+
+import (
+	"fmt"
+	"go/token"
+	"sync"
+)
+
+// CustomNode 自定义指令在渲染时执行的节点，通过 RenderContext 输出 SQL 和参数。
+// 同一个节点会被并发渲染共享，Execute 不能修改节点自身的状态
+type CustomNode interface {
+	Execute(ctx *RenderContext) error
+}
+
+// DirectiveParser 解析自定义指令：args 为 @name(...) 括号中的原始内容（没有括号时为空），
+// block 表示指令后面是否带 { } 块。返回的错误会作为模板的语法错误在加载时报告
+type DirectiveParser func(args string, block bool) (CustomNode, error)
+
+var (
+	directivesMu sync.RWMutex
+	directives   = make(map[string]DirectiveParser)
+)
+
+// reservedDirectives 内置指令，不能被 RegisterDirective 覆盖
+var reservedDirectives = map[string]bool{
+	"if": true, "for": true, "foreach": true, "switch": true, "case": true, "default": true,
+	"use": true, "import": true, "include": true, "define": true, "cover": true, "set": true,
+	"flag": true,
+	"trim": true, "Trim": true,
+}
+
+// RegisterDirective 注册自定义指令，让下游项目不修改词法和语法分析就能扩展模板语法，例如：
+//
+//	gosql.RegisterDirective("hint", func(args string, block bool) (gosql.CustomNode, error) {
+//		return hintNode(args), nil
+//	})
+//
+// 模板中的 @hint、@hint(...)、@hint(...) { } 都会交给解析函数，不再是同名的变量或函数块。
+// 注册对之后加载的模板生效，应在 init 或加载模板之前调用；name 不是标识符、是内置指令或重复注册时 panic
+func RegisterDirective(name string, parse DirectiveParser) {
+	if !token.IsIdentifier(name) || reservedDirectives[name] {
+		panic(fmt.Sprintf("gosql: invalid directive name %q", name))
+	}
+	if parse == nil {
+		panic("gosql: RegisterDirective parser is nil")
+	}
+	directivesMu.Lock()
+	defer directivesMu.Unlock()
+	if _, dup := directives[name]; dup {
+		panic("gosql: RegisterDirective called twice for directive " + name)
+	}
+	directives[name] = parse
+}
+
+// lookupDirective 返回注册的指令解析函数，没有注册时返回 nil
+func lookupDirective(name string) DirectiveParser {
+	directivesMu.RLock()
+	defer directivesMu.RUnlock()
+	return directives[name]
+}
+
+// executeDirective 执行自定义指令
+func (ctx *executionContext) executeDirective(n *DirectiveNode) error {
+	ctx.tracef("directive", "@%s(%s)", n.Name, n.Args)
+	defer ctx.traceIn()()
+	if err := n.Custom.Execute(&RenderContext{ctx: ctx, node: n}); err != nil {
+		return fmt.Errorf("@%s: %w", n.Name, err)
+	}
+	return nil
+}
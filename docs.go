@@ -0,0 +1,212 @@
+package gosql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// TemplateInfo 模板的元信息（用于文档、搜索、工具等）
+type TemplateInfo struct {
+	Path        string      `json:"path"` // namespace.name
+	Namespace   string      `json:"namespace"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Dialect     Dialect     `json:"dialect,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	Params      []ParamInfo `json:"params,omitempty"`  // 模板引用的参数
+	Defines     []string    `json:"defines,omitempty"` // define 完整路径（嵌套用 . 连接）
+	Uses        []string    `json:"uses,omitempty"`    // @use 引用的模板路径
+	Imports     []string    `json:"imports,omitempty"` // @import 引用的模板路径（加载时已展开）
+	Flags       []string    `json:"flags,omitempty"`   // @flag 使用的开关名称
+	SQL         string      `json:"sql"`               // 模板原文
+}
+
+// DocFormat 文档输出格式
+type DocFormat string
+
+const (
+	DocFormatMarkdown DocFormat = "markdown"
+	DocFormatHTML     DocFormat = "html"
+	DocFormatJSON     DocFormat = "json"
+)
+
+// Templates 返回所有已加载模板的元信息，按路径排序
+func (e *Engine) Templates() []*TemplateInfo {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	infos := make([]*TemplateInfo, 0, len(e.compiledAST))
+	for key, ast := range e.compiledAST {
+		infos = append(infos, e.templateInfo(key, ast))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Path < infos[j].Path
+	})
+	return infos
+}
+
+// templateInfo 构建单个模板的元信息
+func (e *Engine) templateInfo(key string, ast *TemplateAST) *TemplateInfo {
+	info := &TemplateInfo{
+		Path:      key,
+		Namespace: ast.Namespace,
+		Name:      ast.Name,
+		Dialect:   ast.Dialect,
+	}
+	if tmpl, ok := e.store.Get(key); ok {
+		info.Description = tmpl.Description
+		info.Tags = tmpl.Tags
+		info.SQL = tmpl.Content
+	}
+	refs := analyzeTemplate(ast.Nodes)
+	info.Params = refs.params
+	for i, p := range info.Params {
+		info.Params[i].Type = ast.paramTypes[p.Name]
+	}
+	info.Defines = refs.defines
+	info.Uses = refs.uses
+	info.Flags = refs.flags
+	if ast.refs != nil {
+		info.Imports = ast.refs.imports
+	}
+	return info
+}
+
+// docNamespace 文档中的命名空间
+type docNamespace struct {
+	Name      string          `json:"name"`
+	Templates []*TemplateInfo `json:"templates"`
+}
+
+// docNamespaces 按命名空间分组
+func docNamespaces(infos []*TemplateInfo) []*docNamespace {
+	var namespaces []*docNamespace
+	for _, info := range infos {
+		if len(namespaces) == 0 || namespaces[len(namespaces)-1].Name != info.Namespace {
+			namespaces = append(namespaces, &docNamespace{Name: info.Namespace})
+		}
+		ns := namespaces[len(namespaces)-1]
+		ns.Templates = append(ns.Templates, info)
+	}
+	return namespaces
+}
+
+// GenerateDocs 根据已加载的模板生成 API 文档（markdown / html / json）
+func (e *Engine) GenerateDocs(format DocFormat) (string, error) {
+	namespaces := docNamespaces(e.Templates())
+
+	switch format {
+	case DocFormatMarkdown, "md":
+		return renderMarkdownDocs(namespaces), nil
+	case DocFormatHTML:
+		var buf bytes.Buffer
+		if err := htmlDocsTemplate.Execute(&buf, namespaces); err != nil {
+			return "", fmt.Errorf("generate html docs: %w", err)
+		}
+		return buf.String(), nil
+	case DocFormatJSON:
+		bs, err := json.MarshalIndent(map[string]interface{}{"namespaces": namespaces}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("generate json docs: %w", err)
+		}
+		return string(bs), nil
+	default:
+		return "", fmt.Errorf("unsupported doc format: %s", format)
+	}
+}
+
+// renderMarkdownDocs 生成 markdown 文档
+func renderMarkdownDocs(namespaces []*docNamespace) string {
+	var sb strings.Builder
+	sb.WriteString("# SQL 模板目录\n")
+	for _, ns := range namespaces {
+		sb.WriteString("\n## " + ns.Name + "\n")
+		for _, info := range ns.Templates {
+			sb.WriteString("\n### " + info.Name + "\n\n")
+			if info.Description != "" {
+				sb.WriteString(info.Description + "\n\n")
+			}
+			sb.WriteString("- 路径：`" + info.Path + "`\n")
+			if info.Dialect != DialectDefault {
+				sb.WriteString("- 方言：" + string(info.Dialect) + "\n")
+			}
+			if len(info.Tags) > 0 {
+				sb.WriteString("- 标签：" + strings.Join(info.Tags, ", ") + "\n")
+			}
+			if len(info.Defines) > 0 {
+				sb.WriteString("- define：`" + strings.Join(info.Defines, "`, `") + "`\n")
+			}
+			if len(info.Uses) > 0 {
+				sb.WriteString("- 引用：`" + strings.Join(info.Uses, "`, `") + "`\n")
+			}
+			if len(info.Params) > 0 {
+				sb.WriteString("\n| 参数 | 直接输出 | 可选 |\n| --- | --- | --- |\n")
+				for _, p := range info.Params {
+					sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", p.Name, yesNo(p.Raw), yesNo(p.Optional)))
+				}
+			}
+			sb.WriteString("\n```sql\n" + info.SQL + "\n```\n")
+		}
+	}
+	return sb.String()
+}
+
+// yesNo 布尔值的文档展示
+func yesNo(b bool) string {
+	if b {
+		return "是"
+	}
+	return ""
+}
+
+// htmlDocsTemplate html 文档模板
+var htmlDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SQL 模板目录</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; }
+</style>
+</head>
+<body>
+<h1>SQL 模板目录</h1>
+<ul>
+{{- range .}}
+<li><a href="#ns-{{.Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="ns-{{.Name}}">{{.Name}}</h2>
+{{- range .Templates}}
+<h3 id="{{.Path}}">{{.Name}}</h3>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+<p>路径：<code>{{.Path}}</code>{{if .Dialect}}，方言：{{.Dialect}}{{end}}{{if .Tags}}，标签：{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}{{end}}</p>
+{{- if .Defines}}
+<p>define：{{range $i, $d := .Defines}}{{if $i}}, {{end}}<code>{{$d}}</code>{{end}}</p>
+{{- end}}
+{{- if .Uses}}
+<p>引用：{{range $i, $u := .Uses}}{{if $i}}, {{end}}<a href="#{{$u}}"><code>{{$u}}</code></a>{{end}}</p>
+{{- end}}
+{{- if .Params}}
+<table>
+<tr><th>参数</th><th>直接输出</th><th>可选</th></tr>
+{{- range .Params}}
+<tr><td>{{.Name}}</td><td>{{if .Raw}}是{{end}}</td><td>{{if .Optional}}是{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<pre><code>{{.SQL}}</code></pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
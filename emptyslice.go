@@ -0,0 +1,89 @@
+package gosql
+
+import "strings"
+
+// EmptySlicePolicy 绑定的切片为空时的处理方式。默认什么也不输出，id in (@ids) 会渲染为无效的 id in ()
+type EmptySlicePolicy int
+
+const (
+	EmptySliceKeep  EmptySlicePolicy = iota // 什么也不输出（默认）
+	EmptySliceFalse                         // col in (@ids) 整体替换为 1 = 0（not in 为 1 = 1），不在 in (...) 中时同 EmptySliceNull
+	EmptySliceNull                          // 输出 NULL，如 id in (NULL)
+	EmptySliceSkip                          // 跳过所在的行，同 @ids?；跳过后整条语句为空时返回错误（GOSQL022）
+	EmptySliceError                         // 返回错误（GOSQL022）
+)
+
+// bindValue 绑定 @name、@ expr @ 的值，切片为空时按 WithEmptySlicePolicy 处理（ref 用于跳过行的跟踪和错误信息）
+func (ctx *executionContext) bindValue(value interface{}, ref string) error {
+	if policy := ctx.engine.emptySlices; policy != EmptySliceKeep && ctx.engine.sliceLen(value) == 0 {
+		switch policy {
+		case EmptySliceSkip:
+			// 切片通常在 in (...) 中，行内剩下的文本（如右括号）也一起跳过
+			ctx.tracef("skip", "%s is an empty slice, line skipped", ref)
+			ctx.skipLine()
+			ctx.skippedSlice = ref
+		case EmptySliceError:
+			return codeError(CodeEmptySlice, "%s is an empty slice", ref)
+		default:
+			// EmptySliceFalse 先输出 NULL，紧接着的 ) 输出时再替换为 1 = 0
+			var loc []int
+			if policy == EmptySliceFalse {
+				loc = inListPattern.FindSubmatchIndex(ctx.sql.buf)
+			}
+			ctx.sql.WriteString("NULL")
+			if loc != nil {
+				text := "1 = 0"
+				if loc[4] >= 0 {
+					text = "1 = 1"
+				}
+				ctx.inList = &inListRewrite{start: loc[0], end: ctx.sql.Len(), text: text}
+			}
+		}
+		return nil
+	}
+	if v, ok := ctx.arrayValue(value); ok {
+		ctx.bindArray(v)
+		return nil
+	}
+	if limit := ctx.engine.inListLimit; limit > 0 && ctx.engine.sliceLen(value) > limit {
+		if loc := inListPattern.FindSubmatchIndex(ctx.sql.buf); loc != nil {
+			ctx.bindChunkedIn(value, loc, limit)
+			return nil
+		}
+	}
+	ctx.appendArg(value)
+	return nil
+}
+
+// emptyStatement EmptySliceSkip 跳过行后整条语句只剩空白时返回错误（GOSQL022），避免执行空 SQL
+// （如单行的 select * from t where id in (@ids)）。只检查最外层的渲染结果，嵌套渲染的片段可以为空
+func (ctx *executionContext) emptyStatement(sql string) error {
+	if ctx.skippedSlice == "" || ctx.depth > 0 || strings.TrimSpace(sql) != "" {
+		return nil
+	}
+	return codeError(CodeEmptySlice, "%s is an empty slice and skipping its line leaves an empty statement", ctx.skippedSlice)
+}
+
+// writeText 输出模板文本：skipLine 跳过行之后，丢弃到行尾为止的文本，遇到换行时撤销期间其它节点的输出；
+// 上一个节点是等待替换的 col in (...)（空切片或超过 WithInListLimit 的切片）、文本以 ) 开头时连同 ) 一起替换
+func (ctx *executionContext) writeText(text string) {
+	if ctx.skipRest {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return
+		}
+		ctx.endSkip()
+		text = text[i:]
+	}
+	if in := ctx.inList; in != nil {
+		ctx.inList = nil
+		if ctx.sql.Len() == in.end {
+			if loc := closeParenPattern.FindStringIndex(text); loc != nil {
+				ctx.sql.buf = ctx.sql.buf[:in.start]
+				ctx.sql.WriteString(in.text)
+				text = text[loc[1]:]
+			}
+		}
+	}
+	ctx.sql.WriteString(text)
+}
@@ -0,0 +1,117 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrorCode 稳定的错误码，便于监控按类别告警、文档引用。错误码一经发布不再改变含义
+type ErrorCode string
+
+const (
+	CodeUnclosedExpression ErrorCode = "GOSQL001" // 表达式未闭合（缺少结尾的 @）
+	CodeUnclosedBrace      ErrorCode = "GOSQL002" // 块未闭合（缺少 }）
+	CodeSyntax             ErrorCode = "GOSQL003" // 模板语法错误
+	CodeParseLimit         ErrorCode = "GOSQL004" // 超出解析限制（大小、token 数、嵌套深度）
+	CodeMarkdown           ErrorCode = "GOSQL005" // markdown 结构错误（代码块缺少标题、方言冲突等）
+	CodeDuplicateTemplate  ErrorCode = "GOSQL010" // 重复定义的模板
+	CodeCycle              ErrorCode = "GOSQL011" // @import、@use 或 include 循环引用
+	CodeAssertion          ErrorCode = "GOSQL012" // 模板断言无效或不满足
+	CodeTemplateLimit      ErrorCode = "GOSQL013" // 超出模板结构限制（@define / @for 嵌套、@use 链）
+	CodeTemplateNotFound   ErrorCode = "GOSQL014" // 模板不存在
+	CodeDefineNotFound     ErrorCode = "GOSQL015" // define 不存在
+	CodeVariableNotFound   ErrorCode = "GOSQL016" // 变量不存在
+	CodeInvalidPath        ErrorCode = "GOSQL017" // 模板路径格式错误
+	CodeScopeConflict      ErrorCode = "GOSQL018" // 严格模式下多层 scope 中的同名变量取值不同
+	CodeInvalidSignature   ErrorCode = "GOSQL019" // 模板文件签名无效
+	CodeNotStatic          ErrorCode = "GOSQL020" // GetStatic 的模板包含动态节点
+	CodeValidation         ErrorCode = "GOSQL021" // 渲染前的参数校验失败
+	CodeEmptySlice         ErrorCode = "GOSQL022" // 绑定的切片为空（WithEmptySlicePolicy(EmptySliceError)）
+)
+
+// errorCatalog 错误码说明（英文 / 中文）
+var errorCatalog = map[ErrorCode][2]string{
+	CodeUnclosedExpression: {"unclosed expression", "表达式未闭合"},
+	CodeUnclosedBrace:      {"unclosed brace", "块未闭合"},
+	CodeSyntax:             {"template syntax error", "模板语法错误"},
+	CodeParseLimit:         {"parse limit exceeded", "超出解析限制"},
+	CodeMarkdown:           {"invalid markdown structure", "markdown 结构错误"},
+	CodeDuplicateTemplate:  {"duplicate template", "模板重复定义"},
+	CodeCycle:              {"import, use or include cycle", "循环引用"},
+	CodeAssertion:          {"template assertion failed", "模板断言不满足"},
+	CodeTemplateLimit:      {"template limit exceeded", "超出模板结构限制"},
+	CodeTemplateNotFound:   {"template not found", "模板不存在"},
+	CodeDefineNotFound:     {"define not found", "define 不存在"},
+	CodeVariableNotFound:   {"variable not found", "变量不存在"},
+	CodeInvalidPath:        {"invalid template path", "模板路径格式错误"},
+	CodeScopeConflict:      {"scope conflict", "scope 取值冲突"},
+	CodeInvalidSignature:   {"invalid template signature", "模板签名无效"},
+	CodeNotStatic:          {"template is not static", "模板包含动态语法"},
+	CodeValidation:         {"argument validation failed", "参数校验失败"},
+	CodeEmptySlice:         {"empty slice bound", "绑定的切片为空"},
+}
+
+// Describe 返回错误码的说明，lang 为 "zh" 时返回中文，否则返回英文
+func (c ErrorCode) Describe(lang string) string {
+	desc, ok := errorCatalog[c]
+	if !ok {
+		return string(c)
+	}
+	if lang == "zh" {
+		return desc[1]
+	}
+	return desc[0]
+}
+
+// ErrorCodes 返回所有错误码（按编号排序），用于生成文档
+func ErrorCodes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(errorCatalog))
+	for code := range errorCatalog {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Error 带错误码的错误。Error() 与原始错误信息相同，外层用 %w 包装后仍可以通过 CodeOf 取得错误码
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf 返回错误链中最内层（最具体）的错误码，没有错误码时返回空串
+func CodeOf(err error) ErrorCode {
+	var code ErrorCode
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			break
+		}
+		code = e.Code
+		err = e.Err
+	}
+	return code
+}
+
+// codeError 创建带错误码的错误
+func codeError(code ErrorCode, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// withCode 为还没有错误码的错误附加错误码
+func withCode(code ErrorCode, err error) error {
+	if err == nil || CodeOf(err) != "" {
+		return err
+	}
+	return &Error{Code: code, Err: err}
+}
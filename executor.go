@@ -0,0 +1,170 @@
+package gosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Executor 在 Engine 的基础上直接通过 database/sql 执行模板，
+// 并按 SQL 文本缓存预编译语句（同一模板在不同参数下渲染出相同 SQL 时复用同一个语句）。
+// 缓存的语句个数有上限（WithStmtCacheSize），模板重新加载后清空
+type Executor struct {
+	engine  *Engine
+	db      *sql.DB
+	scanner *Scanner
+
+	dialect      Dialect    // 数据库方言（WithDialect），Migrate 使用
+	stmts        *stmtCache // SQL -> 预编译语句
+	cancelReload func()     // 注销清空缓存的 OnReload 回调
+}
+
+// NewExecutor 创建执行器，不再使用时调用 Close 关闭缓存的预编译语句
+func NewExecutor(engine *Engine, db *sql.DB, opts ...ExecutorOption) *Executor {
+	x := &Executor{
+		engine:  engine,
+		db:      db,
+		scanner: NewScanner(),
+		stmts:   newStmtCache(DefaultStmtCacheSize),
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	x.cancelReload = engine.OnReload(func(changed []string, err error) {
+		if len(changed) > 0 {
+			x.stmts.reset()
+		}
+	})
+	return x
+}
+
+// Bind 把引擎绑定到数据库连接，返回执行器（同 NewExecutor(e, db, opts...)）：
+//
+//	db := engine.Bind(sqlDB)
+//	err := db.Select(&users, "user.list", args)
+func (e *Engine) Bind(db *sql.DB, opts ...ExecutorOption) *Executor {
+	return NewExecutor(e, db, opts...)
+}
+
+// Engine 返回执行器使用的模板引擎
+func (x *Executor) Engine() *Engine {
+	return x.engine
+}
+
+// DB 返回执行器使用的数据库连接
+func (x *Executor) DB() *sql.DB {
+	return x.db
+}
+
+// Scanner 返回执行器扫描结果使用的扫描器，可以注册列名映射和字段转换：
+//
+//	x.Scanner().MapColumns(gosql.StripPrefix("u_"))
+//	x.Scanner().RegisterConverter(reflect.TypeOf(Status(0)), parseStatus)
+func (x *Executor) Scanner() *Scanner {
+	return x.scanner
+}
+
+// query 渲染模板并执行查询（使用缓存的预编译语句）
+func (x *Executor) query(ctx context.Context, path string, args interface{}) (*sql.Rows, error) {
+	q, err := x.engine.GetSqlContext(ctx, path, args)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := x.stmts.acquire(ctx, x.db, q.SQL)
+	if err != nil {
+		return nil, err
+	}
+	// 返回的 rows 关闭之前，database/sql 不会真正关闭语句
+	defer x.stmts.release(stmt)
+	return stmt.stmt.QueryContext(ctx, q.Params...)
+}
+
+// Query 渲染模板并执行查询，返回的 rows 由调用方关闭
+func (x *Executor) Query(path string, args interface{}) (*sql.Rows, error) {
+	return x.QueryContext(context.Background(), path, args)
+}
+
+// QueryContext 同 Query，可以传入 context
+func (x *Executor) QueryContext(ctx context.Context, path string, args interface{}) (*sql.Rows, error) {
+	return x.query(ctx, path, args)
+}
+
+// Exec 渲染模板并执行（insert / update / delete 等）
+func (x *Executor) Exec(path string, args interface{}) (sql.Result, error) {
+	return x.ExecContext(context.Background(), path, args)
+}
+
+// ExecContext 同 Exec，可以传入 context
+func (x *Executor) ExecContext(ctx context.Context, path string, args interface{}) (sql.Result, error) {
+	q, err := x.engine.GetSqlContext(ctx, path, args)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := x.stmts.acquire(ctx, x.db, q.SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer x.stmts.release(stmt)
+	return stmt.stmt.ExecContext(ctx, q.Params...)
+}
+
+// Select 渲染模板、执行查询并把所有行扫描到 dest（切片指针）
+func (x *Executor) Select(dest interface{}, path string, args interface{}) error {
+	return x.SelectContext(context.Background(), dest, path, args)
+}
+
+// SelectContext 同 Select，可以传入 context
+func (x *Executor) SelectContext(ctx context.Context, dest interface{}, path string, args interface{}) error {
+	rows, err := x.query(ctx, path, args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return x.scanner.ScanAll(rows, dest)
+}
+
+// Get 渲染模板、执行查询并把第一行扫描到 dest，没有数据时返回 sql.ErrNoRows
+func (x *Executor) Get(dest interface{}, path string, args interface{}) error {
+	return x.GetContext(context.Background(), dest, path, args)
+}
+
+// GetContext 同 Get，可以传入 context
+func (x *Executor) GetContext(ctx context.Context, dest interface{}, path string, args interface{}) error {
+	rows, err := x.query(ctx, path, args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return x.scanner.ScanOne(rows, dest)
+}
+
+// Warm 在启动时渲染并预编译热点模板，避免首个请求承担 prepare 的往返开销。
+// sampleArgs 的 key 为模板路径，value 为渲染该模板使用的示例参数（没有时按 nil 渲染）。
+// 所有模板都会尝试预热，返回遇到的第一个错误
+func (x *Executor) Warm(paths []string, sampleArgs map[string]interface{}) error {
+	return x.WarmContext(context.Background(), paths, sampleArgs)
+}
+
+// WarmContext 同 Warm，可以传入 context 控制超时
+func (x *Executor) WarmContext(ctx context.Context, paths []string, sampleArgs map[string]interface{}) error {
+	var firstErr error
+	for _, path := range paths {
+		query, _, err := x.engine.render(ctx, path, sampleArgs[path], false)
+		if err == nil {
+			var stmt *cachedStmt
+			if stmt, err = x.stmts.acquire(ctx, x.db, query.SQL); err == nil {
+				x.stmts.release(stmt)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("warm %s: %w", path, err)
+		}
+	}
+	return firstErr
+}
+
+// Close 关闭所有缓存的预编译语句（不会关闭 db），并不再随模板重新加载清空缓存
+func (x *Executor) Close() error {
+	x.cancelReload()
+	return x.stmts.reset()
+}
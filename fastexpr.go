@@ -0,0 +1,245 @@
+package gosql
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+)
+
+// fastExpr 预先编译的简单表达式：直接在 scope 上求值，不经过解释器（不用每次重新解析表达式、复制变量表）。
+// 返回 false 表示这次无法快速求值（变量不存在、类型不匹配等），调用方改用解释器求值，
+// 因此只需要在能求值时与解释器的结果一致
+type fastExpr func(scope map[string]interface{}) (interface{}, bool)
+
+// compileExpr 解析表达式，表达式只由变量、字段、字面量、比较和逻辑运算组成时返回 fastExpr，否则返回 nil
+func compileExpr(expr string) (fastExpr, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return compileFast(node), nil
+}
+
+// constExpr 表达式是字面量（数字、字符串、true / false）时返回它的值，与解释器求值的结果相同
+func constExpr(expr string) (interface{}, bool) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, false
+	}
+	for {
+		paren, ok := node.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		node = paren.X
+	}
+	switch n := node.(type) {
+	case *ast.BasicLit:
+	case *ast.Ident:
+		if n.Name != "true" && n.Name != "false" {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	fn := compileFast(node)
+	if fn == nil {
+		return nil, false
+	}
+	return fn(nil)
+}
+
+// compileFast 把表达式节点编译为 fastExpr，语义与解释器相同：
+// == 和 != 按 reflect.DeepEqual 比较，&& 和 || 两边都求值且必须是 bool，
+// 大小比较在类型不同时把左边转换为右边的类型
+func compileFast(node ast.Expr) fastExpr {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return compileFast(n.X)
+
+	case *ast.Ident:
+		name := n.Name
+		switch name {
+		case "true", "false":
+			v := name == "true"
+			return func(map[string]interface{}) (interface{}, bool) { return v, true }
+		case "nil":
+			return nil
+		}
+		return func(scope map[string]interface{}) (interface{}, bool) {
+			v, ok := scope[name]
+			return v, ok && v != nil
+		}
+
+	case *ast.BasicLit:
+		var v interface{}
+		switch n.Kind {
+		case token.INT:
+			i, err := strconv.ParseInt(n.Value, 0, 64)
+			if err != nil {
+				return nil
+			}
+			v = int(i)
+		case token.FLOAT:
+			f, err := strconv.ParseFloat(n.Value, 64)
+			if err != nil {
+				return nil
+			}
+			v = f
+		case token.STRING:
+			s, err := strconv.Unquote(n.Value)
+			if err != nil {
+				return nil
+			}
+			v = s
+		default:
+			return nil
+		}
+		return func(map[string]interface{}) (interface{}, bool) { return v, true }
+
+	case *ast.SelectorExpr:
+		x := compileFast(n.X)
+		if x == nil || !n.Sel.IsExported() {
+			return nil
+		}
+		field := n.Sel.Name
+		return func(scope map[string]interface{}) (interface{}, bool) {
+			v, ok := x(scope)
+			if !ok {
+				return nil, false
+			}
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+				rv = rv.Elem()
+			}
+			if rv.Kind() != reflect.Struct {
+				return nil, false
+			}
+			f, ok := rv.Type().FieldByName(field)
+			if !ok || len(f.Index) != 1 {
+				return nil, false
+			}
+			return rv.Field(f.Index[0]).Interface(), true
+		}
+
+	case *ast.UnaryExpr:
+		x := compileFast(n.X)
+		if x == nil || n.Op != token.NOT {
+			return nil
+		}
+		return func(scope map[string]interface{}) (interface{}, bool) {
+			v, ok := x(scope)
+			if !ok {
+				return nil, false
+			}
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Bool {
+				return nil, false
+			}
+			return !rv.Bool(), true
+		}
+
+	case *ast.BinaryExpr:
+		x, y := compileFast(n.X), compileFast(n.Y)
+		if x == nil || y == nil {
+			return nil
+		}
+		op := n.Op
+		switch op {
+		case token.EQL, token.NEQ, token.LAND, token.LOR, token.LSS, token.GTR, token.LEQ, token.GEQ:
+		default:
+			return nil
+		}
+		return func(scope map[string]interface{}) (interface{}, bool) {
+			l, ok := x(scope)
+			if !ok {
+				return nil, false
+			}
+			r, ok := y(scope)
+			if !ok {
+				return nil, false
+			}
+			switch op {
+			case token.EQL:
+				return reflect.DeepEqual(l, r), true
+			case token.NEQ:
+				return !reflect.DeepEqual(l, r), true
+			case token.LAND, token.LOR:
+				lb, lok := l.(bool)
+				rb, rok := r.(bool)
+				if !lok || !rok {
+					return nil, false
+				}
+				if op == token.LAND {
+					return lb && rb, true
+				}
+				return lb || rb, true
+			}
+			c, ok := compareValues(reflect.ValueOf(l), reflect.ValueOf(r))
+			if !ok {
+				return nil, false
+			}
+			switch op {
+			case token.LSS:
+				return c < 0, true
+			case token.GTR:
+				return c > 0, true
+			case token.LEQ:
+				return c <= 0, true
+			default:
+				return c >= 0, true
+			}
+		}
+	}
+	return nil
+}
+
+// compareValues 比较两个数值或字符串，类型不同时（只支持数值之间）把左边转换为右边的类型
+func compareValues(l, r reflect.Value) (int, bool) {
+	if l.Type() != r.Type() {
+		if !isNumberKind(l.Kind()) || !isNumberKind(r.Kind()) {
+			return 0, false
+		}
+		l = l.Convert(r.Type())
+	}
+	switch l.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(l.Int(), r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(l.Uint(), r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		a, b := l.Float(), r.Float()
+		if a != a || b != b {
+			// NaN：所有比较都为 false，交给解释器
+			return 0, false
+		}
+		return compareOrdered(a, b), true
+	case reflect.String:
+		return compareOrdered(l.String(), r.String()), true
+	}
+	return 0, false
+}
+
+// compareOrdered 比较两个可排序的值
+func compareOrdered[T int64 | uint64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// isNumberKind 判断是否是整数或浮点数
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
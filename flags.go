@@ -0,0 +1,54 @@
+package gosql
+
+import "context"
+
+// FlagProvider 决定 @flag "name" { } 块的开关是否打开（WithFlagProvider），
+// ctx 为 GetSqlContext 传入的 context（GetSql 为 context.Background()），会被并发调用
+type FlagProvider interface {
+	Enabled(ctx context.Context, name string) bool
+}
+
+// FlagFunc 把函数适配为 FlagProvider，例如接入已有的功能开关服务：
+//
+//	gosql.WithFlagProvider(gosql.FlagFunc(func(ctx context.Context, name string) bool {
+//		return flags.IsOn(name, userFromContext(ctx))
+//	}))
+type FlagFunc func(ctx context.Context, name string) bool
+
+// Enabled 调用 f
+func (f FlagFunc) Enabled(ctx context.Context, name string) bool {
+	return f(ctx, name)
+}
+
+// StaticFlags 固定的开关表，不在表中的开关为关闭
+type StaticFlags map[string]bool
+
+// Enabled 返回 name 在表中的值
+func (s StaticFlags) Enabled(_ context.Context, name string) bool {
+	return s[name]
+}
+
+// flagEnabled 查询开关是否打开，没有设置 FlagProvider 时为关闭
+func (ctx *executionContext) flagEnabled(name string) bool {
+	if ctx.engine.flags == nil {
+		return false
+	}
+	goctx := ctx.goctx
+	if goctx == nil {
+		goctx = context.Background()
+	}
+	return ctx.engine.flags.Enabled(goctx, name)
+}
+
+// executeFlag 执行 @flag 节点：开关打开时执行 Body，否则执行 else 分支
+func (ctx *executionContext) executeFlag(n *FlagNode) error {
+	enabled := ctx.flagEnabled(n.Name)
+	ctx.tracef("flag", "flag %q: %v", n.Name, enabled)
+	if enabled {
+		return ctx.executeNodes(n.Body)
+	}
+	if n.Else != nil {
+		return ctx.executeNodes(n.Else.Body)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package gosql
+
+import "strings"
+
+// Minify 返回压缩后的查询：空白折叠为单个空格，去掉 -- 行注释（块注释保留，
+// 例如优化器提示和查询指纹），字符串和引号标识符中的内容保持不变；参数不变
+func (q Query) Minify() Query {
+	q.SQL = minifySQL(q.SQL)
+	return q
+}
+
+// minifySQL 折叠空白并去掉行注释
+func minifySQL(sql string) string {
+	var sb strings.Builder
+	pendingSpace := false
+	for _, t := range scanSQL(sql) {
+		if t.kind == sqlSpace || t.kind == sqlLineComment {
+			pendingSpace = true
+			continue
+		}
+		if pendingSpace && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// NormalizeSQL 返回 SQL 的规范形式，用于比较不同版本渲染出的 SQL 是否等价：
+// 关键字转为小写，去掉 -- 和 /* */ 注释，空白折叠为单个空格（括号内侧和逗号前的空白去掉），
+// 字符串、引号标识符和非关键字的单词保持不变
+func NormalizeSQL(s string) string {
+	var sb strings.Builder
+	pendingSpace := false
+	prev := ""
+	for _, t := range scanSQL(s) {
+		switch t.kind {
+		case sqlSpace, sqlLineComment, sqlBlockComment:
+			pendingSpace = true
+			continue
+		case sqlWord:
+			if isSQLKeyword(t.text) {
+				t.text = strings.ToLower(t.text)
+			}
+		}
+		if pendingSpace && sb.Len() > 0 && prev != "(" && t.text != ")" && t.text != "," {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+		prev = t.text
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// clauseKeywords 格式化时另起一行的子句关键字
+var clauseKeywords = toSet(`
+SELECT FROM WHERE GROUP ORDER HAVING LIMIT OFFSET UNION EXCEPT INTERSECT
+INSERT UPDATE DELETE VALUES SET JOIN LEFT RIGHT INNER FULL CROSS NATURAL WINDOW
+`)
+
+// dialectClauseKeywords 各方言额外的子句关键字
+var dialectClauseKeywords = map[Dialect]map[string]bool{
+	DialectPostgres:  toSet("RETURNING"),
+	DialectSQLite:    toSet("RETURNING"),
+	DialectSQLServer: toSet("OUTPUT FETCH"),
+	DialectOracle:    toSet("FETCH CONNECT START"),
+}
+
+// joinModifiers 出现在 JOIN 之前的修饰词，其后的 JOIN/OUTER 不再换行
+var joinModifiers = toSet("LEFT RIGHT INNER FULL CROSS OUTER NATURAL")
+
+// Pretty 返回格式化后的查询，便于人工阅读：关键字大写，子句另起一行，
+// WHERE / HAVING / ON 中的 AND / OR 换行缩进，子查询整体缩进；
+// dialect 用于识别方言特有的子句（如 PostgreSQL 的 RETURNING）。参数不变
+func (q Query) Pretty(dialect Dialect) Query {
+	q.SQL = prettySQL(q.SQL, dialect)
+	return q
+}
+
+// prettySQL 格式化 SQL
+func prettySQL(sql string, dialect Dialect) string {
+	tokens := scanSQL(minifySQL(sql))
+	extra := dialectClauseKeywords[dialect]
+
+	var sb strings.Builder
+	var parens []bool       // 括号栈：是否为子查询括号
+	clauses := []string{""} // 每层子查询当前所在的子句
+	indent := 0             // 子查询缩进层级
+	prev := ""              // 上一个单词（大写）
+	inBetween := false      // 是否在 BETWEEN ... AND 中
+	pendingSpace := false
+
+	newline := func(extraIndent string) {
+		sb.WriteString("\n")
+		sb.WriteString(strings.Repeat("  ", indent))
+		sb.WriteString(extraIndent)
+		pendingSpace = false
+	}
+	// atClauseLevel 当前是否位于子查询（或最外层）的直接层级，而不是函数调用、IN 列表等括号中
+	atClauseLevel := func() bool {
+		return len(parens) == 0 || parens[len(parens)-1]
+	}
+
+	for i, t := range tokens {
+		switch t.kind {
+		case sqlSpace:
+			pendingSpace = true
+			continue
+		case sqlSymbol:
+			switch t.text {
+			case "(":
+				sub := nextWordIs(tokens, i+1, "SELECT", "WITH")
+				parens = append(parens, sub)
+				if sub {
+					indent++
+					clauses = append(clauses, "")
+				}
+			case ")":
+				if len(parens) > 0 {
+					sub := parens[len(parens)-1]
+					parens = parens[:len(parens)-1]
+					if sub {
+						indent--
+						clauses = clauses[:len(clauses)-1]
+						newline("")
+					}
+				}
+			}
+		case sqlWord:
+			upper := strings.ToUpper(t.text)
+			if isSQLKeyword(upper) || extra[upper] {
+				t.text = upper
+			}
+			if atClauseLevel() {
+				isClause := clauseKeywords[upper] || extra[upper]
+				switch {
+				case (upper == "LEFT" || upper == "RIGHT") && nextSymbolIs(tokens, i+1, "("):
+					isClause = false // LEFT(str, n) 函数
+				case upper == "JOIN" || upper == "OUTER":
+					isClause = !joinModifiers[prev]
+				case upper == "FROM":
+					isClause = prev != "DELETE"
+				case upper == "UPDATE":
+					isClause = prev != "KEY" && prev != "DO"
+				}
+				if isClause {
+					if sb.Len() > 0 {
+						newline("")
+					}
+					clauses[len(clauses)-1] = upper
+				} else if upper == "ON" {
+					clauses[len(clauses)-1] = upper
+				}
+
+				clause := clauses[len(clauses)-1]
+				if (upper == "AND" || upper == "OR") && !inBetween && (clause == "WHERE" || clause == "HAVING" || clause == "ON") {
+					newline("  ")
+				}
+			}
+			if upper == "BETWEEN" {
+				inBetween = true
+			} else if upper == "AND" {
+				inBetween = false
+			}
+			prev = upper
+		}
+
+		if pendingSpace && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// nextWordIs 从 start 开始的下一个非空白单元是否为指定单词之一（不区分大小写）
+func nextWordIs(tokens []sqlToken, start int, words ...string) bool {
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].kind == sqlSpace {
+			continue
+		}
+		if tokens[i].kind != sqlWord {
+			return false
+		}
+		for _, w := range words {
+			if strings.EqualFold(tokens[i].text, w) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// nextSymbolIs 从 start 开始的下一个非空白单元是否为指定符号
+func nextSymbolIs(tokens []sqlToken, start int, symbol string) bool {
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].kind == sqlSpace {
+			continue
+		}
+		return tokens[i].kind == sqlSymbol && tokens[i].text == symbol
+	}
+	return false
+}
+
+// KeywordCase 关键字大小写风格
+type KeywordCase int
+
+const (
+	KeywordCasePreserve KeywordCase = iota // 保持原样
+	KeywordCaseUpper                       // 关键字大写
+	KeywordCaseLower                       // 关键字小写
+)
+
+// NormalizeKeywords 返回关键字大小写统一后的查询
+// 只转换 SQL 关键字，标识符、字符串、引号标识符和注释保持不变；参数不变
+func (q Query) NormalizeKeywords(c KeywordCase) Query {
+	q.SQL = normalizeKeywordCase(q.SQL, c)
+	return q
+}
+
+// normalizeKeywordCase 统一关键字大小写
+func normalizeKeywordCase(sql string, c KeywordCase) string {
+	if c == KeywordCasePreserve {
+		return sql
+	}
+	tokens := scanSQL(sql)
+	for i, t := range tokens {
+		if t.kind != sqlWord || !isSQLKeyword(t.text) {
+			continue
+		}
+		if c == KeywordCaseUpper {
+			tokens[i].text = strings.ToUpper(t.text)
+		} else {
+			tokens[i].text = strings.ToLower(t.text)
+		}
+	}
+	return joinSQLTokens(tokens)
+}
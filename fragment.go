@@ -0,0 +1,80 @@
+package gosql
+
+import "strings"
+
+// Fragment 带参数的 SQL 片段，用于在代码中拼装查询：
+//
+//	cond := gosql.Frag("status = ? and created_at > ?", "active", since)
+type Fragment struct {
+	SQL    string        // SQL 片段，参数以 ? 占位
+	Params []interface{} // 参数列表
+}
+
+// Frag 创建 SQL 片段
+func Frag(sql string, params ...interface{}) Fragment {
+	return Fragment{SQL: sql, Params: params}
+}
+
+// Query 转换为 Query
+func (f Fragment) Query() Query {
+	return Query{SQL: f.SQL, Params: f.Params}
+}
+
+// subQuery 参数是 Query / Fragment（或它们的指针）时返回对应的 Query
+func subQuery(v interface{}) (Query, bool) {
+	switch x := v.(type) {
+	case Query:
+		return x, true
+	case *Query:
+		if x != nil {
+			return *x, true
+		}
+	case Fragment:
+		return x.Query(), true
+	case *Fragment:
+		if x != nil {
+			return x.Query(), true
+		}
+	}
+	return Query{}, false
+}
+
+// Flatten 展开参数中嵌套的 Query / Fragment：对应的 ? 替换为子查询的 SQL（原样拼接，括号由外层 SQL 提供），
+// 子查询的参数按顺序插入到该位置，子查询中再嵌套的 Query / Fragment 递归展开。例如
+//
+//	Query{SQL: "select * from user where id in (?)", Params: []interface{}{gosql.Frag("select uid from vip where level > ?", 3)}}
+//
+// 展开为 "select * from user where id in (select uid from vip where level > ?)" 和 [3]。
+// 字符串、引号标识符和注释中的 ? 不是占位符；没有嵌套参数时返回原查询
+func (q Query) Flatten() Query {
+	nested := false
+	for _, p := range q.Params {
+		if _, ok := subQuery(p); ok {
+			nested = true
+			break
+		}
+	}
+	if !nested {
+		return q
+	}
+	var sb strings.Builder
+	params := make([]interface{}, 0, len(q.Params))
+	i := 0
+	for _, t := range scanSQL(q.SQL) {
+		if t.kind != sqlSymbol || t.text != "?" || i >= len(q.Params) {
+			sb.WriteString(t.text)
+			continue
+		}
+		p := q.Params[i]
+		i++
+		if sub, ok := subQuery(p); ok {
+			sub = sub.Flatten()
+			sb.WriteString(sub.SQL)
+			params = append(params, sub.Params...)
+			continue
+		}
+		sb.WriteString(t.text)
+		params = append(params, p)
+	}
+	return Query{SQL: sb.String(), Params: append(params, q.Params[i:]...)}
+}
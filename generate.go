@@ -0,0 +1,121 @@
+package gosql
+
+import (
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Generators 内置函数 uuid() / nowUTC() 的取值来源，测试中可以用 WithGenerators 替换，
+// 使渲染结果（参数）可以稳定地做快照对比。字段为 nil 时使用默认实现
+type Generators struct {
+	UUID func() string    // 默认生成随机的 v4 UUID
+	Now  func() time.Time // 默认 time.Now，nowUTC() 会转换为 UTC
+}
+
+// SequentialUUIDs 返回依次生成 00000000-0000-0000-0000-000000000001、...0002 的 UUID 函数，用于测试
+func SequentialUUIDs() func() string {
+	var n uint64
+	return func() string {
+		v := atomic.AddUint64(&n, 1)
+		return fmt.Sprintf("00000000-0000-0000-0000-%012x", v)
+	}
+}
+
+// FixedNow 返回总是返回 t 的时间函数，用于测试
+func FixedNow(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// generated 由 uuid / nowUTC / seq 生成的值：只能作为参数绑定（@expr@），不能用 @= 原样输出
+type generated struct {
+	name  string
+	value interface{}
+}
+
+// generatedFunc 创建内置函数 uuid() / nowUTC() / seq(name)。
+// 同一次渲染中 nowUTC() 总是返回同一时刻，seq(name) 按名字从 1 开始递增
+func (ctx *executionContext) generatedFunc(name string) interface{} {
+	gen := ctx.engine.generators
+	switch name {
+	case "uuid":
+		return func() generated {
+			if gen.UUID != nil {
+				return generated{name: name, value: gen.UUID()}
+			}
+			return generated{name: name, value: newUUID()}
+		}
+	case "nowUTC":
+		return func() generated {
+			if ctx.now.IsZero() {
+				if gen.Now != nil {
+					ctx.now = gen.Now().UTC()
+				} else {
+					ctx.now = time.Now().UTC()
+				}
+			}
+			return generated{name: name, value: ctx.now}
+		}
+	case "seq":
+		return func(seqName string) generated {
+			if ctx.seqs == nil {
+				ctx.seqs = make(map[string]int64)
+			}
+			ctx.seqs[seqName]++
+			return generated{name: name, value: ctx.seqs[seqName]}
+		}
+	}
+	return nil
+}
+
+// newUUID 生成随机的 v4 UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return formatUUID(b)
+}
+
+// formatUUID 把 16 字节随机数设置为 v4 UUID 的版本和变体位并格式化
+func formatUUID(b [16]byte) string {
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SeededUUIDs 返回由 seed 决定的 v4 UUID 序列（相同 seed 生成相同的序列），用于测试
+func SeededUUIDs(seed int64) func() string {
+	var mu sync.Mutex
+	r := mathrand.New(mathrand.NewSource(seed))
+	return func() string {
+		var b [16]byte
+		mu.Lock()
+		r.Read(b[:])
+		mu.Unlock()
+		return formatUUID(b)
+	}
+}
+
+// sortMapKeys 对 map 的键排序：数字按大小，其它类型按格式化后的文本
+func sortMapKeys(keys []reflect.Value) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		}
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	})
+}
@@ -0,0 +1,54 @@
+package gosql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Queryer 可以执行查询的数据库连接（*sql.DB、*sql.Tx、*sql.Conn 都满足）
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// defaultScanner Select / Get 使用的扫描器（没有列名映射和转换函数）
+var defaultScanner = NewScanner()
+
+// Select 渲染模板、在 db 上执行查询并把所有行扫描为 []T。T 可以是结构体（列按 `db` 标签、字段名、
+// snake_case 匹配字段）、结构体指针、map[string]interface{} 或基本类型（只有一列时）：
+//
+//	users, err := gosql.Select[User](ctx, db, engine, "user.list", args)
+//
+// 需要列名映射或字段转换时使用 Executor 的 Scanner
+func Select[T any](ctx context.Context, db Queryer, engine *Engine, path string, args interface{}) ([]T, error) {
+	rows, err := queryRows(ctx, db, engine, path, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []T{}
+	if err := defaultScanner.ScanAll(rows, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Get 同 Select，只扫描第一行，没有数据时返回 sql.ErrNoRows
+func Get[T any](ctx context.Context, db Queryer, engine *Engine, path string, args interface{}) (T, error) {
+	var item T
+	rows, err := queryRows(ctx, db, engine, path, args)
+	if err != nil {
+		return item, err
+	}
+	defer rows.Close()
+	err = defaultScanner.ScanOne(rows, &item)
+	return item, err
+}
+
+// queryRows 渲染模板并在 db 上执行查询
+func queryRows(ctx context.Context, db Queryer, engine *Engine, path string, args interface{}) (*sql.Rows, error) {
+	q, err := engine.GetSqlContext(ctx, path, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q.SQL, q.Params...)
+}
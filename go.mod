@@ -2,6 +2,18 @@ module github.com/llyb120/gosql
 
 go 1.21
 
-require github.com/llyb120/goscript2 v0.0.1
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/llyb120/goscript2 v0.0.1
+	google.golang.org/grpc v1.66.2
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117 // indirect
+)
 
 // replace github.com/llyb120/goscript2 => D:/code/goscript2
@@ -1,9 +1,15 @@
 package gosql
 
 import (
+	"context"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/llyb120/goscript2/interpreter"
@@ -17,55 +23,263 @@ type Query struct {
 
 // Engine SQL 模板引擎
 type Engine struct {
+	mu          sync.RWMutex // 加载模板时加写锁，渲染时加读锁
 	store       *TemplateStore
 	compiledAST map[string]*TemplateAST // 缓存编译后的 AST
 	interp      *interpreter.Interpreter
-	funcs       map[string]interface{} // 注册的自定义函数
+	funcs       map[string]interface{}     // 注册的自定义函数
+	ctxFuncs    map[string]bool            // 第一个参数为 *RenderContext 的注册函数
+	usage       map[string]*int64          // 模板渲染次数（加载时创建计数器，渲染时原子递增）
+	parseCache  *parseCache                // 模板解析缓存（按内容哈希）
+	programs    *programCache              // GetSqlCompiled 编译出的程序（按模板路径，加载后清空）
+	files       map[string][]string        // 文件 -> 该文件中的模板（LoadFile 加载）
+	fileOf      map[string]string          // 模板 -> 所在文件
+	includes    map[string][]string        // 文件 -> 该文件 include 的文件
+	pins        map[string]*pinnedTemplate // 常驻模板（Engine.Pin），值为 nil 表示模板暂时不存在
+	loadedAt    time.Time                  // 最近一次成功加载的时间
+	layers      []*Engine                  // Overlay 合并的引擎（后面的覆盖前面的），nil 表示不是叠加的引擎
+	detach      []func()                   // Overlay 注册在各层引擎上的回调的注销函数（Close 时调用）
+
+	hooksMu  sync.Mutex    // 保护 onReload
+	onReload []*reloadHook // OnReload 注册的回调
+
+	engineConfig
+}
+
+// engineConfig 由 Option 设置的引擎配置，Overlay 创建的引擎整体复制
+type engineConfig struct {
+	hashComment   bool             // 是否在 SQL 末尾追加查询指纹注释
+	keywordCase   KeywordCase      // 渲染后关键字的大小写风格
+	methodPolicy  MethodPolicy     // 参数方法绑定策略（nil 表示绑定所有导出方法）
+	noMethods     bool             // 完全关闭参数方法绑定
+	adapters      []ValueAdapter   // 自定义类型适配器
+	scalarTypes   []reflect.Type   // 作为单个参数绑定、不展开的切片或数组类型（WithScalarTypes）
+	verifier      BundleVerifier   // 外部模板文件的签名校验器
+	generators    Generators       // uuid / nowUTC 内置函数的取值来源
+	deterministic bool             // 确定性模式：按键排序遍历 map 等
+	limits        TemplateLimits   // 模板结构限制
+	skipRefCheck  bool             // 加载时不校验模板间的引用
+	argCheck      bool             // 渲染前校验参数
+	coerce        bool             // 渲染前把 map 参数转换为模板声明的类型
+	watchInterval time.Duration    // Watch 合并文件变化事件的间隔
+	validators    []ArgValidator   // 自定义的参数校验函数
+	memo          *memoCache       // 纯模板的渲染结果缓存（nil 表示不缓存）
+	hasher        *ArgsHasher      // 计算渲染结果缓存 key 的参数哈希（nil 表示默认规则）
+	limiter       *renderLimiter   // 渲染并发限制（nil 表示不限制）
+	placeholders  Placeholders     // 参数占位符的生成方式（nil 表示 ?）
+	missingVars   MissingVarPolicy // 变量不存在时的处理方式
+	emptySlices   EmptySlicePolicy // 绑定的切片为空时的处理方式
+	inListLimit   int              // in (...) 中单个列表的参数上限，超过时拆分为多个 in，0 表示不拆分
+	arrays        arrayWrap        // 切片作为数组参数绑定时的转换（WithPostgresArrays），nil 表示不启用
+	flags         FlagProvider     // @flag 的开关来源（WithFlagProvider），nil 表示所有开关都关闭
+
+	onLoaded []func(*SQLTemplate, *TemplateAST) error // OnTemplateLoaded 注册的回调
 }
 
 // New 创建新的 SQL 模板引擎
-func New() *Engine {
-	return &Engine{
+func New(opts ...Option) *Engine {
+	e := &Engine{
 		store:       NewTemplateStore(),
 		compiledAST: make(map[string]*TemplateAST),
 		interp:      interpreter.New(),
 		funcs:       make(map[string]interface{}),
-	}
+		usage:       make(map[string]*int64),
+		parseCache:  newParseCache(),
+		programs:    newProgramCache(),
+		files:       make(map[string][]string),
+		fileOf:      make(map[string]string),
+		includes:    make(map[string][]string),
+		engineConfig: engineConfig{
+			limits: DefaultTemplateLimits,
+			memo:   newMemoCache(DefaultMemoSize),
+		},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
-// RegisterFunc 注册自定义函数
+// RegisterFunc 注册自定义函数。第一个参数为 *RenderContext 的函数在渲染时绑定当前的渲染上下文，
+// 模板中调用时省略这个参数（如 func(ctx *RenderContext, col string) string 在模板中写作 fn("id")）
 func (e *Engine) RegisterFunc(name string, fn interface{}) {
 	e.funcs[name] = fn
+	if acceptsRenderContext(fn) {
+		if e.ctxFuncs == nil {
+			e.ctxFuncs = make(map[string]bool)
+		}
+		e.ctxFuncs[name] = true
+	} else {
+		delete(e.ctxFuncs, name)
+	}
 }
 
 // LoadMarkdown 加载 markdown 文件内容
 func (e *Engine) LoadMarkdown(content string) error {
-	if err := e.store.LoadMarkdown(content); err != nil {
-		return err
+	e.mu.Lock()
+	changed, err := e.loadMarkdown(content)
+	e.mu.Unlock()
+	e.notifyReload(changed, err)
+	return err
+}
+
+// loadMarkdown 加载 markdown 内容并预编译，返回新增或内容发生变化的模板。
+// 在暂存的副本上编译和校验，全部成功后再写入，失败时保留之前的模板
+func (e *Engine) loadMarkdown(content string) ([]string, error) {
+	templates, err := ParseMarkdown(content)
+	if err != nil {
+		return nil, err
 	}
 
-	// 预编译所有模板
-	for key, tmpl := range e.store.templates {
-		ast, err := ParseTemplate(tmpl.Content)
+	// 预编译新加载的模板（内容未变化的模板直接复用缓存的节点）
+	staged := make(map[string]*TemplateAST, len(e.compiledAST)+len(templates))
+	for key, ast := range e.compiledAST {
+		staged[key] = ast
+	}
+	var changed []string
+	for _, tmpl := range templates {
+		key := tmpl.Namespace + "." + tmpl.Name
+		ast, err := e.compileTemplate(tmpl)
 		if err != nil {
-			return fmt.Errorf("template %s: %w", key, err)
+			return nil, fmt.Errorf("template %s: %w", key, err)
 		}
-		ast.Namespace = tmpl.Namespace
-		ast.Name = tmpl.Name
-		e.compiledAST[key] = ast
+		if old, ok := staged[key]; !ok || old.contentHash != ast.contentHash {
+			changed = append(changed, key)
+		}
+		staged[key] = ast
+	}
+	if err := e.commitTemplates(staged); err != nil {
+		return nil, err
 	}
 
+	for _, tmpl := range templates {
+		key := tmpl.Namespace + "." + tmpl.Name
+		e.store.Set(key, tmpl)
+		if _, ok := e.usage[key]; !ok {
+			e.usage[key] = new(int64)
+		}
+	}
+	e.parseCache.retain(e.compiledAST)
+	e.linkTemplates()
+	e.loadedAt = time.Now()
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+// commitTemplates 在暂存的模板集合上展开 @import 并校验模板间的引用，成功后用它替换 e.compiledAST，
+// 失败时 e.compiledAST 保持不变（调用方持有写锁）
+func (e *Engine) commitTemplates(staged map[string]*TemplateAST) error {
+	prev := e.compiledAST
+	e.compiledAST = staged
+	err := e.resolveImports()
+	if err == nil {
+		err = e.validateRefs(false)
+	}
+	if err != nil {
+		e.compiledAST = prev
+		return err
+	}
 	return nil
 }
 
+// compileTemplate 编译单个模板（内容未变化时复用缓存的节点）
+func (e *Engine) compileTemplate(tmpl *SQLTemplate) (*TemplateAST, error) {
+	ast, err := e.parseCache.parse(tmpl.Content)
+	if err != nil {
+		return nil, err
+	}
+	ast.Namespace = tmpl.Namespace
+	ast.Name = tmpl.Name
+	ast.Dialect = tmpl.Dialect
+	if err := e.limits.checkNodes(ast.Nodes); err != nil {
+		return nil, err
+	}
+	if ast.Asserts, err = compileAssertions(tmpl, ast); err != nil {
+		return nil, err
+	}
+	if ast.paramTypes, err = compileParamTypes(tmpl); err != nil {
+		return nil, err
+	}
+	if ast.pure, err = compilePure(tmpl, ast); err != nil {
+		return nil, err
+	}
+	if len(e.onLoaded) > 0 {
+		for _, fn := range e.onLoaded {
+			if err := fn(tmpl, ast); err != nil {
+				return nil, err
+			}
+		}
+		// 回调可能替换了 Nodes（例如注入标准 define），重新分析引用
+		ast.refs = analyzeTemplate(ast.Nodes)
+	}
+	if len(ast.refs.imports) > 0 {
+		// @import 在所有模板加载后由 resolveImports 展开
+		ast.source = ast.Nodes
+	}
+	return ast, nil
+}
+
+// OnTemplateLoaded 注册模板加载回调：每个模板编译之后、生效之前调用，
+// 可以用来检查命名规范、注入标准 define，或拒绝不符合规范的模板（返回错误时本次加载失败）。
+// 回调可以修改 ast（例如替换 ast.Nodes），但不要原地修改已有节点：内容相同的模板之间会共享节点。
+// 重新加载时内容未变化的模板也可能再次调用，回调需要是幂等的
+func (e *Engine) OnTemplateLoaded(fn func(tmpl *SQLTemplate, ast *TemplateAST) error) {
+	e.onLoaded = append(e.onLoaded, fn)
+}
+
 // GetSql 获取渲染后的 SQL 和参数
 // path: 模板路径，格式为 "namespace.name" 或 "namespace.name.define"
-// args: 模板渲染的 scope（任意类型，会被展开为变量）
+// args: 模板渲染的 scope（任意类型，会被展开为变量；实现了 Scope 接口时按需查找变量）
 func (e *Engine) GetSql(path string, args interface{}) (Query, error) {
+	return e.GetSqlContext(context.Background(), path, args)
+}
+
+// GetSqlContext 同 GetSql，goctx 传给 @flag 的 FlagProvider（按请求的用户、租户等决定开关）
+func (e *Engine) GetSqlContext(goctx context.Context, path string, args interface{}) (Query, error) {
+	query, _, err := e.render(goctx, path, args, true)
+	return query, err
+}
+
+// render 渲染模板，record 为 true 时计入模板的渲染次数
+func (e *Engine) render(goctx context.Context, path string, args interface{}, record bool) (Query, *TemplateAST, error) {
+	if e.limiter != nil {
+		// 在加读锁之前排队，排队的渲染不会阻塞模板加载
+		e.limiter.acquire()
+		defer e.limiter.release()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	args, err := e.prepareArgs(path, args)
+	if err != nil {
+		return Query{}, nil, err
+	}
+	return e.renderMemo(goctx, path, args, record)
+}
+
+// prepareArgs 渲染前按引擎配置转换、校验参数（调用方持有读锁）
+func (e *Engine) prepareArgs(path string, args interface{}) (interface{}, error) {
+	if e.coerce {
+		var err error
+		if args, err = e.coerceArgs(path, args); err != nil {
+			return nil, err
+		}
+	}
+	if e.argCheck {
+		if err := e.checkArgs(path, args); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// renderAt 渲染模板，goctx 为 GetSqlContext 传入的 context，depth 为内置 render 函数的嵌套深度，
+// covers 为 Go 代码提供的 cover，trace 不为 nil 时记录渲染过程
+func (e *Engine) renderAt(goctx context.Context, path string, args interface{}, record bool, depth int, covers map[string][]Node, trace *tracer) (Query, *TemplateAST, error) {
 	// 解析路径
 	parts := strings.Split(path, ".")
 	if len(parts) < 2 {
-		return Query{}, fmt.Errorf("invalid path: %s, expected format: namespace.name", path)
+		return Query{}, nil, codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", path)
 	}
 
 	namespace := parts[0]
@@ -80,32 +294,77 @@ func (e *Engine) GetSql(path string, args interface{}) (Query, error) {
 	// 获取 AST
 	ast, ok := e.compiledAST[key]
 	if !ok {
-		return Query{}, fmt.Errorf("template not found: %s", key)
+		return Query{}, nil, e.templateNotFound(key)
+	}
+	if record {
+		e.recordUsage(key)
 	}
 
-	// 创建执行上下文
-	ctx := newExecutionContext(e, args)
-
-	// 如果指定了 define 名称，只执行该 define 块
-	if defineName != "" {
-		defineNode := findDefine(ast.Nodes, defineName)
-		if defineNode == nil {
-			return Query{}, fmt.Errorf("define not found: %s in template %s", defineName, key)
+	if len(covers) == 0 && trace == nil {
+		if s := ast.skeletonFor(defineName); s != nil {
+			if sql, ok := s.static(); ok {
+				// 只有静态文本：不需要执行上下文
+				return e.finishQuery(Query{SQL: sql}), ast, nil
+			}
 		}
-		if err := ctx.executeNodes(defineNode.Body); err != nil {
-			return Query{}, err
-		}
-	} else {
-		// 执行整个模板
-		if err := ctx.executeNodes(ast.Nodes); err != nil {
-			return Query{}, err
+		if p := e.pinned(key, ast); p != nil {
+			return e.renderPinned(goctx, p, defineName, key, args, depth)
 		}
 	}
 
-	return Query{
-		SQL:    ctx.sql.String(),
-		Params: ctx.args,
-	}, nil
+	// 创建执行上下文
+	linked := ast
+	if len(covers) > 0 {
+		linked = e.linkCovers(ast, covers)
+	}
+	ctx := newExecutionContext(e, args, linked)
+	ctx.goctx = goctx
+	ctx.depth = depth
+	ctx.trace = trace
+	if depth == 0 {
+		ctx.placeholders = e.placeholders
+	}
+	for name, body := range covers {
+		ctx.covers[name] = body
+	}
+	if ctx.err != nil {
+		return Query{}, nil, ctx.err
+	}
+
+	// 执行整个模板（指定了 define 名称时只执行该 define 块）
+	if defineName == "" {
+		ctx.preallocate(&ast.sizes)
+	}
+	if err := ctx.executeTemplate(ast, key, defineName); err != nil {
+		return Query{}, nil, err
+	}
+	if ctx.err != nil {
+		return Query{}, nil, ctx.err
+	}
+
+	ctx.endSkip()
+	query := Query{SQL: ctx.sql.take()}
+	if err := ctx.emptyStatement(query.SQL); err != nil {
+		return Query{}, nil, err
+	}
+	if len(ctx.args) > 0 {
+		query.Params = ctx.args
+	}
+	if defineName == "" {
+		ast.sizes.observe(len(query.SQL), len(query.Params))
+	}
+	return e.finishQuery(query), ast, nil
+}
+
+// finishQuery 对渲染结果做引擎配置的后处理（关键字大小写、指纹注释等）
+func (e *Engine) finishQuery(query Query) Query {
+	if e.keywordCase != KeywordCasePreserve {
+		query = query.NormalizeKeywords(e.keywordCase)
+	}
+	if e.hashComment {
+		query = query.WithHashComment()
+	}
+	return query
 }
 
 // findDefine 在节点列表中查找 define 块
@@ -138,6 +397,15 @@ func findDefine(nodes []Node, name string) *DefineNode {
 			if found := findDefine(n.Body, name); found != nil {
 				return found
 			}
+		case *FlagNode:
+			if found := findDefine(n.Body, name); found != nil {
+				return found
+			}
+			if n.Else != nil {
+				if found := findDefine(n.Else.Body, name); found != nil {
+					return found
+				}
+			}
 		}
 	}
 	return nil
@@ -146,34 +414,66 @@ func findDefine(nodes []Node, name string) *DefineNode {
 // executionContext 执行上下文
 type executionContext struct {
 	engine     *Engine
+	goctx      context.Context // GetSqlContext 传入的 context，nil 表示 context.Background()
 	scope      map[string]interface{}
-	sql        strings.Builder
+	sql        sqlBuffer
 	args       []interface{}
 	covers     map[string][]Node // cover 覆盖
-	interp     *interpreter.Interpreter
-	scopeObj   interface{}     // 原始 scope 对象（用于方法调用）
-	typeInfo   *CachedTypeInfo // 缓存的类型信息
-	lineBuffer strings.Builder // 行缓冲（用于条件行控制）
-	lineArgs   []interface{}   // 行参数缓冲
-	inCondLine bool            // 是否在条件行中
-	condResult bool            // 条件结果
-	definePath []string        // 当前 define 块的路径栈（用于嵌套覆盖）
+	scopeObj   interface{}       // 原始 scope 对象（用于方法调用）
+	typeInfo   *CachedTypeInfo   // 缓存的类型信息
+	lineBuffer strings.Builder   // 行缓冲（用于条件行控制）
+	lineArgs   []interface{}     // 行参数缓冲
+	inCondLine bool              // 是否在条件行中
+	condResult bool              // 条件结果
+	definePath []string          // 当前 define 块的路径栈（用于嵌套覆盖）
+	calls      map[string]bool   // 模板可能调用的函数名（用于按需绑定方法），nil 表示全部绑定
+	names      map[string]bool   // 模板可能引用的变量名和函数名（用于从 Scope 中查找）
+	err        error             // 展开 scope、内置函数执行时产生的错误
+	depth      int               // 内置 render 函数的嵌套深度
+	now        time.Time         // 本次渲染中 nowUTC() 的取值（首次调用时确定）
+	seqs       map[string]int64  // 本次渲染中 seq(name) 的计数
+	uses       []string          // 当前正在执行的 @use 链（用于限制深度）
+	trace      *tracer           // 渲染过程跟踪（Engine.Trace），nil 表示不跟踪
+	pinned     *pinnedTemplate   // 常驻模板的预解析表达式，nil 表示没有
+	tmpl       *TemplateAST      // 正在执行的模板（@use 时切换为被引用的模板）
+
+	placeholders Placeholders   // 参数占位符的生成方式，nil 表示 ?（嵌套渲染的结果会被拼接到外层，总是使用 ?）
+	argBase      int            // 收集块内容时 ctx.args 之前已经绑定的参数个数，用于占位符编号
+	argPos       []int          // 每个参数的占位符（或包含它的一段 SQL）在 ctx.sql 中的起始位置，与 ctx.args 一一对应
+	inList       *inListRewrite // 刚输出的 col in (...)，等待之后的 ) 一起替换（空切片、WithInListLimit）
+	skipRest     bool           // 空切片或不存在的变量跳过了当前行，丢弃到行尾为止的输出（skipLine）
+	skipMark     int            // skipRest 时开始跳过的位置（ctx.sql 中），之后的输出在行尾或渲染结束时撤销
+	skippedSlice string         // EmptySliceSkip 跳过过行的切片（如 @ids），渲染结果为空时用于报告错误
 }
 
 // newExecutionContext 创建执行上下文
-func newExecutionContext(engine *Engine, args interface{}) *executionContext {
+// ast 为要渲染的模板，用于按需绑定方法和查找 Scope 中的变量
+func newExecutionContext(engine *Engine, args interface{}, ast *TemplateAST) *executionContext {
 	ctx := &executionContext{
-		engine:   engine,
-		scope:    getScope(),
-		covers:   make(map[string][]Node),
-		interp:   interpreter.New(),
-		scopeObj: args,
+		scope:  getScope(),
+		covers: make(map[string][]Node),
 	}
+	ctx.init(engine, args, ast)
+	return ctx
+}
+
+// init 初始化执行上下文：绑定函数、展开参数（scope 和 covers 由调用方创建）
+func (ctx *executionContext) init(engine *Engine, args interface{}, ast *TemplateAST) {
+	ctx.engine = engine
+	ctx.scopeObj = args
+	ctx.calls = ast.methodCalls
+	ctx.names = ast.scopeNames
+	ctx.tmpl = ast
 
 	// 绑定引擎注册的函数
 	for name, fn := range engine.funcs {
+		if engine.ctxFuncs[name] {
+			if ctx.calls != nil && !ctx.calls[name] {
+				continue
+			}
+			fn = ctx.bindRenderContext(fn)
+		}
 		ctx.scope[name] = fn
-		ctx.interp.BindFunc(name, fn)
 	}
 
 	// 将 args 展开到 scope（使用缓存的类型信息）
@@ -181,11 +481,17 @@ func newExecutionContext(engine *Engine, args interface{}) *executionContext {
 		ctx.expandToScopeWithCache(args)
 	}
 
-	return ctx
+	// 绑定内置函数
+	ctx.bindBuiltins()
 }
 
 // expandToScopeWithCache 使用缓存将值展开到 scope
 func (ctx *executionContext) expandToScopeWithCache(args interface{}) {
+	if s, ok := args.(Scope); ok {
+		ctx.err = ctx.expandScope(s)
+		return
+	}
+
 	rv := reflect.ValueOf(args)
 	rt := rv.Type()
 
@@ -211,7 +517,7 @@ func (ctx *executionContext) expandToScopeWithCache(args interface{}) {
 		// map：遍历键值对
 		for _, key := range rv.MapKeys() {
 			if key.Kind() == reflect.String {
-				ctx.scope[key.String()] = rv.MapIndex(key).Interface()
+				ctx.scope[key.String()] = ctx.engine.scopeValue(rv.MapIndex(key).Interface())
 			}
 		}
 	}
@@ -255,15 +561,16 @@ func (ctx *executionContext) expandStructFields(rv reflect.Value) {
 
 		// 添加字段值
 		lowerName := toLowerFirst(field.Name)
+		var val interface{}
 		if fieldValue.CanInterface() {
-			ctx.scope[lowerName] = fieldValue.Interface()
-			ctx.scope[field.Name] = fieldValue.Interface()
+			val = fieldValue.Interface()
 		} else {
 			// 私有字段，使用 unsafe 获取
-			val := getUnexportedFieldValue(fieldValue)
-			ctx.scope[lowerName] = val
-			ctx.scope[field.Name] = val
+			val = getUnexportedFieldValue(fieldValue)
 		}
+		val = ctx.engine.scopeValue(val)
+		ctx.scope[lowerName] = val
+		ctx.scope[field.Name] = val
 	}
 }
 
@@ -299,44 +606,46 @@ func getUnexportedFieldValue(field reflect.Value) interface{} {
 
 // bindMethodsWithCache 使用缓存绑定方法
 func (ctx *executionContext) bindMethodsWithCache(rv reflect.Value) {
-	if !rv.IsValid() {
+	if !rv.IsValid() || ctx.engine.noMethods || (ctx.calls != nil && len(ctx.calls) == 0) {
 		return
 	}
 
-	rt := rv.Type()
-	typeInfo := GetTypeInfo(rt)
+	typeInfo := GetTypeInfo(rv.Type())
+
+	// 统一在指针上按名称取方法（指针的方法集包含值接收器方法；
+	// 缓存的方法下标是值类型的下标，不能直接用于指针）
+	ptrRv := rv
+	if rv.Kind() != reflect.Ptr {
+		// 创建可寻址的副本
+		ptrRv = reflect.New(rv.Type())
+		ptrRv.Elem().Set(rv)
+	}
 
 	// 绑定值接收器方法
-	for name, methodInfo := range typeInfo.Methods {
-		if _, exists := ctx.scope[name]; !exists {
-			ctx.scope[name] = rv.Method(methodInfo.Index).Interface()
-			ctx.interp.BindFunc(name, rv.Method(methodInfo.Index).Interface())
-		}
+	for name := range typeInfo.Methods {
+		ctx.bindMethod(typeInfo.Type, name, ptrRv.MethodByName(name))
 	}
 
 	// 绑定指针接收器方法
-	if rv.Kind() != reflect.Ptr {
-		// 创建可寻址的副本
-		ptrRv := reflect.New(rv.Type())
-		ptrRv.Elem().Set(rv)
-		for name, methodInfo := range typeInfo.PtrMethods {
-			if _, exists := ctx.scope[name]; !exists {
-				ctx.scope[name] = ptrRv.Method(methodInfo.Index).Interface()
-				ctx.interp.BindFunc(name, ptrRv.Method(methodInfo.Index).Interface())
-			}
-		}
-	} else {
-		// rv 已经是指针
-		ptrTypeInfo := GetTypeInfo(rv.Type())
-		for name, methodInfo := range ptrTypeInfo.Methods {
-			if _, exists := ctx.scope[name]; !exists {
-				ctx.scope[name] = rv.Method(methodInfo.Index).Interface()
-				ctx.interp.BindFunc(name, rv.Method(methodInfo.Index).Interface())
-			}
-		}
+	for name := range typeInfo.PtrMethods {
+		ctx.bindMethod(typeInfo.Type, name, ptrRv.MethodByName(name))
 	}
 }
 
+// bindMethod 按方法绑定策略把方法绑定到 scope 和解释器（已存在的同名变量优先）
+func (ctx *executionContext) bindMethod(t reflect.Type, name string, method reflect.Value) {
+	if ctx.calls != nil && !ctx.calls[name] {
+		return
+	}
+	if _, exists := ctx.scope[name]; exists {
+		return
+	}
+	if !ctx.engine.methodPolicy.allows(t, name) {
+		return
+	}
+	ctx.scope[name] = method.Interface()
+}
+
 // executeNodes 执行节点列表
 func (ctx *executionContext) executeNodes(nodes []Node) error {
 	for _, node := range nodes {
@@ -351,7 +660,7 @@ func (ctx *executionContext) executeNodes(nodes []Node) error {
 func (ctx *executionContext) executeNode(node Node) error {
 	switch n := node.(type) {
 	case *TextNode:
-		ctx.sql.WriteString(n.Text)
+		ctx.writeText(n.Text)
 		return nil
 
 	case *VarNode:
@@ -360,6 +669,9 @@ func (ctx *executionContext) executeNode(node Node) error {
 	case *VarExprNode:
 		return ctx.executeVarExprNode(n)
 
+	case *RowsNode:
+		return ctx.executeRows(n)
+
 	case *RawNode:
 		return ctx.executeRawNode(n)
 
@@ -378,6 +690,14 @@ func (ctx *executionContext) executeNode(node Node) error {
 	case *UseNode:
 		return ctx.executeUse(n)
 
+	case *ImportNode:
+		// 加载时未展开的 @import（例如 GetSqlWithCovers 传入的 cover）在渲染时展开
+		nodes, err := ctx.engine.importNodes(n.Path, nil)
+		if err != nil {
+			return err
+		}
+		return ctx.executeNodes(nodes)
+
 	case *DefineNode:
 		return ctx.executeDefine(n)
 
@@ -387,6 +707,24 @@ func (ctx *executionContext) executeNode(node Node) error {
 	case *FuncBlockNode:
 		return ctx.executeFuncBlock(n)
 
+	case *ForEachNode:
+		return ctx.executeForEach(n)
+
+	case *SwitchNode:
+		return ctx.executeSwitch(n)
+
+	case *SetNode:
+		return ctx.executeSet(n)
+
+	case *FlagNode:
+		return ctx.executeFlag(n)
+
+	case *TrimNode:
+		return ctx.executeTrim(n)
+
+	case *DirectiveNode:
+		return ctx.executeDirective(n)
+
 	default:
 		return fmt.Errorf("unknown node type: %T", node)
 	}
@@ -399,15 +737,17 @@ func (ctx *executionContext) executeVarNode(n *VarNode) error {
 	if n.Conditional {
 		// 条件控制：如果字段不存在或值为假，跳过当前行
 		if !ok || !ctx.isTruthy(value) {
+			ctx.tracef("skip", "@%s? is empty, line skipped", n.Name)
 			ctx.skipCurrentLine()
 			return nil
 		}
 	} else if !ok {
-		return fmt.Errorf("variable not found: %s", n.Name)
+		if skip, err := ctx.missingVar("@" + n.Name); skip || err != nil {
+			return err
+		}
 	}
 
-	ctx.appendArg(value)
-	return nil
+	return ctx.bindValue(value, "@"+n.Name)
 }
 
 // executeVarExprNode 执行变量表达式节点
@@ -420,13 +760,13 @@ func (ctx *executionContext) executeVarExprNode(n *VarExprNode) error {
 	if n.Conditional {
 		// 条件控制：检查值是否为 "真"
 		if !ctx.isTruthy(value) {
+			ctx.tracef("skip", "@ %s @? is empty, line skipped", n.Expr)
 			ctx.skipCurrentLine()
 			return nil
 		}
 	}
 
-	ctx.appendArg(value)
-	return nil
+	return ctx.bindValue(value, "@ "+n.Expr+" @")
 }
 
 // executeRawNode 执行直接输出变量节点
@@ -436,15 +776,19 @@ func (ctx *executionContext) executeRawNode(n *RawNode) error {
 	if n.Conditional {
 		// 条件控制：如果字段不存在或值为假，跳过当前行
 		if !ok || !ctx.isTruthy(value) {
+			ctx.tracef("skip", "@=%s? is empty, line skipped", n.Name)
 			ctx.skipCurrentLine()
 			return nil
 		}
 	} else if !ok {
-		return fmt.Errorf("variable not found: %s", n.Name)
+		if skip, err := ctx.missingVar("@=" + n.Name); skip || err != nil {
+			return err
+		}
+		ctx.sql.WriteString("NULL")
+		return nil
 	}
 
-	ctx.sql.WriteString(fmt.Sprintf("%v", value))
-	return nil
+	return ctx.writeRaw(value)
 }
 
 // executeRawExprNode 执行直接输出表达式节点
@@ -454,13 +798,27 @@ func (ctx *executionContext) executeRawExprNode(n *RawExprNode) error {
 		return err
 	}
 
+	if q, ok := value.(Query); ok {
+		ctx.executeQueryValue(q, n.Conditional)
+		return nil
+	}
+
 	if n.Conditional {
 		if !ctx.isTruthy(value) {
+			ctx.tracef("skip", "@= %s @? is empty, line skipped", n.Expr)
 			ctx.skipCurrentLine()
 			return nil
 		}
 	}
 
+	return ctx.writeRaw(value)
+}
+
+// writeRaw 原样输出值（@= ），uuid / nowUTC / seq 生成的值只能作为参数绑定
+func (ctx *executionContext) writeRaw(value interface{}) error {
+	if g, ok := value.(generated); ok {
+		return fmt.Errorf("%s() must be bound as a parameter, not output raw", g.name)
+	}
 	ctx.sql.WriteString(fmt.Sprintf("%v", value))
 	return nil
 }
@@ -474,6 +832,7 @@ func (ctx *executionContext) executeConditionalLine(n *ConditionalLineNode) erro
 	}
 
 	if !result {
+		ctx.tracef("skip", "%s is false, line skipped", n.Condition)
 		return nil // 条件为假，跳过整行
 	}
 
@@ -486,6 +845,16 @@ func (ctx *executionContext) isTruthy(value interface{}) bool {
 	if value == nil {
 		return false
 	}
+	if truthy, ok := ctx.engine.truthy(value); ok {
+		return truthy
+	}
+	if a, ok := value.(ArrayParam); ok {
+		return ctx.isTruthy(a.Value)
+	}
+	if sub, ok := subQuery(value); ok {
+		// 子查询的 SQL 为空时视为假
+		return strings.TrimSpace(sub.SQL) != ""
+	}
 
 	rv := reflect.ValueOf(value)
 	switch rv.Kind() {
@@ -508,43 +877,98 @@ func (ctx *executionContext) isTruthy(value interface{}) bool {
 	}
 }
 
+// missingVar 按 WithMissingVarPolicy 处理不存在的变量（ref 为 @name 或 @=name）：
+// 返回 skip 表示所在的行已跳过；skip 和 err 都为零值时调用方按 NULL 处理
+func (ctx *executionContext) missingVar(ref string) (skip bool, err error) {
+	switch ctx.engine.missingVars {
+	case MissingVarNull:
+		ctx.tracef("missing", "%s not found, NULL used", ref)
+		return false, nil
+	case MissingVarSkip:
+		ctx.tracef("skip", "%s not found, line skipped", ref)
+		ctx.skipLine()
+		return true, nil
+	}
+	name := strings.TrimLeft(ref, "@=")
+	return false, ctx.variableNotFound(name)
+}
+
 // skipCurrentLine 跳过当前行（移除到上一个换行符之后的内容）
 func (ctx *executionContext) skipCurrentLine() {
-	sql := ctx.sql.String()
-	lastNewline := strings.LastIndex(sql, "\n")
-	if lastNewline >= 0 {
-		ctx.sql.Reset()
-		ctx.sql.WriteString(sql[:lastNewline+1])
-	} else {
-		ctx.sql.Reset()
+	ctx.sql.truncateLine()
+	ctx.dropArgs()
+}
+
+// dropArgs 丢弃占位符已经不在 ctx.sql 中的参数
+func (ctx *executionContext) dropArgs() {
+	n := len(ctx.args)
+	for n > 0 && ctx.argPos[n-1] >= ctx.sql.Len() {
+		n--
 	}
+	ctx.args = ctx.args[:n]
+	ctx.argPos = ctx.argPos[:n]
+}
+
+// skipLine 跳过整行：移除行内已经输出的内容，之后到行尾为止的节点照常执行，
+// 它们的输出和参数在遇到换行（或渲染结束）时由 endSkip 撤销
+func (ctx *executionContext) skipLine() {
+	ctx.skipCurrentLine()
+	ctx.skipRest = true
+	ctx.skipMark = ctx.sql.Len()
+}
+
+// endSkip 结束 skipLine 开始的跳过，撤销之后的输出和参数。在取出 ctx.sql 之前调用
+func (ctx *executionContext) endSkip() {
+	if !ctx.skipRest {
+		return
+	}
+	ctx.skipRest = false
+	ctx.inList = nil
+	if ctx.skipMark < ctx.sql.Len() {
+		ctx.sql.buf = ctx.sql.buf[:ctx.skipMark]
+	}
+	ctx.dropArgs()
 }
 
 // executeFuncBlock 执行函数块节点 @ func() {}
 func (ctx *executionContext) executeFuncBlock(n *FuncBlockNode) error {
 	// 先执行块内节点，生成 Query
-	subCtx := &executionContext{
+	subCtx := ctx.funcBlockContext()
+	if err := subCtx.executeNodes(n.Body); err != nil {
+		return err
+	}
+	subCtx.endSkip()
+	return ctx.applyFuncBlock(n.FuncExpr, subCtx.sql.take(), subCtx.args)
+}
+
+// funcBlockContext 创建执行函数块内节点的上下文（与当前上下文共享 scope 和 cover，输出单独收集）
+func (ctx *executionContext) funcBlockContext() *executionContext {
+	return &executionContext{
 		engine:   ctx.engine,
+		goctx:    ctx.goctx,
 		scope:    ctx.scope,
 		covers:   ctx.covers,
-		interp:   ctx.interp,
 		scopeObj: ctx.scopeObj,
 		typeInfo: ctx.typeInfo,
-	}
+		trace:    ctx.trace,
+		tmpl:     ctx.tmpl,
 
-	if err := subCtx.executeNodes(n.Body); err != nil {
-		return err
+		placeholders: ctx.placeholders,
+		argBase:      ctx.argBase + len(ctx.args),
 	}
+}
 
+// applyFuncBlock 以函数块内容（body 和 args）调用函数，输出函数处理后的结果
+func (ctx *executionContext) applyFuncBlock(funcExpr, body string, args []interface{}) error {
 	// 创建 Query 对象（优先以指针形式传递，便于函数块直接修改 SQL/Params）
 	query := &Query{
-		SQL:    subCtx.sql.String(),
-		Params: subCtx.args,
+		SQL:    body,
+		Params: args,
 	}
 
 	// 调用函数，传入 Query 作为最后一个参数
 	// 构造调用表达式
-	funcExpr := strings.TrimSpace(n.FuncExpr)
+	funcExpr = strings.TrimSpace(funcExpr)
 
 	// 检查是否是 scope 中的函数
 	if fn, ok := ctx.scope[funcExpr]; ok {
@@ -564,8 +988,7 @@ func (ctx *executionContext) executeFuncBlock(n *FuncBlockNode) error {
 						query = qp
 					}
 				}
-				ctx.sql.WriteString(query.SQL)
-				ctx.args = append(ctx.args, query.Params...)
+				ctx.writeArgs(query.SQL, query.Params)
 				return nil
 			}
 			// 兼容旧：func(Query)
@@ -579,8 +1002,7 @@ func (ctx *executionContext) executeFuncBlock(n *FuncBlockNode) error {
 						*query = q
 					}
 				}
-				ctx.sql.WriteString(query.SQL)
-				ctx.args = append(ctx.args, query.Params...)
+				ctx.writeArgs(query.SQL, query.Params)
 				return nil
 			}
 		}
@@ -612,14 +1034,12 @@ func (ctx *executionContext) executeFuncBlock(n *FuncBlockNode) error {
 
 	// 绑定 query 到作用域（指针），便于函数直接修改
 	ctx.scope["__query__"] = query
-	ctx.interp.BindValue("__query__", query)
 
 	// 调用函数
 	result, err := ctx.evalExpr(funcExpr)
 	if err != nil {
 		// 如果函数调用失败，直接输出块内容
-		ctx.sql.WriteString(subCtx.sql.String())
-		ctx.args = append(ctx.args, subCtx.args...)
+		ctx.writeArgs(body, args)
 		return nil
 	}
 
@@ -634,8 +1054,7 @@ func (ctx *executionContext) executeFuncBlock(n *FuncBlockNode) error {
 		}
 	}
 
-	ctx.sql.WriteString(query.SQL)
-	ctx.args = append(ctx.args, query.Params...)
+	ctx.writeArgs(query.SQL, query.Params)
 
 	return nil
 }
@@ -647,6 +1066,7 @@ func (ctx *executionContext) executeIf(n *IfNode) error {
 	if err != nil {
 		return err
 	}
+	ctx.tracef("if", "if %s: %v", n.Condition, result)
 
 	if result {
 		return ctx.executeNodes(n.Body)
@@ -658,6 +1078,7 @@ func (ctx *executionContext) executeIf(n *IfNode) error {
 		if err != nil {
 			return err
 		}
+		ctx.tracef("if", "else if %s: %v", elseIf.Condition, result)
 		if result {
 			return ctx.executeNodes(elseIf.Body)
 		}
@@ -665,6 +1086,7 @@ func (ctx *executionContext) executeIf(n *IfNode) error {
 
 	// else
 	if n.Else != nil {
+		ctx.tracef("if", "else")
 		return ctx.executeNodes(n.Else.Body)
 	}
 
@@ -673,20 +1095,54 @@ func (ctx *executionContext) executeIf(n *IfNode) error {
 
 // executeFor 执行 for 节点
 func (ctx *executionContext) executeFor(n *ForNode) error {
-	expr := strings.TrimSpace(n.Expr)
+	loop, err := ctx.newForLoop(n.Expr)
+	if err != nil {
+		return err
+	}
+	for loop.Next() {
+		if err := ctx.executeNodes(n.Body); err != nil {
+			return err
+		}
+	}
+	return loop.err
+}
 
-	// 判断是 range 形式还是传统 for 形式
+// forLoop @for 的循环状态，Next 设置循环变量并返回是否继续（执行器和编译后的程序共用）
+type forLoop struct {
+	ctx  *executionContext
+	expr string
+	err  error
+	next int // 已经开始的循环次数
+
+	// range 形式：i, v := range xxx
+	isRange  bool
+	indexVar string
+	valueVar string
+	rv       reflect.Value
+	keys     []reflect.Value // map 的键（遍历顺序）
+	count    int
+
+	// 传统形式：i := 0; i < 10; i++
+	varName  string
+	condPart string
+	postPart string
+	noInit   bool // 初始化不是 :=，不执行循环
+}
+
+// newForLoop 解析 for 表达式，判断是 range 形式还是传统 for 形式
+func (ctx *executionContext) newForLoop(expr string) (*forLoop, error) {
+	expr = strings.TrimSpace(expr)
+	l := &forLoop{ctx: ctx, expr: expr}
 	if strings.Contains(expr, "range") {
-		return ctx.executeForRange(n)
+		return l, l.initRange()
 	}
-	return ctx.executeForTraditional(n)
+	return l, l.initTraditional()
 }
 
-// executeForRange 执行 range 形式的 for 循环
-func (ctx *executionContext) executeForRange(n *ForNode) error {
-	expr := strings.TrimSpace(n.Expr)
-
-	// 解析 range 表达式：i, v := range xxx
+// initRange 解析 range 表达式：i, v := range xxx
+func (l *forLoop) initRange() error {
+	ctx, expr := l.ctx, l.expr
+	l.isRange = true
 	parts := strings.SplitN(expr, ":=", 2)
 	if len(parts) != 2 {
 		return fmt.Errorf("invalid range expression: %s", expr)
@@ -697,13 +1153,11 @@ func (ctx *executionContext) executeForRange(n *ForNode) error {
 
 	// 解析变量名
 	varNames := strings.Split(varPart, ",")
-	indexVar := ""
-	valueVar := ""
 	if len(varNames) >= 1 {
-		indexVar = strings.TrimSpace(varNames[0])
+		l.indexVar = strings.TrimSpace(varNames[0])
 	}
 	if len(varNames) >= 2 {
-		valueVar = strings.TrimSpace(varNames[1])
+		l.valueVar = strings.TrimSpace(varNames[1])
 	}
 
 	// 评估 range 表达式
@@ -712,91 +1166,90 @@ func (ctx *executionContext) executeForRange(n *ForNode) error {
 		return fmt.Errorf("range expression error: %w", err)
 	}
 
-	rv := reflect.ValueOf(rangeValue)
-	switch rv.Kind() {
+	l.rv = reflect.ValueOf(rangeValue)
+	switch l.rv.Kind() {
 	case reflect.Slice, reflect.Array:
-		for i := 0; i < rv.Len(); i++ {
-			// 设置循环变量
-			if indexVar != "" && indexVar != "_" {
-				ctx.scope[indexVar] = i
-			}
-			if valueVar != "" && valueVar != "_" {
-				ctx.scope[valueVar] = rv.Index(i).Interface()
-			}
-
-			// 执行 body
-			if err := ctx.executeNodes(n.Body); err != nil {
-				return err
-			}
-		}
+		l.count = l.rv.Len()
 	case reflect.Map:
-		for _, key := range rv.MapKeys() {
-			if indexVar != "" && indexVar != "_" {
-				ctx.scope[indexVar] = key.Interface()
-			}
-			if valueVar != "" && valueVar != "_" {
-				ctx.scope[valueVar] = rv.MapIndex(key).Interface()
-			}
-
-			if err := ctx.executeNodes(n.Body); err != nil {
-				return err
-			}
+		l.keys = l.rv.MapKeys()
+		if ctx.engine.deterministic {
+			sortMapKeys(l.keys)
 		}
+		l.count = len(l.keys)
 	default:
-		return fmt.Errorf("cannot range over %s", rv.Kind())
+		return fmt.Errorf("cannot range over %s", l.rv.Kind())
 	}
-
+	ctx.tracef("for", "for %s: %d iterations", expr, l.count)
 	return nil
 }
 
-// executeForTraditional 执行传统 for 循环
-func (ctx *executionContext) executeForTraditional(n *ForNode) error {
-	expr := strings.TrimSpace(n.Expr)
-
-	// 解析 for i := 0; i < 10; i++
-	parts := strings.Split(expr, ";")
+// initTraditional 解析并执行传统 for 循环的初始化：for i := 0; i < 10; i++
+func (l *forLoop) initTraditional() error {
+	parts := strings.Split(l.expr, ";")
 	if len(parts) != 3 {
-		return fmt.Errorf("invalid for expression: %s", expr)
+		return fmt.Errorf("invalid for expression: %s", l.expr)
 	}
 
 	initPart := strings.TrimSpace(parts[0])
-	condPart := strings.TrimSpace(parts[1])
-	postPart := strings.TrimSpace(parts[2])
-
-	// 执行初始化
-	if strings.Contains(initPart, ":=") {
-		initParts := strings.SplitN(initPart, ":=", 2)
-		varName := strings.TrimSpace(initParts[0])
-		initValue, err := ctx.evalExpr(strings.TrimSpace(initParts[1]))
-		if err != nil {
-			return fmt.Errorf("for init error: %w", err)
-		}
-		ctx.scope[varName] = initValue
-
-		// 循环
-		for {
-			// 检查条件
-			cond, err := ctx.evalCondition(condPart)
-			if err != nil {
-				return fmt.Errorf("for condition error: %w", err)
-			}
-			if !cond {
-				break
-			}
+	l.condPart = strings.TrimSpace(parts[1])
+	l.postPart = strings.TrimSpace(parts[2])
 
-			// 执行 body
-			if err := ctx.executeNodes(n.Body); err != nil {
-				return err
-			}
+	if !strings.Contains(initPart, ":=") {
+		l.noInit = true
+		return nil
+	}
+	initParts := strings.SplitN(initPart, ":=", 2)
+	l.varName = strings.TrimSpace(initParts[0])
+	initValue, err := l.ctx.evalExpr(strings.TrimSpace(initParts[1]))
+	if err != nil {
+		return fmt.Errorf("for init error: %w", err)
+	}
+	l.ctx.scope[l.varName] = initValue
+	return nil
+}
 
-			// 执行 post
-			if err := ctx.executePost(varName, postPart); err != nil {
-				return err
-			}
+// Next 开始下一次循环：设置循环变量（传统形式先执行 post 再检查条件），没有下一次或出错时返回 false
+func (l *forLoop) Next() bool {
+	ctx := l.ctx
+	if l.isRange {
+		if l.next == l.count {
+			return false
+		}
+		var index, value interface{}
+		if l.keys != nil {
+			index, value = l.keys[l.next].Interface(), l.rv.MapIndex(l.keys[l.next]).Interface()
+		} else {
+			index, value = l.next, l.rv.Index(l.next).Interface()
+		}
+		l.next++
+		if l.indexVar != "" && l.indexVar != "_" {
+			ctx.scope[l.indexVar] = index
 		}
+		if l.valueVar != "" && l.valueVar != "_" {
+			ctx.scope[l.valueVar] = value
+		}
+		return true
 	}
 
-	return nil
+	if l.noInit {
+		return false
+	}
+	if l.next > 0 {
+		if l.err = ctx.executePost(l.varName, l.postPart); l.err != nil {
+			return false
+		}
+	}
+	cond, err := ctx.evalCondition(l.condPart)
+	if err != nil {
+		l.err = fmt.Errorf("for condition error: %w", err)
+		return false
+	}
+	if !cond {
+		ctx.tracef("for", "for %s: %d iterations", l.expr, l.next)
+		return false
+	}
+	l.next++
+	return true
 }
 
 // executePost 执行 for 循环的 post 语句
@@ -848,14 +1301,10 @@ func (ctx *executionContext) executePost(varName, postPart string) error {
 	return nil
 }
 
-// executeCode 执行直接代码
+// executeCode 执行直接代码。goscript2 的 Eval 只声明包装出的 main 而不调用它，
+// 代码块实际上只做语法检查，所以直接解析，不必为每个代码块创建解释器
 func (ctx *executionContext) executeCode(code string) error {
-	interp := interpreter.New()
-	for name, value := range ctx.scope {
-		interp.BindValue(name, value)
-	}
-
-	// 直接执行代码（需要包装成合法的 Go 代码）
+	// 包装成合法的 Go 代码
 	wrappedCode := fmt.Sprintf(`
 package main
 
@@ -864,8 +1313,10 @@ func main() {
 }
 `, code)
 
-	_, err := interp.Eval(wrappedCode)
-	return err
+	if _, err := parser.ParseFile(token.NewFileSet(), "", wrappedCode, parser.AllErrors); err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	return nil
 }
 
 // executeUse 执行 use 节点
@@ -873,7 +1324,7 @@ func (ctx *executionContext) executeUse(n *UseNode) error {
 	// 解析路径
 	parts := strings.Split(n.Path, ".")
 	if len(parts) < 2 {
-		return fmt.Errorf("invalid use path: %s", n.Path)
+		return codeError(CodeInvalidPath, "invalid use path: %s", n.Path)
 	}
 
 	namespace := parts[0]
@@ -888,7 +1339,30 @@ func (ctx *executionContext) executeUse(n *UseNode) error {
 	// 获取目标模板的 AST
 	ast, ok := ctx.engine.compiledAST[key]
 	if !ok {
-		return fmt.Errorf("template not found: %s", key)
+		return ctx.engine.templateNotFound(key)
+	}
+	ctx.engine.recordUsage(key)
+
+	if max := ctx.engine.limits.MaxUseDepth; max > 0 && len(ctx.uses) >= max {
+		return codeError(CodeTemplateLimit, "use chain exceeds %d: %s -> %s", max, strings.Join(ctx.uses, " -> "), n.Path)
+	}
+	ctx.uses = append(ctx.uses, n.Path)
+	defer func() { ctx.uses = ctx.uses[:len(ctx.uses)-1] }()
+	ctx.tracef("use", "use %s", n.Path)
+	defer ctx.traceIn()()
+	outer := ctx.tmpl
+	ctx.tmpl = ast
+	defer func() { ctx.tmpl = outer }()
+
+	// 带实参时在当前 scope 中求值并绑定到 define 的形参
+	if defineName != "" && n.Args != nil {
+		if define := findDefine(ast.Nodes, defineName); define != nil {
+			restore, err := ctx.bindDefineArgs(define, n)
+			if err != nil {
+				return err
+			}
+			defer restore()
+		}
 	}
 
 	// 设置 covers
@@ -898,20 +1372,9 @@ func (ctx *executionContext) executeUse(n *UseNode) error {
 		ctx.covers[cover.Name] = cover.Body
 	}
 
-	// 如果指定了 define，只执行该 define
-	if defineName != "" {
-		defineNode := findDefine(ast.Nodes, defineName)
-		if defineNode == nil {
-			return fmt.Errorf("define not found: %s in template %s", defineName, key)
-		}
-		if err := ctx.executeNodes(defineNode.Body); err != nil {
-			return err
-		}
-	} else {
-		// 执行整个模板
-		if err := ctx.executeNodes(ast.Nodes); err != nil {
-			return err
-		}
+	// 执行整个模板（指定了 define 时只执行该 define）
+	if err := ctx.executeTemplate(ast, key, defineName); err != nil {
+		return err
 	}
 
 	// 恢复 covers
@@ -922,21 +1385,19 @@ func (ctx *executionContext) executeUse(n *UseNode) error {
 
 // executeDefine 执行 define 节点
 func (ctx *executionContext) executeDefine(n *DefineNode) error {
-	// 构建完整路径（用于嵌套 define 块的覆盖）
-	// 例如：如果当前路径栈是 ["abc"]，当前 define 是 "d"，则完整路径是 "abc.d"
-	fullPath := n.Name
-	if len(ctx.definePath) > 0 {
-		fullPath = strings.Join(ctx.definePath, ".") + "." + n.Name
+	if len(n.Params) > 0 {
+		// 带参数的 define 相当于函数声明，只在被 @use 或直接渲染时输出
+		return nil
 	}
 
-	// 检查是否有 cover 覆盖（优先检查完整路径，再检查简单名称）
-	if coverBody, ok := ctx.covers[fullPath]; ok {
-		return ctx.executeNodes(coverBody)
-	}
-	// 兼容：也检查简单名称
-	if coverBody, ok := ctx.covers[n.Name]; ok {
+	fullPath, coverBody, covered := ctx.coverFor(n.Name)
+	if covered {
+		ctx.tracef("cover", "define %s covered", fullPath)
+		defer ctx.traceIn()()
 		return ctx.executeNodes(coverBody)
 	}
+	ctx.tracef("define", "define %s", fullPath)
+	defer ctx.traceIn()()
 
 	// 没有覆盖，执行原始内容
 	// 将当前 define 名称压入路径栈
@@ -949,29 +1410,88 @@ func (ctx *executionContext) executeDefine(n *DefineNode) error {
 	return ctx.executeNodes(n.Body)
 }
 
-// appendArg 添加参数（支持数组展开）
-func (ctx *executionContext) appendArg(value interface{}) {
-	rv := reflect.ValueOf(value)
+// coverFor 返回 define 的完整路径和覆盖它的 cover（用于嵌套 define 块的覆盖）。
+// 例如：如果当前路径栈是 ["abc"]，当前 define 是 "d"，则完整路径是 "abc.d"
+func (ctx *executionContext) coverFor(name string) (fullPath string, body []Node, ok bool) {
+	fullPath = name
+	if len(ctx.definePath) > 0 {
+		fullPath = strings.Join(ctx.definePath, ".") + "." + name
+	}
+
+	// 检查是否有 cover 覆盖（优先检查完整路径，再检查简单名称）
+	if body, ok = ctx.covers[fullPath]; ok {
+		return fullPath, body, true
+	}
+	// 兼容：也检查简单名称
+	body, ok = ctx.covers[name]
+	return fullPath, body, ok
+}
 
-	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
-		n := rv.Len()
+// appendArg 添加参数（支持数组展开，[]byte、driver.Valuer 等作为单个参数见 sliceLen；Query / Fragment 作为子查询拼接 SQL 并追加参数）
+func (ctx *executionContext) appendArg(value interface{}) {
+	if sub, ok := subQuery(value); ok {
+		// 子查询：SQL 原样拼接，参数按顺序追加
+		ctx.writeQuery(sub.Flatten())
+		return
+	}
+	if n := ctx.engine.sliceLen(value); n >= 0 {
+		rv := reflect.ValueOf(value)
 		for i := 0; i < n; i++ {
 			if i > 0 {
 				ctx.sql.WriteString(", ")
 			}
-			ctx.sql.WriteString("?")
-			ctx.args = append(ctx.args, rv.Index(i).Interface())
+			ctx.bindArg(ctx.engine.paramValue(rv.Index(i).Interface()))
 		}
 	} else {
-		ctx.sql.WriteString("?")
-		ctx.args = append(ctx.args, value)
+		if g, ok := value.(generated); ok {
+			value = g.value
+		}
+		ctx.bindArg(ctx.engine.paramValue(value))
 	}
 }
 
 // evalExpr 评估表达式
 func (ctx *executionContext) evalExpr(expr string) (interface{}, error) {
+	if ctx.pinned != nil {
+		// 常驻模板预先解析的简单表达式
+		if fn := ctx.pinned.exprs[expr]; fn != nil {
+			if value, ok := fn(ctx.scope); ok {
+				return value, nil
+			}
+		}
+	}
 	// 使用 goscript2 评估表达式
-	return ctx.interp.EvalExprWithArgs(expr, ctx.scope)
+	interp := exprInterps.Get().(*interpreter.Interpreter)
+	value, err := interp.EvalExprWithArgs(expr, ctx.exprScope())
+	exprInterps.Put(interp)
+	if err == nil && ctx.err != nil {
+		// 内置函数（如 render）执行失败
+		err, ctx.err = ctx.err, nil
+	}
+	return value, err
+}
+
+// exprInterps 评估表达式用的解释器。变量和函数都通过 EvalExprWithArgs 传入，不绑定到解释器的全局作用域，
+// 所以解释器不保存任何渲染的状态，可以在渲染之间复用（评估期间独占，用完放回）
+var exprInterps = sync.Pool{
+	New: func() interface{} { return interpreter.New() },
+}
+
+// exprScope 返回传给解释器的变量表。
+// 解释器不支持值为 nil 的变量（如无效的 sql.NullString），这些变量只对 @name / @name? 可见
+func (ctx *executionContext) exprScope() map[string]interface{} {
+	for _, value := range ctx.scope {
+		if value == nil {
+			scope := make(map[string]interface{}, len(ctx.scope))
+			for name, value := range ctx.scope {
+				if value != nil {
+					scope[name] = value
+				}
+			}
+			return scope
+		}
+	}
+	return ctx.scope
 }
 
 // evalCondition 评估条件表达式
@@ -996,6 +1516,9 @@ func (ctx *executionContext) evalCondition(condition string) (bool, error) {
 	case nil:
 		return false, nil
 	default:
+		if truthy, ok := ctx.engine.truthy(v); ok {
+			return truthy, nil
+		}
 		return !reflect.ValueOf(v).IsZero(), nil
 	}
 }
@@ -0,0 +1,231 @@
+// Package gosqlrpc 提供 gosql 模板渲染的 gRPC 服务（render / list / validate）和 Go 客户端，
+// 服务定义见 gosql.proto（gosql.pb.go、gosql_grpc.pb.go 由它生成）。
+// Service 实现了各 RPC 的语义；NewServer / Serve 提供服务端，Dial / NewClient 提供客户端，
+// 其它语言用 gosql.proto 生成客户端即可调用
+package gosqlrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gosql.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/llyb120/gosql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service TemplateServiceServer 的实现。
+// 模板的渲染错误放在响应中返回，方法只在请求本身无效（如参数不是合法 JSON）时返回错误（codes.InvalidArgument）
+type Service struct {
+	UnimplementedTemplateServiceServer
+	Engine *gosql.Engine
+}
+
+// Render 渲染模板
+func (s *Service) Render(ctx context.Context, req *RenderRequest) (*RenderResponse, error) {
+	args, err := decodeArgs(req.ArgsJson)
+	if err != nil {
+		return nil, err
+	}
+	query, err := s.Engine.GetSqlContext(ctx, req.Path, args)
+	if err != nil {
+		return &RenderResponse{Error: err.Error(), Code: string(gosql.CodeOf(err))}, nil
+	}
+	params := query.Params
+	if params == nil {
+		params = []interface{}{}
+	}
+	bs, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return &RenderResponse{Sql: query.SQL, ParamsJson: string(bs)}, nil
+}
+
+// List 列出模板，Namespace 不为空时只返回该命名空间的模板
+func (s *Service) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	resp := &ListResponse{}
+	for _, info := range s.Engine.Templates() {
+		if req.Namespace != "" && info.Namespace != req.Namespace {
+			continue
+		}
+		t := &Template{Path: info.Path, Description: info.Description, Tags: info.Tags}
+		for _, p := range info.Params {
+			t.Params = append(t.Params, p.Name)
+		}
+		resp.Templates = append(resp.Templates, t)
+	}
+	return resp, nil
+}
+
+// Validate 渲染模板并校验断言（同 Engine.DryRun）
+func (s *Service) Validate(ctx context.Context, req *ValidateRequest) (*ValidateResponse, error) {
+	args, err := decodeArgs(req.ArgsJson)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Engine.DryRun(req.Path, args); err != nil {
+		return &ValidateResponse{Error: err.Error(), Code: string(gosql.CodeOf(err))}, nil
+	}
+	return &ValidateResponse{Valid: true}, nil
+}
+
+// decodeArgs 解析 JSON 参数对象，格式错误时返回 codes.InvalidArgument
+func decodeArgs(s string) (map[string]interface{}, error) {
+	args, err := gosql.DecodeJSONArgs(s)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "gosqlrpc: invalid args_json: "+err.Error())
+	}
+	return args, nil
+}
+
+// NewServer 创建注册了渲染服务的 gRPC 服务端，opts 可以添加 TLS、鉴权拦截器等
+func NewServer(engine *gosql.Engine, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	RegisterTemplateServiceServer(server, &Service{Engine: engine})
+	return server
+}
+
+// Serve 在 lis 上提供 gRPC 服务，直到 lis 关闭
+func Serve(lis net.Listener, engine *gosql.Engine, opts ...grpc.ServerOption) error {
+	err := NewServer(engine, opts...).Serve(lis)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// TokenAuth 返回校验 Bearer token 的拦截器（作为 grpc.UnaryInterceptor 传给 NewServer / Serve），
+// 请求的 metadata 中没有 authorization: Bearer <token> 或 token 不一致时返回 codes.Unauthenticated
+func TokenAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var got string
+		if values := md.Get("authorization"); len(values) > 0 {
+			got = values[0]
+		}
+		got, ok := strings.CutPrefix(got, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// WithToken 让客户端的每个请求带上 authorization: Bearer <token>（对应服务端的 TokenAuth）
+func WithToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(bearerToken(token))
+}
+
+// bearerToken 以 Bearer token 作为每个请求的凭证
+type bearerToken string
+
+// GetRequestMetadata 实现 credentials.PerRPCCredentials
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity 实现 credentials.PerRPCCredentials，允许在内网的明文连接上使用
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// Client 渲染服务的客户端，可以并发使用
+type Client struct {
+	conn *grpc.ClientConn // Dial 创建的连接（NewClient 时为 nil）
+	rpc  TemplateServiceClient
+}
+
+// Dial 连接渲染服务。默认使用明文连接（只应在内网使用），opts 中的 grpc.WithTransportCredentials 可以改为 TLS，
+// WithToken 添加 Bearer token
+func Dial(target string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, rpc: NewTemplateServiceClient(conn)}, nil
+}
+
+// NewClient 基于已有的 gRPC 连接创建客户端，连接由调用方关闭
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: NewTemplateServiceClient(conn)}
+}
+
+// Close 关闭 Dial 创建的连接
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Render 远程渲染模板，args 按 JSON 编码传递。模板错误返回 *gosql.Error（保留错误码）
+func (c *Client) Render(ctx context.Context, path string, args interface{}) (gosql.Query, error) {
+	argsJSON, err := encodeArgs(args)
+	if err != nil {
+		return gosql.Query{}, err
+	}
+	resp, err := c.rpc.Render(ctx, &RenderRequest{Path: path, ArgsJson: argsJSON})
+	if err != nil {
+		return gosql.Query{}, err
+	}
+	if resp.Error != "" {
+		return gosql.Query{}, remoteError(resp.Code, resp.Error)
+	}
+	var params []interface{}
+	dec := json.NewDecoder(strings.NewReader(resp.ParamsJson))
+	dec.UseNumber()
+	if err := dec.Decode(&params); err != nil {
+		return gosql.Query{}, err
+	}
+	gosql.JSONValue(params)
+	return gosql.Query{SQL: resp.Sql, Params: params}, nil
+}
+
+// List 远程列出模板
+func (c *Client) List(ctx context.Context, namespace string) ([]*Template, error) {
+	resp, err := c.rpc.List(ctx, &ListRequest{Namespace: namespace})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Templates, nil
+}
+
+// Validate 远程校验模板，不满足时返回 *gosql.Error
+func (c *Client) Validate(ctx context.Context, path string, args interface{}) error {
+	argsJSON, err := encodeArgs(args)
+	if err != nil {
+		return err
+	}
+	resp, err := c.rpc.Validate(ctx, &ValidateRequest{Path: path, ArgsJson: argsJSON})
+	if err != nil {
+		return err
+	}
+	if !resp.Valid {
+		return remoteError(resp.Code, resp.Error)
+	}
+	return nil
+}
+
+// encodeArgs 把参数编码为 JSON，nil 编码为空串
+func encodeArgs(args interface{}) (string, error) {
+	if args == nil {
+		return "", nil
+	}
+	bs, err := json.Marshal(args)
+	return string(bs), err
+}
+
+// remoteError 还原服务端返回的错误
+func remoteError(code, msg string) error {
+	return &gosql.Error{Code: gosql.ErrorCode(code), Err: errors.New(msg)}
+}
@@ -0,0 +1,110 @@
+package gosqlrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/llyb120/gosql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientServer(t *testing.T) {
+	engine := gosql.New()
+	markdown := "# user\n\n## byId\nassert: params <= 2\n```sql\nselect * from users where id in (@id) and score > @score\n```\n\n# order\n\n## list\n```sql\nselect * from orders\n```\n"
+	if err := engine.LoadMarkdown(markdown); err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- Serve(lis, engine) }()
+
+	client, err := Dial(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	q, err := client.Render(ctx, "user.byId", map[string]interface{}{"id": 7, "score": 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.SQL != "select * from users where id in (?) and score > ?" || len(q.Params) != 2 || q.Params[0] != int64(7) || q.Params[1] != 1.5 {
+		t.Errorf("unexpected query %+v", q)
+	}
+
+	_, err = client.Render(ctx, "user.byName", nil)
+	if gosql.CodeOf(err) != gosql.CodeTemplateNotFound {
+		t.Errorf("expected template not found, got %v", err)
+	}
+
+	templates, err := client.List(ctx, "user")
+	if err != nil || len(templates) != 1 || templates[0].Path != "user.byId" || len(templates[0].Params) != 2 {
+		t.Errorf("unexpected templates %+v, %v", templates, err)
+	}
+
+	if err := client.Validate(ctx, "user.byId", map[string]interface{}{"id": []int{1, 2}, "score": 2}); gosql.CodeOf(err) != gosql.CodeAssertion {
+		t.Errorf("expected assertion error, got %v", err)
+	}
+	if err := client.Validate(ctx, "order.list", nil); err != nil {
+		t.Errorf("unexpected validate error %v", err)
+	}
+
+	// 其它语言按 gosql.proto 生成的客户端直接发送请求
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = NewTemplateServiceClient(conn).Render(ctx, &RenderRequest{Path: "user.byId", ArgsJson: "{"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected invalid argument, got %v", err)
+	}
+	if templates, err := NewClient(conn).List(ctx, ""); err != nil || len(templates) != 2 {
+		t.Errorf("unexpected templates %+v, %v", templates, err)
+	}
+
+	lis.Close()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected serve error %v", err)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	engine := gosql.New()
+	if err := engine.LoadMarkdown("# order\n\n## list\n```sql\nselect * from orders\n```\n"); err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go Serve(lis, engine, grpc.UnaryInterceptor(TokenAuth("s3cret")))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		opts []grpc.DialOption
+		code codes.Code
+	}{
+		{nil, codes.Unauthenticated},
+		{[]grpc.DialOption{WithToken("wrong")}, codes.Unauthenticated},
+		{[]grpc.DialOption{WithToken("s3cret")}, codes.OK},
+	} {
+		client, err := Dial(lis.Addr().String(), tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Render(ctx, "order.list", nil); status.Code(err) != tc.code {
+			t.Errorf("expected %s, got %v", tc.code, err)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,95 @@
+package gosqltest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/llyb120/gosql"
+)
+
+// DiffOption DiffQuery 的比较选项
+type DiffOption func(*diffConfig)
+
+// diffConfig 比较配置
+type diffConfig struct {
+	timeTolerance time.Duration
+	floatEpsilon  float64
+}
+
+// TimeTolerance time.Time 参数相差不超过 d 时视为相等
+func TimeTolerance(d time.Duration) DiffOption {
+	return func(c *diffConfig) {
+		c.timeTolerance = d
+	}
+}
+
+// FloatEpsilon 浮点参数相差不超过 eps 时视为相等
+func FloatEpsilon(eps float64) DiffOption {
+	return func(c *diffConfig) {
+		c.floatEpsilon = eps
+	}
+}
+
+// DiffQuery 比较两个查询，相同时返回空串，否则返回逐项的差异说明（每行一条）。
+// SQL 按 gosql.NormalizeSQL 规范化后比较（忽略空白、注释和关键字大小写的差异）；参数要求个数和类型一致，time.Time 和浮点数可以按选项容忍误差：
+//
+//	if diff := gosqltest.DiffQuery(want, got, gosqltest.TimeTolerance(time.Second)); diff != "" {
+//		t.Error(diff)
+//	}
+func DiffQuery(want, got gosql.Query, opts ...DiffOption) string {
+	cfg := &diffConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var diffs []string
+	if w, g := gosql.NormalizeSQL(want.SQL), gosql.NormalizeSQL(got.SQL); w != g {
+		diffs = append(diffs, fmt.Sprintf("SQL:\n  want: %s\n  got:  %s", w, g))
+	}
+	if len(want.Params) != len(got.Params) {
+		diffs = append(diffs, fmt.Sprintf("params: want %d, got %d: want %v, got %v",
+			len(want.Params), len(got.Params), want.Params, got.Params))
+	} else {
+		for i := range want.Params {
+			if d := cfg.diffParam(want.Params[i], got.Params[i]); d != "" {
+				diffs = append(diffs, fmt.Sprintf("param %d: %s", i+1, d))
+			}
+		}
+	}
+	return strings.Join(diffs, "\n")
+}
+
+// diffParam 比较单个参数，相同时返回空串
+func (c *diffConfig) diffParam(want, got interface{}) string {
+	if reflect.TypeOf(want) != reflect.TypeOf(got) {
+		return fmt.Sprintf("want %v (%T), got %v (%T)", want, want, got, got)
+	}
+	switch w := want.(type) {
+	case time.Time:
+		g := got.(time.Time)
+		delta := w.Sub(g)
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta <= c.timeTolerance {
+			return ""
+		}
+		return fmt.Sprintf("want %v, got %v (off by %v)", w, g, delta)
+	case float64:
+		if math.Abs(w-got.(float64)) <= c.floatEpsilon {
+			return ""
+		}
+	case float32:
+		if math.Abs(float64(w)-float64(got.(float32))) <= c.floatEpsilon {
+			return ""
+		}
+	default:
+		if reflect.DeepEqual(want, got) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("want %v, got %v", want, got)
+}
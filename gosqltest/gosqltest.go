@@ -0,0 +1,32 @@
+// Package gosqltest 提供基于 gosql 模板的集成测试辅助函数
+package gosqltest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/llyb120/gosql"
+)
+
+// LoadFixtures 开启一个事务并执行命名空间中的所有 seed 模板（见 Executor.Seed），
+// 返回该事务供测试使用；测试结束时事务自动回滚，使模板驱动的集成测试互不影响。
+// 执行失败时测试立即失败
+func LoadFixtures(t testing.TB, db *sql.DB, engine *gosql.Engine, namespace string) *sql.Tx {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("gosqltest: begin: %v", err)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+
+	executor := gosql.NewExecutor(engine, db)
+	defer executor.Close()
+	if _, err := executor.SeedTx(ctx, tx, namespace); err != nil {
+		t.Fatalf("gosqltest: load fixtures %s: %v", namespace, err)
+	}
+	return tx
+}
@@ -0,0 +1,96 @@
+package gosqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llyb120/gosql"
+)
+
+// recorder 测试用的 database/sql 驱动：记录执行的 SQL 以及事务的提交 / 回滚
+type recorder struct {
+	execs      []string
+	rolledBack bool
+	committed  bool
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return conn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type conn struct{ r *recorder }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.r, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{c.r}, nil }
+
+type tx struct{ r *recorder }
+
+func (t tx) Commit() error   { t.r.committed = true; return nil }
+func (t tx) Rollback() error { t.r.rolledBack = true; return nil }
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.execs = append(s.r.execs, s.query)
+	return driver.RowsAffected(1), nil
+}
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestLoadFixtures(t *testing.T) {
+	engine := gosql.New()
+	err := engine.LoadMarkdown("# fixtures\n\n## users\nseed: true\n```sql\ninsert into users (id) values (@id)\n```\n```csv\nid\n1\n2\n```\n")
+	if err != nil {
+		t.Fatalf("LoadMarkdown error: %v", err)
+	}
+	rec := &recorder{}
+	db := sql.OpenDB(rec)
+	defer db.Close()
+
+	t.Run("fixtures", func(t *testing.T) {
+		if tx := LoadFixtures(t, db, engine, "fixtures"); tx == nil {
+			t.Fatal("expected transaction")
+		}
+		if len(rec.execs) != 2 || rec.rolledBack {
+			t.Errorf("unexpected state %+v", rec)
+		}
+	})
+	if !rec.rolledBack || rec.committed {
+		t.Errorf("fixtures should be rolled back at test end, got %+v", rec)
+	}
+}
+
+func TestDiffQuery(t *testing.T) {
+	now := time.Now()
+	a, b := 0.1, 0.2
+	want := gosql.Query{SQL: "select *\nfrom users\nwhere id = ?  -- by id", Params: []interface{}{1, now, 0.3}}
+	got := gosql.Query{SQL: "select * from users where id = ?", Params: []interface{}{1, now.Add(time.Millisecond), a + b}}
+
+	if diff := DiffQuery(want, got, TimeTolerance(time.Second), FloatEpsilon(1e-9)); diff != "" {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	diff := DiffQuery(want, got)
+	if !strings.Contains(diff, "param 2: ") || !strings.Contains(diff, "param 3: ") || strings.Contains(diff, "SQL") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	got = gosql.Query{SQL: "select * from orders where id = ?", Params: []interface{}{int64(1), now, 0.3}}
+	diff = DiffQuery(want, got)
+	if !strings.Contains(diff, "SQL:") || !strings.Contains(diff, "param 1: want 1 (int), got 1 (int64)") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if diff := DiffQuery(want, gosql.Query{SQL: want.SQL}); !strings.Contains(diff, "params: want 3, got 0") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,110 @@
+package gosqlweb
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// APIOption API 配置选项
+type APIOption func(*apiConfig)
+
+// apiConfig API 配置
+type apiConfig struct {
+	auth []func(r *http.Request) error
+}
+
+// WithAuth 添加鉴权钩子：每个请求处理前依次调用，任意一个返回错误时响应 401，错误信息作为响应内容。
+// 可以多次指定（例如先校验 token，再按模板路径校验权限，模板路径见 TemplatePath）
+func WithAuth(fn func(r *http.Request) error) APIOption {
+	return func(c *apiConfig) {
+		c.auth = append(c.auth, fn)
+	}
+}
+
+// API 返回渲染服务的 http.Handler，供非 Go 服务和 BI 工具使用同一套 SQL 模板：
+//   - GET  /templates        模板列表（JSON）
+//   - POST /render/{path}    渲染模板，请求体为 JSON 参数对象，返回 {"sql": ..., "params": [...]}
+//
+// 渲染失败时返回 {"error": ..., "code": ...}：模板不存在为 404，其它错误为 400
+func API(engine *gosql.Engine, opts ...APIOption) http.Handler {
+	cfg := &apiConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, engine.Templates())
+	})
+	mux.HandleFunc("/render/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid args: " + err.Error()})
+			return
+		}
+		args, err := gosql.DecodeJSONArgs(string(body))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid args: " + err.Error()})
+			return
+		}
+
+		query, err := engine.GetSqlContext(r.Context(), TemplatePath(r), args)
+		if err != nil {
+			status := http.StatusBadRequest
+			if code := gosql.CodeOf(err); code == gosql.CodeTemplateNotFound || code == gosql.CodeDefineNotFound {
+				status = http.StatusNotFound
+			}
+			writeJSON(w, status, apiError{Error: err.Error(), Code: gosql.CodeOf(err)})
+			return
+		}
+		params := query.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		writeJSON(w, http.StatusOK, apiQuery{SQL: query.SQL, Params: params})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, auth := range cfg.auth {
+			if err := auth(r); err != nil {
+				writeJSON(w, http.StatusUnauthorized, apiError{Error: err.Error()})
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// TemplatePath 返回 POST /render/{path} 请求中的模板路径，其它请求返回空串，用于在鉴权钩子中按模板控制权限
+func TemplatePath(r *http.Request) string {
+	_, path, ok := strings.Cut(r.URL.Path, "/render/")
+	if !ok {
+		return ""
+	}
+	return path
+}
+
+// apiQuery 渲染结果
+type apiQuery struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+}
+
+// apiError 错误响应
+type apiError struct {
+	Error string          `json:"error"`
+	Code  gosql.ErrorCode `json:"code,omitempty"`
+}
@@ -0,0 +1,179 @@
+// Package gosqlweb 提供 gosql 模板的 Web 调试页面：选择模板、填写 JSON 参数，
+// 查看渲染出的 SQL、参数和渲染过程，方便不写 Go 的分析人员和评审人员使用
+package gosqlweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/llyb120/gosql"
+)
+
+// Handler 返回调试页面的 http.Handler，可以挂载在任意前缀下：
+//
+//	http.Handle("/gosql/", http.StripPrefix("/gosql", gosqlweb.Handler(engine)))
+//
+// 路由：
+//   - GET  /           调试页面
+//   - GET  /templates  模板列表（JSON）
+//   - POST /render     渲染模板，请求体为 {"path": "user.byId", "args": {...}}
+//
+// 渲染使用 Engine.Trace，不计入模板的渲染次数。页面本身不做鉴权，不要直接暴露在公网
+func Handler(engine *gosql.Engine) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.Templates())
+	})
+	mux.HandleFunc("/render", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req RenderRequest
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, RenderResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, Render(engine, req))
+	})
+	return mux
+}
+
+// RenderRequest 渲染请求
+type RenderRequest struct {
+	Path string                 `json:"path"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// RenderResponse 渲染结果，渲染失败时 Error 不为空（Trace 仍包含失败前的步骤）
+type RenderResponse struct {
+	SQL    string             `json:"sql"`
+	Pretty string             `json:"pretty"` // 格式化后的 SQL
+	Params []interface{}      `json:"params"`
+	Trace  []gosql.TraceEvent `json:"trace"`
+	Error  string             `json:"error,omitempty"`
+	Code   gosql.ErrorCode    `json:"code,omitempty"`
+}
+
+// Render 按请求渲染模板。JSON 参数中的数字按整数（int64）或浮点数（float64）传给模板
+func Render(engine *gosql.Engine, req RenderRequest) RenderResponse {
+	args, _ := gosql.JSONValue(req.Args).(map[string]interface{})
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	query, trace, err := engine.Trace(req.Path, args)
+	resp := RenderResponse{Trace: trace, Params: []interface{}{}}
+	if err != nil {
+		resp.Error = err.Error()
+		resp.Code = gosql.CodeOf(err)
+		return resp
+	}
+	resp.SQL = query.SQL
+	resp.Pretty = query.Pretty(gosql.DialectDefault).SQL
+	if query.Params != nil {
+		resp.Params = query.Params
+	}
+	return resp
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// page 调试页面（使用相对路径请求接口，挂载在任意前缀下都可以工作）
+var page = strings.TrimSpace(`
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gosql playground</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+#side { width: 280px; border-right: 1px solid #ddd; overflow: auto; padding: 8px; }
+#side input { width: 100%; box-sizing: border-box; margin-bottom: 8px; }
+#side div { padding: 4px; cursor: pointer; font-family: monospace; }
+#side div.active, #side div:hover { background: #eef; }
+#main { flex: 1; padding: 12px; overflow: auto; }
+textarea { width: 100%; height: 120px; font-family: monospace; }
+pre { background: #f6f6f6; padding: 8px; white-space: pre-wrap; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<div id="side"><input id="filter" placeholder="filter"><div id="list"></div></div>
+<div id="main">
+<h3 id="title">select a template</h3>
+<p id="desc"></p>
+<pre id="source"></pre>
+<textarea id="args">{}</textarea>
+<p><button id="render">render</button></p>
+<pre id="sql"></pre>
+<h4>params</h4><pre id="params"></pre>
+<h4>trace</h4><pre id="trace"></pre>
+</div>
+<script>
+var templates = [], current = null;
+function $(id) { return document.getElementById(id); }
+function showList() {
+  var q = $("filter").value.toLowerCase(), list = $("list");
+  list.innerHTML = "";
+  templates.forEach(function (t) {
+    if (q && t.path.toLowerCase().indexOf(q) < 0) return;
+    var div = document.createElement("div");
+    div.textContent = t.path;
+    if (current && current.path === t.path) div.className = "active";
+    div.onclick = function () { select(t); };
+    list.appendChild(div);
+  });
+}
+function select(t) {
+  current = t;
+  $("title").textContent = t.path;
+  $("desc").textContent = t.description || "";
+  $("source").textContent = t.sql;
+  var args = {};
+  (t.params || []).forEach(function (p) { args[p.name] = null; });
+  $("args").value = JSON.stringify(args, null, 2);
+  showList();
+}
+$("filter").oninput = showList;
+$("render").onclick = function () {
+  if (!current) return;
+  var args;
+  try { args = JSON.parse($("args").value || "{}"); } catch (e) { $("sql").textContent = e; $("sql").className = "error"; return; }
+  fetch("render", { method: "POST", body: JSON.stringify({ path: current.path, args: args }) })
+    .then(function (r) { return r.json(); })
+    .then(function (res) {
+      $("sql").className = res.error ? "error" : "";
+      $("sql").textContent = res.error ? (res.code ? res.code + ": " : "") + res.error : res.pretty;
+      $("params").textContent = JSON.stringify(res.params, null, 2);
+      $("trace").textContent = (res.trace || []).map(function (e) {
+        return "  ".repeat(e.depth) + e.kind + ": " + e.detail;
+      }).join("\n");
+    });
+};
+fetch("templates").then(function (r) { return r.json(); }).then(function (list) {
+  templates = list || [];
+  showList();
+});
+</script>
+</body>
+</html>
+`)
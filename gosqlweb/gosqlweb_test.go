@@ -0,0 +1,129 @@
+package gosqlweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/llyb120/gosql"
+)
+
+func TestHandler(t *testing.T) {
+	engine := gosql.New()
+	err := engine.LoadMarkdown("# user\n\n## search\n```sql\nselect * from users\nwhere 1 = 1\n@if id > 0 {\n  and id = @id\n}\n  and name = @name?\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.StripPrefix("/gosql", Handler(engine)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/gosql/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("unexpected page response %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Get(server.URL + "/gosql/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infos []gosql.TemplateInfo
+	json.NewDecoder(resp.Body).Decode(&infos)
+	resp.Body.Close()
+	if len(infos) != 1 || infos[0].Path != "user.search" {
+		t.Errorf("unexpected templates %+v", infos)
+	}
+
+	render := func(body string) RenderResponse {
+		resp, err := http.Post(server.URL+"/gosql/render", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res RenderResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := render(`{"path": "user.search", "args": {"id": 42, "name": ""}}`)
+	if res.Error != "" || !strings.Contains(res.SQL, "and id = ?") || strings.Contains(res.SQL, "name") {
+		t.Fatalf("unexpected render result %+v", res)
+	}
+	if len(res.Params) != 1 || res.Params[0] != float64(42) {
+		t.Errorf("unexpected params %v", res.Params)
+	}
+	var kinds []string
+	for _, ev := range res.Trace {
+		kinds = append(kinds, ev.Kind)
+	}
+	if strings.Join(kinds, ",") != "if,param,skip" {
+		t.Errorf("unexpected trace %+v", res.Trace)
+	}
+
+	res = render(`{"path": "user.serch", "args": {}}`)
+	if res.Code != gosql.CodeTemplateNotFound || !strings.Contains(res.Error, "did you mean") {
+		t.Errorf("unexpected error response %+v", res)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	engine := gosql.New()
+	if err := engine.LoadMarkdown("# user\n\n## byId\n```sql\nselect * from users where id = @id\n```\n"); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	handler := API(engine, WithAuth(func(r *http.Request) error {
+		if r.Header.Get("X-Key") != "secret" {
+			return errors.New("forbidden")
+		}
+		paths = append(paths, TemplatePath(r))
+		return nil
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	do := func(method, path, body string, key string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("X-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res
+	}
+
+	if status, _ := do("POST", "/render/user.byId", `{"id": 1}`, "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", status)
+	}
+	status, res := do("POST", "/render/user.byId", `{"id": 1}`, "secret")
+	if status != http.StatusOK || res["sql"] != "select * from users where id = ?" || fmt.Sprint(res["params"]) != "[1]" {
+		t.Errorf("unexpected response %d %v", status, res)
+	}
+	if status, res := do("POST", "/render/user.missing", `{}`, "secret"); status != http.StatusNotFound || res["code"] != string(gosql.CodeTemplateNotFound) {
+		t.Errorf("unexpected response %d %v", status, res)
+	}
+	if status, res := do("POST", "/render/user.byId", `{}`, "secret"); status != http.StatusBadRequest || res["code"] != string(gosql.CodeVariableNotFound) {
+		t.Errorf("unexpected response %d %v", status, res)
+	}
+	if status, _ := do("GET", "/render/user.byId", ``, "secret"); status != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", status)
+	}
+	if status, _ := do("GET", "/templates", ``, "secret"); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+	if strings.Join(paths, ",") != "user.byId,user.missing,user.byId,user.byId," {
+		t.Errorf("unexpected template paths %q", paths)
+	}
+}
@@ -0,0 +1,177 @@
+package gosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// groupedParentField 动态行结构中父结构体字段的名称
+const groupedParentField = "GosqlParent"
+
+// SelectGrouped 执行联表查询，并把父子关系的多行结果合并为带子切片的父结构体（一对多）：
+//
+//	type Order struct {
+//		ID    int64  `gosql:"key"`
+//		No    string
+//		Items []Item `db:"item"`
+//	}
+//	var orders []Order
+//	x.SelectGrouped(&orders, "order.withItems", args)
+//
+// 父结构体中元素为结构体的切片字段都是子集合，子表的列可以用 db 标签或字段名作为前缀（item.id、items_id），
+// 也可以和父表列同名（select o.*, i.*，同名列按顺序先分配给父结构体、再分配给子结构体）。
+// groupBy 为父结构体中用于分组的字段名；不传时使用带 `gosql:"key"` 标签的字段，都没有时使用 ID / Id 字段。
+// 子结构体所有字段都为零值时（LEFT JOIN 没有匹配的子记录，子字段建议使用指针或 sql.Null* 类型）不会追加
+func (x *Executor) SelectGrouped(dest interface{}, path string, args interface{}, groupBy ...string) error {
+	return x.SelectGroupedContext(context.Background(), dest, path, args, groupBy...)
+}
+
+// SelectGroupedContext 同 SelectGrouped，可以传入 context
+func (x *Executor) SelectGroupedContext(ctx context.Context, dest interface{}, path string, args interface{}, groupBy ...string) error {
+	rows, err := x.query(ctx, path, args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return x.scanner.ScanGrouped(rows, dest, groupBy...)
+}
+
+// ScanGrouped 把联表查询的所有行合并扫描到 dest（父结构体切片的指针），规则见 Executor.SelectGrouped
+func (s *Scanner) ScanGrouped(rows *sql.Rows, dest interface{}, groupBy ...string) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	parentType := elemType
+	if parentType.Kind() == reflect.Ptr {
+		parentType = parentType.Elem()
+	}
+	if parentType.Kind() != reflect.Struct {
+		return fmt.Errorf("scan: grouped dest element must be a struct, got %s", elemType)
+	}
+
+	keys, err := groupKeyFields(parentType, groupBy)
+	if err != nil {
+		return err
+	}
+	children := childSliceFields(parentType)
+	if len(children) == 0 {
+		return fmt.Errorf("scan: %s has no child slice fields", parentType)
+	}
+
+	// 每一行扫描到一个动态结构体：{ GosqlParent Parent; Items Item; ... }
+	fields := []reflect.StructField{{Name: groupedParentField, Type: parentType}}
+	for _, c := range children {
+		fields = append(fields, reflect.StructField{
+			Name: c.Name,
+			Type: c.Type.Elem(),
+			Tag:  c.Tag,
+		})
+	}
+	rowType := reflect.StructOf(fields)
+
+	slice.Set(slice.Slice(0, 0))
+	index := make(map[string]int) // 分组 key -> 父结构体在 slice 中的位置
+	for rows.Next() {
+		row := reflect.New(rowType).Elem()
+		if err := s.scanRow(rows, row); err != nil {
+			return err
+		}
+		parent := row.Field(0)
+
+		var sb strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "%#v\x00", parent.FieldByIndex(k).Interface())
+		}
+		key := sb.String()
+		pos, ok := index[key]
+		if !ok {
+			pos = slice.Len()
+			index[key] = pos
+			elem := parent
+			if elemType.Kind() == reflect.Ptr {
+				elem = reflect.New(parentType)
+				elem.Elem().Set(parent)
+			}
+			slice.Set(reflect.Append(slice, elem))
+		}
+
+		target := slice.Index(pos)
+		if target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+		for i, c := range children {
+			child := row.Field(i + 1)
+			if child.IsZero() {
+				continue
+			}
+			list := target.FieldByIndex(c.Index)
+			list.Set(reflect.Append(list, child))
+		}
+	}
+	return rows.Err()
+}
+
+// groupKeyFields 返回分组用的字段路径
+func groupKeyFields(t reflect.Type, groupBy []string) ([][]int, error) {
+	var keys [][]int
+	if len(groupBy) > 0 {
+		for _, name := range groupBy {
+			f, ok := t.FieldByName(name)
+			if !ok {
+				return nil, fmt.Errorf("scan: group by field %s not found in %s", name, t)
+			}
+			keys = append(keys, f.Index)
+		}
+		return keys, nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if tagOptions(t.Field(i).Tag.Get("gosql"))["key"] {
+			keys = append(keys, t.Field(i).Index)
+		}
+	}
+	if len(keys) > 0 {
+		return keys, nil
+	}
+	for _, name := range []string{"ID", "Id"} {
+		if f, ok := t.FieldByName(name); ok {
+			return [][]int{f.Index}, nil
+		}
+	}
+	return nil, fmt.Errorf("scan: %s has no group key, tag a field with `gosql:\"key\"` or pass groupBy", t)
+}
+
+// childSliceFields 返回父结构体中元素为结构体（或结构体指针）的切片字段
+func childSliceFields(t reflect.Type) []reflect.StructField {
+	var children []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type.Kind() != reflect.Slice || f.Tag.Get("db") == "-" {
+			continue
+		}
+		elem := f.Type.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct && !isScannerType(reflect.New(elem).Elem()) {
+			children = append(children, f)
+		}
+	}
+	return children
+}
+
+// tagOptions 解析逗号分隔的标签选项
+func tagOptions(tag string) map[string]bool {
+	opts := make(map[string]bool)
+	for _, opt := range strings.Split(tag, ",") {
+		if opt = strings.TrimSpace(opt); opt != "" {
+			opts[opt] = true
+		}
+	}
+	return opts
+}
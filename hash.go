@@ -0,0 +1,46 @@
+package gosql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// queryHashPrefix 查询指纹注释前缀
+const queryHashPrefix = "/* qh:"
+
+// Hash 返回查询形态的指纹：只与 SQL 文本有关（与参数值无关），
+// 空白差异会被忽略，已追加的指纹注释也不参与计算，因此在不同进程间保持稳定
+func (q Query) Hash() string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(stripHashComment(q.SQL)), " ")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// WithHashComment 返回在 SQL 末尾追加了指纹注释（/* qh:xxxx */）的查询
+// SQL 以 ; 结尾时注释放在 ; 之前；已有指纹注释时原样返回
+func (q Query) WithHashComment() Query {
+	if strings.Contains(q.SQL, queryHashPrefix) {
+		return q
+	}
+	comment := queryHashPrefix + q.Hash() + " */"
+	sql := strings.TrimRight(q.SQL, " \t\n")
+	if strings.HasSuffix(sql, ";") {
+		q.SQL = strings.TrimRight(sql[:len(sql)-1], " \t\n") + " " + comment + ";"
+	} else {
+		q.SQL = sql + " " + comment
+	}
+	return q
+}
+
+// stripHashComment 去掉 SQL 中的指纹注释
+func stripHashComment(sql string) string {
+	start := strings.Index(sql, queryHashPrefix)
+	if start < 0 {
+		return sql
+	}
+	end := strings.Index(sql[start:], "*/")
+	if end < 0 {
+		return sql
+	}
+	return strings.TrimRight(sql[:start], " \t\n") + sql[start+end+2:]
+}
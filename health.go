@@ -0,0 +1,90 @@
+package gosql
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Health 健康检查的结果
+type Health struct {
+	Templates  int           `json:"templates"`   // 已加载的模板数
+	LoadedAt   time.Time     `json:"loaded_at"`   // 最近一次成功加载的时间
+	ParseCache int           `json:"parse_cache"` // 解析缓存中的条目数
+	Problems   []string      `json:"problems"`    // 发现的问题（缓存与模板内容不一致、数据库不可用等）
+	DBChecked  bool          `json:"db_checked"`  // 是否检查了数据库连接
+	DBLatency  time.Duration `json:"db_latency"`  // 数据库 ping 的耗时
+}
+
+// OK 没有发现问题时返回 true
+func (h Health) OK() bool {
+	return len(h.Problems) == 0
+}
+
+// HealthCheck 重新校验已编译的模板与模板内容是否一致（编译缓存没有过期、渲染计数器齐全），
+// 并返回模板数和最近一次加载时间，用于 readiness 探针。发现问题时返回的 error 不为 nil。
+// 需要同时检查数据库连接时使用 (*Executor).HealthCheck
+func (e *Engine) HealthCheck(ctx context.Context) (Health, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	h := Health{
+		Templates: len(e.compiledAST),
+		LoadedAt:  e.loadedAt,
+		Problems:  []string{},
+	}
+	e.parseCache.mu.Lock()
+	h.ParseCache = len(e.parseCache.entries)
+	e.parseCache.mu.Unlock()
+
+	for key, tmpl := range e.store.templates {
+		if err := ctx.Err(); err != nil {
+			return h, err
+		}
+		ast, ok := e.compiledAST[key]
+		if !ok {
+			h.Problems = append(h.Problems, fmt.Sprintf("template %s: not compiled", key))
+			continue
+		}
+		if ast.contentHash != sha256.Sum256([]byte(tmpl.Content)) {
+			h.Problems = append(h.Problems, fmt.Sprintf("template %s: compiled AST is stale", key))
+		}
+	}
+	for key := range e.compiledAST {
+		if _, ok := e.store.templates[key]; !ok {
+			h.Problems = append(h.Problems, fmt.Sprintf("template %s: compiled but not in store", key))
+		}
+		if _, ok := e.usage[key]; !ok {
+			h.Problems = append(h.Problems, fmt.Sprintf("template %s: missing usage counter", key))
+		}
+	}
+	sort.Strings(h.Problems)
+	return h, h.err()
+}
+
+// HealthCheck 在 Engine.HealthCheck 的基础上 ping 数据库
+func (x *Executor) HealthCheck(ctx context.Context) (Health, error) {
+	h, err := x.engine.HealthCheck(ctx)
+	if ctx.Err() != nil {
+		return h, err
+	}
+	start := time.Now()
+	pingErr := x.db.PingContext(ctx)
+	h.DBChecked = true
+	h.DBLatency = time.Since(start)
+	if pingErr != nil {
+		h.Problems = append(h.Problems, "database: "+pingErr.Error())
+	}
+	return h, h.err()
+}
+
+// err 把发现的问题合并为一个错误
+func (h Health) err() error {
+	if len(h.Problems) == 0 {
+		return nil
+	}
+	return errors.New("gosql: unhealthy: " + strings.Join(h.Problems, "; "))
+}
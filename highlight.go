@@ -0,0 +1,114 @@
+package gosql
+
+// HighlightKind 语法高亮的 token 分类
+type HighlightKind string
+
+const (
+	HighlightKeyword   HighlightKind = "keyword"   // SQL 关键字
+	HighlightVariable  HighlightKind = "variable"  // @var、@=var、@ expr @ 等输出
+	HighlightDirective HighlightKind = "directive" // @if / @for / @use / @include / @define / @cover / @import / @{} 以及块的 { }
+	HighlightString    HighlightKind = "string"    // SQL 字符串
+	HighlightNumber    HighlightKind = "number"    // SQL 数字
+	HighlightComment   HighlightKind = "comment"   // SQL 注释
+	HighlightText      HighlightKind = "text"      // 其它 SQL 文本（标识符、符号等）
+)
+
+// HighlightToken 带位置的高亮 token。空白不单独输出
+type HighlightToken struct {
+	Kind   HighlightKind `json:"kind"`
+	Text   string        `json:"text"`
+	Line   int           `json:"line"`   // 行号（从 1 开始）
+	Column int           `json:"column"` // 列号（从 1 开始，按字符计，制表符算一个字符）
+	Offset int           `json:"offset"` // 在源码中的起始位置（按字符计）
+	Length int           `json:"length"` // 长度（按字符计）
+}
+
+// Highlight 对模板源码进行分类，供编辑器插件、管理后台高亮模板 DSL，与渲染使用同一个词法分析器。
+// 源码中的 \r\n 会先统一为 \n，位置按统一后的源码计算
+func Highlight(source string) ([]HighlightToken, error) {
+	source = normalizeSource(source)
+	tokens, err := NewLexer(source).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	src := []rune(source)
+
+	// 每个字符所在的行列
+	lines := make([]int, len(src)+1)
+	cols := make([]int, len(src)+1)
+	line, col := 1, 1
+	for i, ch := range src {
+		lines[i], cols[i] = line, col
+		if ch == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	lines[len(src)], cols[len(src)] = line, col
+
+	var result []HighlightToken
+	add := func(kind HighlightKind, offset int, text []rune) {
+		result = append(result, HighlightToken{
+			Kind:   kind,
+			Text:   string(text),
+			Line:   lines[offset],
+			Column: cols[offset],
+			Offset: offset,
+			Length: len(text),
+		})
+	}
+	for i, t := range tokens {
+		// 同一次扫描产生的多个 token（如 } else {）共用一段源码，只输出一次
+		if t.Type == TOKEN_EOF || t.End <= t.Offset || i > 0 && tokens[i-1].Offset == t.Offset {
+			continue
+		}
+		switch t.Type {
+		case TOKEN_TEXT:
+			offset := t.Offset
+			for _, st := range scanSQL(string(src[t.Offset:t.End])) {
+				text := []rune(st.text)
+				if kind, ok := sqlHighlightKind(st); ok {
+					add(kind, offset, text)
+				}
+				offset += len(text)
+			}
+		case TOKEN_VAR, TOKEN_VAR_COND, TOKEN_VAR_EXPR, TOKEN_VAR_EXPR_COND,
+			TOKEN_RAW, TOKEN_RAW_COND, TOKEN_RAW_EXPR, TOKEN_RAW_EXPR_COND:
+			add(HighlightVariable, t.Offset, src[t.Offset:t.End])
+		default:
+			add(HighlightDirective, t.Offset, src[t.Offset:t.End])
+		}
+	}
+	return result, nil
+}
+
+// sqlHighlightKind SQL 文本中词法单元的分类，空白返回 false
+func sqlHighlightKind(t sqlToken) (HighlightKind, bool) {
+	switch t.kind {
+	case sqlSpace:
+		return "", false
+	case sqlWord:
+		if isSQLKeyword(t.text) {
+			return HighlightKeyword, true
+		}
+	case sqlString:
+		return HighlightString, true
+	case sqlNumber:
+		return HighlightNumber, true
+	case sqlLineComment, sqlBlockComment:
+		return HighlightComment, true
+	}
+	return HighlightText, true
+}
+
+// Highlight 对已加载的模板进行分类（模板的多个 SQL 代码块按加载时的方式拼接）
+func (e *Engine) Highlight(path string) ([]HighlightToken, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	tmpl, ok := e.store.Get(path)
+	if !ok {
+		return nil, e.templateNotFound(path)
+	}
+	return Highlight(tmpl.Content)
+}
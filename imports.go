@@ -0,0 +1,193 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// resolveImports 把所有模板中的 @import 展开为被引用模板（或 define）的节点。
+// 每次加载之后调用：被引用的模板变化时，引用它的模板也会重新展开
+func (e *Engine) resolveImports() error {
+	keys := make([]string, 0, len(e.compiledAST))
+	for key, ast := range e.compiledAST {
+		if ast.source != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ast := e.compiledAST[key]
+		nodes, err := e.inlineImports(ast.source, []string{key})
+		if err != nil {
+			return fmt.Errorf("template %s: %w", key, err)
+		}
+		// 复制一份再替换节点：ast 可能是当前生效的模板，加载失败时不能被修改
+		resolved := *ast
+		resolved.Nodes = nodes
+		e.compiledAST[key] = &resolved
+	}
+	return nil
+}
+
+// importNodes 返回 @import path 展开后的节点，stack 为正在展开的模板（用于检测循环引用）
+func (e *Engine) importNodes(path string, stack []string) ([]Node, error) {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return nil, codeError(CodeInvalidPath, "invalid import path: %s, expected format: namespace.name", path)
+	}
+	key := parts[0] + "." + parts[1]
+	for _, k := range stack {
+		if k == key {
+			return nil, codeError(CodeCycle, "import cycle: %s -> %s", strings.Join(stack, " -> "), key)
+		}
+	}
+	ast, ok := e.compiledAST[key]
+	if !ok {
+		return nil, fmt.Errorf("import %s: %w", path, e.templateNotFound(key))
+	}
+	nodes := ast.Nodes
+	if ast.source != nil {
+		nodes = ast.source
+	}
+	if len(parts) > 2 {
+		define := findDefine(nodes, parts[2])
+		if define == nil {
+			return nil, fmt.Errorf("import %s: %w", path, defineNotFound(parts[2], key, nodes))
+		}
+		nodes = define.Body
+	}
+	return e.inlineImports(nodes, append(stack, key))
+}
+
+// inlineImports 返回把 @import 替换为被引用节点后的节点列表。
+// 节点可能与解析缓存共享，因此复制容器节点而不是原地修改
+func (e *Engine) inlineImports(nodes []Node, stack []string) ([]Node, error) {
+	var out []Node
+	for i, node := range nodes {
+		replaced, err := e.inlineNode(node, stack)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			if len(replaced) == 1 && replaced[0] == node {
+				continue
+			}
+			out = append(make([]Node, 0, len(nodes)), nodes[:i]...)
+		}
+		out = append(out, replaced...)
+	}
+	if out == nil {
+		return nodes, nil
+	}
+	return out, nil
+}
+
+// inlineNode 展开单个节点，返回替换它的节点
+func (e *Engine) inlineNode(node Node, stack []string) ([]Node, error) {
+	var err error
+	switch n := node.(type) {
+	case *ImportNode:
+		return e.importNodes(n.Path, stack)
+	case *IfNode:
+		c := *n
+		if c.Body, err = e.inlineImports(n.Body, stack); err != nil {
+			return nil, err
+		}
+		c.ElseIf = make([]*ElseIfNode, len(n.ElseIf))
+		for i, ei := range n.ElseIf {
+			cei := *ei
+			if cei.Body, err = e.inlineImports(ei.Body, stack); err != nil {
+				return nil, err
+			}
+			c.ElseIf[i] = &cei
+		}
+		if n.Else != nil {
+			ce := *n.Else
+			if ce.Body, err = e.inlineImports(n.Else.Body, stack); err != nil {
+				return nil, err
+			}
+			c.Else = &ce
+		}
+		return []Node{&c}, nil
+	case *ForNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *DefineNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *CoverNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *ConditionalLineNode:
+		c := *n
+		c.LineNodes, err = e.inlineImports(n.LineNodes, stack)
+		return []Node{&c}, err
+	case *FuncBlockNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *ForEachNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *SwitchNode:
+		c := *n
+		c.Cases = make([]*CaseNode, len(n.Cases))
+		for i, cs := range n.Cases {
+			cc := *cs
+			if cc.Body, err = e.inlineImports(cs.Body, stack); err != nil {
+				return nil, err
+			}
+			c.Cases[i] = &cc
+		}
+		if n.Default != nil {
+			cd := *n.Default
+			if cd.Body, err = e.inlineImports(n.Default.Body, stack); err != nil {
+				return nil, err
+			}
+			c.Default = &cd
+		}
+		return []Node{&c}, nil
+	case *SetNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *FlagNode:
+		c := *n
+		if c.Body, err = e.inlineImports(n.Body, stack); err != nil {
+			return nil, err
+		}
+		if n.Else != nil {
+			ce := *n.Else
+			if ce.Body, err = e.inlineImports(n.Else.Body, stack); err != nil {
+				return nil, err
+			}
+			c.Else = &ce
+		}
+		return []Node{&c}, nil
+	case *TrimNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *DirectiveNode:
+		c := *n
+		c.Body, err = e.inlineImports(n.Body, stack)
+		return []Node{&c}, err
+	case *UseNode:
+		c := *n
+		c.Covers = make([]*CoverNode, len(n.Covers))
+		for i, cover := range n.Covers {
+			cc := *cover
+			if cc.Body, err = e.inlineImports(cover.Body, stack); err != nil {
+				return nil, err
+			}
+			c.Covers[i] = &cc
+		}
+		return []Node{&c}, nil
+	}
+	return []Node{node}, nil
+}
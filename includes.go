@@ -0,0 +1,81 @@
+package gosql
+
+import (
+	"bufio"
+	"strings"
+)
+
+// markdownIncludes 返回 markdown 文件声明依赖的其它文件（按声明顺序），支持两种写法：
+//
+//	<!-- include: ./common.md -->
+//
+// 以及文件开头的 front matter：
+//
+//	---
+//	includes: ./common.md, ./tenant.md
+//	---
+//
+// front matter 中的 includes 也可以写成列表（每行 "- ./common.md"）。代码块中的内容不会被识别
+func markdownIncludes(content string) []string {
+	var includes []string
+	scanner := bufio.NewScanner(strings.NewReader(normalizeSource(content)))
+	lineNum := 0
+	inFrontMatter := false
+	inList := false
+	fence := ""
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if lineNum == 1 && line == "---" {
+			inFrontMatter = true
+			continue
+		}
+		if inFrontMatter {
+			if line == "---" {
+				inFrontMatter = false
+				continue
+			}
+			if inList && strings.HasPrefix(line, "- ") {
+				includes = append(includes, strings.TrimSpace(strings.TrimPrefix(line, "- ")))
+				continue
+			}
+			inList = false
+			if rest, ok := cutPrefixFold(line, "includes:"); ok {
+				includes = append(includes, splitList([]string{rest})...)
+				inList = strings.TrimSpace(rest) == ""
+			}
+			continue
+		}
+
+		if fence != "" {
+			if strings.HasPrefix(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fence = line[:3]
+			continue
+		}
+
+		if !strings.HasPrefix(line, "<!--") || !strings.HasSuffix(line, "-->") {
+			continue
+		}
+		body := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "<!--"), "-->"))
+		if rest, ok := cutPrefixFold(body, "include:"); ok {
+			if path := strings.TrimSpace(rest); path != "" {
+				includes = append(includes, path)
+			}
+		}
+	}
+	return includes
+}
+
+// cutPrefixFold 不区分大小写地去掉前缀
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
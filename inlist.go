@@ -0,0 +1,64 @@
+package gosql
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// inListPattern 切片之前的 col in ( / col not in (，分组依次为列、not、in
+var inListPattern = regexp.MustCompile("(?i)([A-Za-z0-9_.`\"\\[\\]]+)\\s+(not\\s+)?(in)\\s*\\(\\s*$")
+
+// closeParenPattern 切片之后的 )
+var closeParenPattern = regexp.MustCompile(`^\s*\)`)
+
+// inListRewrite 等待替换的 col in (...)：紧接着的文本以 ) 开头时，ctx.sql[start:end] 连同 ) 替换为 text
+type inListRewrite struct {
+	start int    // col 在 ctx.sql 中的位置
+	end   int    // 切片之后的位置
+	text  string // 替换后的 SQL
+}
+
+// DialectInListLimit 返回方言中单个 in (...) 列表允许的元素个数上限（用于 WithInListLimit），
+// Oracle 为 1000（ORA-01795），其他方言没有限制，返回 0
+func DialectInListLimit(dialect Dialect) int {
+	if dialect == DialectOracle {
+		return 1000
+	}
+	return 0
+}
+
+// bindChunkedIn 绑定元素个数超过 limit 的切片：先照常输出 col in (?, ?, ...，紧接着的 ) 输出时
+// 再替换为 (col in (...) or col in (...))（not in 用 and 连接），参数顺序不变
+func (ctx *executionContext) bindChunkedIn(value interface{}, loc []int, limit int) {
+	prefix := strings.TrimRight(string(ctx.sql.buf[loc[0]:loc[1]]), " \t\r\n")
+	sep := " or "
+	if loc[4] >= 0 {
+		sep = " and "
+	}
+	if ctx.sql.buf[loc[6]] == 'I' {
+		sep = strings.ToUpper(sep)
+	}
+
+	var text strings.Builder
+	text.WriteByte('(')
+	rv := reflect.ValueOf(value)
+	for i, n := 0, rv.Len(); i < n; i++ {
+		if i > 0 {
+			ctx.sql.WriteString(", ")
+		}
+		switch {
+		case i == 0:
+			text.WriteString(prefix)
+		case i%limit == 0:
+			text.WriteString(")" + sep + prefix)
+		default:
+			text.WriteString(", ")
+		}
+		p := ctx.sql.Len()
+		ctx.bindArg(ctx.engine.paramValue(rv.Index(i).Interface()))
+		text.Write(ctx.sql.buf[p:])
+	}
+	text.WriteString("))")
+	ctx.inList = &inListRewrite{start: loc[0], end: ctx.sql.Len(), text: text.String()}
+}
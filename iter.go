@@ -0,0 +1,47 @@
+//go:build go1.23
+
+package gosql
+
+import (
+	"iter"
+	"sort"
+)
+
+// All 按路径顺序遍历所有已加载的模板及其元信息，元信息在遍历到时才构建，
+// 适合模板很多时逐个处理而不是先生成 Templates() 的完整切片。
+// 遍历开始时记录模板路径，之后卸载的模板会被跳过
+func (e *Engine) All() iter.Seq2[string, *TemplateInfo] {
+	return func(yield func(string, *TemplateInfo) bool) {
+		e.mu.RLock()
+		keys := make([]string, 0, len(e.compiledAST))
+		for key := range e.compiledAST {
+			keys = append(keys, key)
+		}
+		e.mu.RUnlock()
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			// 不在持有读锁时调用 yield，循环体中可以重新加载模板
+			e.mu.RLock()
+			var info *TemplateInfo
+			if ast, ok := e.compiledAST[key]; ok {
+				info = e.templateInfo(key, ast)
+			}
+			e.mu.RUnlock()
+			if info != nil && !yield(key, info) {
+				return
+			}
+		}
+	}
+}
+
+// ParamsSeq 按顺序遍历参数（序号从 0 开始）
+func (q Query) ParamsSeq() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i, p := range q.Params {
+			if !yield(i, p) {
+				return
+			}
+		}
+	}
+}
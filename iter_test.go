@@ -0,0 +1,41 @@
+//go:build go1.23
+
+package gosql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	engine := New()
+	markdown := "# user\n\n## find\n```sql\nselect * from user where id = @id\n```\n\n## list\n```sql\nselect * from user\n```\n\n# order\n\n## find\n```sql\nselect * from orders\n```\n"
+	if err := engine.LoadMarkdown(markdown); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for path, info := range engine.All() {
+		if info.Path != path {
+			t.Errorf("unexpected info for %s: %s", path, info.Path)
+		}
+		paths = append(paths, path)
+	}
+	if fmt.Sprint(paths) != "[order.find user.find user.list]" {
+		t.Errorf("unexpected paths: %v", paths)
+	}
+	for path := range engine.All() {
+		if path != "order.find" {
+			t.Errorf("unexpected first path: %s", path)
+		}
+		break
+	}
+
+	q := Query{SQL: "select ?, ?", Params: []interface{}{1, "a"}}
+	var params []string
+	for i, p := range q.ParamsSeq() {
+		params = append(params, fmt.Sprint(i, "=", p))
+	}
+	if fmt.Sprint(params) != "[0=1 1=a]" {
+		t.Errorf("unexpected params: %v", params)
+	}
+}
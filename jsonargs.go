@@ -0,0 +1,44 @@
+package gosql
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DecodeJSONArgs 解析 JSON 编码的参数对象（供 HTTP / RPC 等以 JSON 传参的入口使用），s 为空时返回空参数，
+// 数字按 JSONValue 的规则转换
+func DecodeJSONArgs(s string) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(s) == "" {
+		return args, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil {
+		return nil, err
+	}
+	JSONValue(args)
+	return args, nil
+}
+
+// JSONValue 把以 json.Decoder.UseNumber 解码的值中的 json.Number 转为 int64（整数）或 float64（小数），
+// map 和切片原地转换
+func JSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = JSONValue(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = JSONValue(value)
+		}
+	}
+	return v
+}
@@ -31,6 +31,15 @@ const (
 	TOKEN_DEFINE                  // @define 或 @define("name")
 	TOKEN_COVER                   // @cover 或 @cover("name")
 	TOKEN_FUNC_BLOCK              // @ func() {} 自定义函数块
+	TOKEN_IMPORT                  // @import path
+	TOKEN_SET                     // @set {
+	TOKEN_FOREACH                 // @foreach item in list sep "," open "(" close ")"
+	TOKEN_SWITCH                  // @switch expr
+	TOKEN_CASE                    // @case v1, v2
+	TOKEN_DEFAULT                 // @default
+	TOKEN_INCLUDE                 // @include path 或 @include path(args)
+	TOKEN_DIRECTIVE               // RegisterDirective 注册的指令 @name 或 @name(args)
+	TOKEN_FLAG                    // @flag "name"
 )
 
 // Token 表示一个词法单元
@@ -40,6 +49,8 @@ type Token struct {
 	Line    int    // 行号
 	Column  int    // 列号
 	Context string // 上下文片段（用于错误提示）
+	Offset  int    // token 在源码中的起始位置（按字符计）
+	End     int    // token 在源码中的结束位置（不含）
 }
 
 func (t TokenType) String() string {
@@ -86,42 +97,96 @@ func (t TokenType) String() string {
 		return "COVER"
 	case TOKEN_FUNC_BLOCK:
 		return "FUNC_BLOCK"
+	case TOKEN_IMPORT:
+		return "IMPORT"
+	case TOKEN_SET:
+		return "SET"
+	case TOKEN_FOREACH:
+		return "FOREACH"
+	case TOKEN_SWITCH:
+		return "SWITCH"
+	case TOKEN_CASE:
+		return "CASE"
+	case TOKEN_DEFAULT:
+		return "DEFAULT"
+	case TOKEN_INCLUDE:
+		return "INCLUDE"
+	case TOKEN_DIRECTIVE:
+		return "DIRECTIVE"
+	case TOKEN_FLAG:
+		return "FLAG"
 	default:
 		return "UNKNOWN"
 	}
 }
 
 // Lexer SQL 模板词法分析器
+// 以 rune 为单位扫描，保证多字节字符（如中文注释、中文标识符）不会被截断，
+// 列号也按字符而非字节计算
 type Lexer struct {
 	input  string
+	src    []rune // input 的 rune 形式，pos 为其下标
 	pos    int
 	line   int
 	column int
 	tokens []Token
+	lines  []string     // 按行切分的 input（用于生成上下文，懒加载）
+	text   literalState // 普通 SQL 文本的字符串/注释状态（跨 token 保持）
+
+	// TabWidth 制表符宽度，用于计算列号（制表符会跳到下一个制表位）
+	TabWidth int
+	// MaxTokens 最多生成的 token 数，超出时返回 ErrParseLimit（0 表示不限制）
+	MaxTokens int
 }
 
+// DefaultTabWidth 默认制表符宽度
+const DefaultTabWidth = 4
+
 // NewLexer 创建词法分析器
 func NewLexer(input string) *Lexer {
+	input = normalizeSource(input)
 	return &Lexer{
-		input:  input,
-		pos:    0,
-		line:   1,
-		column: 1,
+		input:    input,
+		src:      []rune(input),
+		pos:      0,
+		line:     1,
+		column:   1,
+		TabWidth: DefaultTabWidth,
+	}
+}
+
+// normalizeSource 规范化模板源码：去掉 UTF-8 BOM，并将 \r\n 和单独的 \r 统一为 \n，
+// 避免 Windows 下编辑的模板在渲染结果中残留 \r 或导致行列号错位
+func normalizeSource(s string) string {
+	s = strings.TrimPrefix(s, "\ufeff")
+	if strings.IndexByte(s, '\r') < 0 {
+		return s
 	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
 }
 
 // Tokenize 执行词法分析
 func (l *Lexer) Tokenize() ([]Token, error) {
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
+		start, n := l.pos, len(l.tokens)
 		if err := l.scanToken(); err != nil {
 			return nil, err
 		}
+		for i := n; i < len(l.tokens); i++ {
+			l.tokens[i].Offset, l.tokens[i].End = start, l.pos
+		}
+		if l.MaxTokens > 0 && len(l.tokens) > l.MaxTokens {
+			return nil, codeError(CodeParseLimit, "line %d: token count exceeds %d: %w", l.line, l.MaxTokens, ErrParseLimit)
+		}
 	}
 
 	l.tokens = append(l.tokens, Token{
 		Type:   TOKEN_EOF,
 		Line:   l.line,
 		Column: l.column,
+		Offset: l.pos,
+		End:    l.pos,
 	})
 
 	return l.tokens, nil
@@ -135,7 +200,8 @@ func (l *Lexer) scanToken() error {
 	}
 
 	// 检查是否是 } 开始（可能是 } else if 或 } else {）
-	if l.peek() == '}' {
+	// 位于 SQL 字符串或注释中的 } 只是普通文本
+	if l.peek() == '}' && !l.text.active() {
 		return l.scanCloseBrace()
 	}
 
@@ -144,30 +210,41 @@ func (l *Lexer) scanToken() error {
 }
 
 // peek 查看当前字符
-func (l *Lexer) peek() byte {
-	if l.pos >= len(l.input) {
+func (l *Lexer) peek() rune {
+	if l.pos >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos]
+}
+
+// peekAt 查看当前位置之后第 offset 个字符
+func (l *Lexer) peekAt(offset int) rune {
+	if l.pos+offset >= len(l.src) {
 		return 0
 	}
-	return l.input[l.pos]
+	return l.src[l.pos+offset]
 }
 
 // peekN 查看后面 n 个字符
 func (l *Lexer) peekN(n int) string {
 	end := l.pos + n
-	if end > len(l.input) {
-		end = len(l.input)
+	if end > len(l.src) {
+		end = len(l.src)
 	}
-	return l.input[l.pos:end]
+	return string(l.src[l.pos:end])
 }
 
 // advance 前进一个字符
-func (l *Lexer) advance() byte {
-	ch := l.input[l.pos]
+func (l *Lexer) advance() rune {
+	ch := l.src[l.pos]
 	l.pos++
-	if ch == '\n' {
+	switch {
+	case ch == '\n':
 		l.line++
 		l.column = 1
-	} else {
+	case ch == '\t' && l.TabWidth > 0:
+		l.column += l.TabWidth - (l.column-1)%l.TabWidth
+	default:
 		l.column++
 	}
 	return ch
@@ -175,14 +252,14 @@ func (l *Lexer) advance() byte {
 
 // skipWhitespace 跳过空白字符（但不包括换行符，在某些情况下）
 func (l *Lexer) skipWhitespace() {
-	for l.pos < len(l.input) && (l.peek() == ' ' || l.peek() == '\t') {
+	for l.pos < len(l.src) && (l.peek() == ' ' || l.peek() == '\t') {
 		l.advance()
 	}
 }
 
 // skipAllWhitespace 跳过所有空白字符
 func (l *Lexer) skipAllWhitespace() {
-	for l.pos < len(l.input) && unicode.IsSpace(rune(l.peek())) {
+	for l.pos < len(l.src) && unicode.IsSpace(l.peek()) {
 		l.advance()
 	}
 }
@@ -218,13 +295,41 @@ func (l *Lexer) scanAtToken() error {
 		return l.scanIfToken(startLine, startColumn)
 	case "for":
 		return l.scanForToken(startLine, startColumn)
+	case "foreach":
+		return l.scanForEachToken(startLine, startColumn)
+	case "switch":
+		return l.scanHeaderToken(TOKEN_SWITCH, startLine, startColumn)
+	case "case":
+		return l.scanHeaderToken(TOKEN_CASE, startLine, startColumn)
 	case "use":
 		return l.scanUseToken(startLine, startColumn)
+	case "import":
+		return l.scanImportToken(startLine, startColumn)
+	case "include":
+		return l.scanIncludeToken(startLine, startColumn)
 	case "define":
 		return l.scanDefineToken(startLine, startColumn)
 	case "cover":
 		return l.scanCoverToken(startLine, startColumn)
+	case "set", "default":
+		// 只有后面跟着 { 时才是 @set / @default 块，否则仍然是同名的变量
+		if l.blockFollows() {
+			tokenType := TOKEN_SET
+			if word == "default" {
+				tokenType = TOKEN_DEFAULT
+			}
+			return l.scanBlockToken(tokenType, startLine, startColumn)
+		}
+		fallthrough
 	default:
+		// 只有后面跟着字符串时才是 @flag "name" 块，否则仍然是名为 flag 的变量
+		if word == "flag" && l.flagFollows() {
+			return l.scanHeaderToken(TOKEN_FLAG, startLine, startColumn)
+		}
+		if lookupDirective(word) != nil {
+			return l.scanDirectiveToken(word, startLine, startColumn)
+		}
+
 		// 检查是否是函数块 @funcName(...) {} 形式
 		if l.peek() == '(' {
 			return l.scanFuncBlockToken(word, startLine, startColumn)
@@ -250,10 +355,10 @@ func (l *Lexer) scanAtToken() error {
 // readWord 读取一个单词（字母、数字、下划线）
 func (l *Lexer) readWord() string {
 	var sb strings.Builder
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
 		ch := l.peek()
-		if unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) || ch == '_' {
-			sb.WriteByte(l.advance())
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' {
+			sb.WriteRune(l.advance())
 		} else {
 			break
 		}
@@ -268,20 +373,20 @@ func (l *Lexer) scanFuncBlockToken(funcName string, startLine, startColumn int)
 	sb.WriteString(funcName)
 
 	parenDepth := 0
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
 		ch := l.peek()
 
 		if ch == '(' {
 			parenDepth++
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 		} else if ch == ')' {
 			parenDepth--
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 			if parenDepth == 0 {
 				break
 			}
 		} else {
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 		}
 	}
 
@@ -293,7 +398,7 @@ func (l *Lexer) scanFuncBlockToken(funcName string, startLine, startColumn int)
 		l.advance() // 跳过 {
 
 		// 读取块内容
-		blockContent, err := l.readUntilMatchingBrace()
+		blockContent, err := l.readUntilMatchingBrace(false)
 		if err != nil {
 			return err
 		}
@@ -337,7 +442,7 @@ func (l *Lexer) scanVarExpr(startLine, startColumn int) error {
 	if hasBlock {
 		// 这是一个函数块 @ func() {}
 		// 读取块内容
-		blockContent, err := l.readUntilMatchingBrace()
+		blockContent, err := l.readUntilMatchingBrace(false)
 		if err != nil {
 			return err
 		}
@@ -375,15 +480,15 @@ func (l *Lexer) readExprOrFuncBlock() (string, bool, error) {
 	startLine := l.line
 	parenDepth := 0
 
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
 		ch := l.peek()
 
 		if ch == '(' {
 			parenDepth++
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 		} else if ch == ')' {
 			parenDepth--
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 		} else if ch == '@' && parenDepth == 0 {
 			// 正常表达式结束
 			l.advance()
@@ -393,11 +498,11 @@ func (l *Lexer) readExprOrFuncBlock() (string, bool, error) {
 			l.advance() // 跳过 {
 			return sb.String(), true, nil
 		} else {
-			sb.WriteByte(l.advance())
+			sb.WriteRune(l.advance())
 		}
 	}
 
-	return "", false, fmt.Errorf("line %d: unclosed expression", startLine)
+	return "", false, codeError(CodeUnclosedExpression, "line %d: unclosed expression", startLine)
 }
 
 // scanRawToken 扫描 @= 开头的 token
@@ -457,22 +562,22 @@ func (l *Lexer) readUntilAt() (string, error) {
 	var sb strings.Builder
 	startLine := l.line
 
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
 		if l.peek() == '@' {
 			l.advance() // 跳过结束的 @
 			return sb.String(), nil
 		}
-		sb.WriteByte(l.advance())
+		sb.WriteRune(l.advance())
 	}
 
-	return "", fmt.Errorf("line %d: unclosed expression, expected '@' to close the expression", startLine)
+	return "", codeError(CodeUnclosedExpression, "line %d: unclosed expression, expected '@' to close the expression", startLine)
 }
 
 // scanCodeBlock 扫描 @{} 代码块
 func (l *Lexer) scanCodeBlock(startLine, startColumn int) error {
 	l.advance() // 跳过 {
 
-	code, err := l.readUntilMatchingBrace()
+	code, err := l.readUntilMatchingBrace(true)
 	if err != nil {
 		return err
 	}
@@ -543,14 +648,109 @@ func (l *Lexer) scanForToken(startLine, startColumn int) error {
 	return nil
 }
 
+// blockFollows 当前位置之后（跳过空格和制表符）是否是 {，不移动位置
+func (l *Lexer) blockFollows() bool {
+	i := l.pos
+	for i < len(l.src) && (l.src[i] == ' ' || l.src[i] == '\t') {
+		i++
+	}
+	return i < len(l.src) && l.src[i] == '{'
+}
+
+// flagFollows 当前位置之后（跳过空格和制表符）是否是字符串，不移动位置
+func (l *Lexer) flagFollows() bool {
+	i := l.pos
+	for i < len(l.src) && (l.src[i] == ' ' || l.src[i] == '\t') {
+		i++
+	}
+	return i < len(l.src) && (l.src[i] == '"' || l.src[i] == '`')
+}
+
+// scanBlockToken 扫描没有参数的块语句（如 @set {），输出 tokenType 和 {
+func (l *Lexer) scanBlockToken(tokenType TokenType, startLine, startColumn int) error {
+	l.skipWhitespace()
+
+	l.tokens = append(l.tokens, Token{
+		Type:    tokenType,
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+
+	l.tokens = append(l.tokens, Token{
+		Type:   TOKEN_LBRACE,
+		Line:   l.line,
+		Column: l.column,
+	})
+	l.advance() // 跳过 {
+
+	return nil
+}
+
+// scanHeaderToken 扫描带表达式的块语句（如 @switch expr {、@case v1, v2 {），输出 tokenType 和 {
+func (l *Lexer) scanHeaderToken(tokenType TokenType, startLine, startColumn int) error {
+	l.skipWhitespace()
+
+	// 读取表达式直到 {（字符串中的 { 不算）
+	expr, err := l.readUntilBrace()
+	if err != nil {
+		return err
+	}
+
+	l.tokens = append(l.tokens, Token{
+		Type:    tokenType,
+		Value:   strings.TrimSpace(expr),
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+
+	l.tokens = append(l.tokens, Token{
+		Type:   TOKEN_LBRACE,
+		Line:   l.line,
+		Column: l.column,
+	})
+	l.advance() // 跳过 {
+
+	return nil
+}
+
+// scanForEachToken 扫描 @foreach 语句
+func (l *Lexer) scanForEachToken(startLine, startColumn int) error {
+	l.skipWhitespace()
+
+	// 读取 foreach 头部直到 {（sep 等选项中的字符串可以包含 {）
+	header, err := l.readUntilBrace()
+	if err != nil {
+		return err
+	}
+
+	l.tokens = append(l.tokens, Token{
+		Type:    TOKEN_FOREACH,
+		Value:   strings.TrimSpace(header),
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+
+	l.tokens = append(l.tokens, Token{
+		Type:   TOKEN_LBRACE,
+		Line:   l.line,
+		Column: l.column,
+	})
+	l.advance() // 跳过 {
+
+	return nil
+}
+
 // scanUseToken 扫描 @use 语句
 func (l *Lexer) scanUseToken(startLine, startColumn int) error {
 	l.skipWhitespace()
 
 	// 读取路径，直到 { 为止
 	var sb strings.Builder
-	for l.pos < len(l.input) && l.peek() != '{' && l.peek() != '\n' {
-		sb.WriteByte(l.advance())
+	for l.pos < len(l.src) && l.peek() != '{' && l.peek() != '\n' {
+		sb.WriteRune(l.advance())
 	}
 	path := strings.TrimSpace(sb.String())
 
@@ -577,14 +777,113 @@ func (l *Lexer) scanUseToken(startLine, startColumn int) error {
 	return nil
 }
 
+// scanImportToken 扫描 @import 语句（路径到空白为止）
+func (l *Lexer) scanImportToken(startLine, startColumn int) error {
+	l.skipWhitespace()
+
+	var sb strings.Builder
+	for l.pos < len(l.src) && !unicode.IsSpace(l.peek()) {
+		sb.WriteRune(l.advance())
+	}
+	path := strings.TrimSpace(sb.String())
+	if path == "" {
+		return fmt.Errorf("line %d: expected path after @import", startLine)
+	}
+
+	l.tokens = append(l.tokens, Token{
+		Type:    TOKEN_IMPORT,
+		Value:   path,
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+	return nil
+}
+
+// scanIncludeToken 扫描 @include 语句：路径到空白为止，路径后面紧跟的 (...) 是传给 define 的实参
+func (l *Lexer) scanIncludeToken(startLine, startColumn int) error {
+	l.skipWhitespace()
+
+	var sb strings.Builder
+	for l.pos < len(l.src) && !unicode.IsSpace(l.peek()) && l.peek() != '(' {
+		sb.WriteRune(l.advance())
+	}
+	if sb.Len() == 0 {
+		return fmt.Errorf("line %d: expected path after @include", startLine)
+	}
+	if l.peek() == '(' {
+		if !l.readParens(&sb) {
+			return codeError(CodeSyntax, "line %d: expected ')' to close @include arguments", startLine)
+		}
+	}
+
+	l.tokens = append(l.tokens, Token{
+		Type:    TOKEN_INCLUDE,
+		Value:   sb.String(),
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+	return nil
+}
+
+// readParens 读取从当前的 ( 到与之匹配的 ) 的内容（包括括号）写入 sb，字符串中的括号不算；
+// 到输入结尾也没有匹配的 ) 时返回 false
+func (l *Lexer) readParens(sb *strings.Builder) bool {
+	var state literalState
+	depth := 0
+	for l.pos < len(l.src) {
+		ch := l.peek()
+		if !state.feed(ch, l.peekAt(1), true) {
+			if ch == '(' {
+				depth++
+			} else if ch == ')' {
+				depth--
+			}
+		}
+		sb.WriteRune(l.advance())
+		if depth == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDirectiveToken 扫描 RegisterDirective 注册的指令：@name、@name(args)，后面可以跟 { } 块
+func (l *Lexer) scanDirectiveToken(name string, startLine, startColumn int) error {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if l.peek() == '(' && !l.readParens(&sb) {
+		return codeError(CodeSyntax, "line %d: expected ')' to close @%s arguments", startLine, name)
+	}
+
+	l.tokens = append(l.tokens, Token{
+		Type:    TOKEN_DIRECTIVE,
+		Value:   sb.String(),
+		Line:    startLine,
+		Column:  startColumn,
+		Context: l.getContext(startLine),
+	})
+	if l.blockFollows() {
+		l.skipWhitespace()
+		l.tokens = append(l.tokens, Token{
+			Type:   TOKEN_LBRACE,
+			Line:   l.line,
+			Column: l.column,
+		})
+		l.advance() // 跳过 {
+	}
+	return nil
+}
+
 // scanDefineToken 扫描 @define 语句
 func (l *Lexer) scanDefineToken(startLine, startColumn int) error {
 	l.skipWhitespace()
 
 	// 读取名称，直到 { 为止
 	var sb strings.Builder
-	for l.pos < len(l.input) && l.peek() != '{' && l.peek() != '\n' {
-		sb.WriteByte(l.advance())
+	for l.pos < len(l.src) && l.peek() != '{' && l.peek() != '\n' {
+		sb.WriteRune(l.advance())
 	}
 	name := strings.TrimSpace(sb.String())
 
@@ -616,8 +915,8 @@ func (l *Lexer) scanCoverToken(startLine, startColumn int) error {
 
 	// 读取名称，直到 { 为止
 	var sb strings.Builder
-	for l.pos < len(l.input) && l.peek() != '{' && l.peek() != '\n' {
-		sb.WriteByte(l.advance())
+	for l.pos < len(l.src) && l.peek() != '{' && l.peek() != '\n' {
+		sb.WriteRune(l.advance())
 	}
 	name := strings.TrimSpace(sb.String())
 
@@ -656,6 +955,12 @@ func (l *Lexer) scanCloseBrace() error {
 
 	l.skipAllWhitespace()
 
+	// @flag 块的 else 分支也可以写成 } @else {
+	if l.peekN(5) == "@else" {
+		l.pos++
+		l.column++
+	}
+
 	// 检查是否是 else
 	if l.peekN(4) == "else" {
 		l.pos += 4
@@ -728,12 +1033,16 @@ func (l *Lexer) scanText() error {
 	startColumn := l.column
 	var sb strings.Builder
 
-	for l.pos < len(l.input) {
+	for l.pos < len(l.src) {
 		ch := l.peek()
-		if ch == '@' || ch == '}' {
+		if ch == '@' {
+			break
+		}
+		// SQL 字符串（如 JSON 字面量）和注释中的 } 不会结束当前块
+		if !l.text.feed(ch, l.peekAt(1), false) && ch == '}' {
 			break
 		}
-		sb.WriteByte(l.advance())
+		sb.WriteRune(l.advance())
 	}
 
 	text := sb.String()
@@ -749,30 +1058,37 @@ func (l *Lexer) scanText() error {
 	return nil
 }
 
-// readUntilBrace 读取直到遇到 {
+// readUntilBrace 读取直到遇到 {（跳过 Go 字符串中的 {）
 func (l *Lexer) readUntilBrace() (string, error) {
 	var sb strings.Builder
 	startLine := l.line
+	var state literalState
 
-	for l.pos < len(l.input) {
-		if l.peek() == '{' {
+	for l.pos < len(l.src) {
+		ch := l.peek()
+		if !state.feed(ch, l.peekAt(1), true) && ch == '{' {
 			return sb.String(), nil
 		}
-		sb.WriteByte(l.advance())
+		sb.WriteRune(l.advance())
 	}
 
-	return "", fmt.Errorf("line %d: expected '{' but reached end of input", startLine)
+	return "", codeError(CodeUnclosedBrace, "line %d: expected '{' but reached end of input", startLine)
 }
 
 // readUntilMatchingBrace 读取直到匹配的 }
-func (l *Lexer) readUntilMatchingBrace() (string, error) {
+// goSyntax 为 true 时按 Go 代码识别字符串和注释（@{} 代码块），
+// 否则按 SQL 识别（函数块的块体），位于其中的括号不参与匹配
+func (l *Lexer) readUntilMatchingBrace(goSyntax bool) (string, error) {
 	var sb strings.Builder
 	startLine := l.line
 	depth := 1
+	var state literalState
 
-	for l.pos < len(l.input) && depth > 0 {
+	for l.pos < len(l.src) && depth > 0 {
 		ch := l.peek()
-		if ch == '{' {
+		if state.feed(ch, l.peekAt(1), goSyntax) {
+			// 字符串或注释内部，原样保留
+		} else if ch == '{' {
 			depth++
 		} else if ch == '}' {
 			depth--
@@ -781,15 +1097,81 @@ func (l *Lexer) readUntilMatchingBrace() (string, error) {
 				return sb.String(), nil
 			}
 		}
-		sb.WriteByte(l.advance())
+		sb.WriteRune(l.advance())
 	}
 
-	return "", fmt.Errorf("line %d: unclosed brace, expected '}' to close the code block", startLine)
+	return "", codeError(CodeUnclosedBrace, "line %d: unclosed brace, expected '}' to close the code block", startLine)
+}
+
+// literalState 记录扫描过程中的字符串/注释状态，用于括号匹配时跳过其中的 { }
+type literalState struct {
+	quote        rune // 当前字符串的引号，0 表示不在字符串中
+	escaped      bool // 上一个字符是否为转义符（仅 Go 的双引号和单引号字符串）
+	lineComment  bool
+	blockComment bool
+	skip         bool // 跳过下一个字符（注释起止符的第二个字符）
+}
+
+// active 是否位于字符串或注释中
+func (s *literalState) active() bool {
+	return s.quote != 0 || s.lineComment || s.blockComment
+}
+
+// feed 读入当前字符 ch（next 为其后一个字符）并更新状态，
+// 返回 ch 是否属于字符串或注释（包括引号和注释符本身）
+// goSyntax 为 true 时识别 Go 的双引号、单引号、反引号字符串与 // /* */ 注释，
+// 否则识别 SQL 的单引号、双引号字符串与 -- /* */ 注释
+func (s *literalState) feed(ch, next rune, goSyntax bool) bool {
+	switch {
+	case s.skip:
+		s.skip = false
+		return true
+	case s.lineComment:
+		if ch == '\n' {
+			s.lineComment = false
+		}
+		return true
+	case s.blockComment:
+		if ch == '*' && next == '/' {
+			s.blockComment = false
+			s.skip = true
+		}
+		return true
+	case s.quote != 0:
+		if goSyntax && s.quote != '`' {
+			if s.escaped {
+				s.escaped = false
+			} else if ch == '\\' {
+				s.escaped = true
+			} else if ch == s.quote {
+				s.quote = 0
+			}
+		} else if ch == s.quote {
+			s.quote = 0
+		}
+		return true
+	}
+
+	switch {
+	case ch == '\'' || ch == '"' || (goSyntax && ch == '`'):
+		s.quote = ch
+	case ch == '/' && next == '*':
+		s.blockComment = true
+		s.skip = true
+	case goSyntax && ch == '/' && next == '/', !goSyntax && ch == '-' && next == '-':
+		s.lineComment = true
+	default:
+		return false
+	}
+	return true
 }
 
 // getContext 获取指定行的上下文
 func (l *Lexer) getContext(line int) string {
-	lines := strings.Split(l.input, "\n")
+	if l.lines == nil {
+		l.lines = strings.Split(l.input, "\n")
+	}
+	lines := l.lines
 	if line <= 0 || line > len(lines) {
 		return ""
 	}
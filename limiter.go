@@ -0,0 +1,80 @@
+package gosql
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// renderLimiter 限制同时进行的渲染数（WithMaxConcurrentRenders）
+type renderLimiter struct {
+	slots     chan struct{}
+	renders   int64 // 经过限流的渲染次数
+	waits     int64 // 需要排队的次数
+	waiting   int64 // 正在排队的渲染数
+	queueTime int64 // 排队总耗时（纳秒）
+	maxQueue  int64 // 最长的一次排队耗时（纳秒）
+}
+
+// acquire 获取一个渲染名额，没有空闲名额时排队等待
+func (l *renderLimiter) acquire() {
+	atomic.AddInt64(&l.renders, 1)
+	select {
+	case l.slots <- struct{}{}:
+		return
+	default:
+	}
+	atomic.AddInt64(&l.waits, 1)
+	atomic.AddInt64(&l.waiting, 1)
+	start := time.Now()
+	l.slots <- struct{}{}
+	waited := int64(time.Since(start))
+	atomic.AddInt64(&l.waiting, -1)
+	atomic.AddInt64(&l.queueTime, waited)
+	for {
+		max := atomic.LoadInt64(&l.maxQueue)
+		if waited <= max || atomic.CompareAndSwapInt64(&l.maxQueue, max, waited) {
+			return
+		}
+	}
+}
+
+// release 归还渲染名额
+func (l *renderLimiter) release() {
+	<-l.slots
+}
+
+// RenderMetrics 渲染并发限制（WithMaxConcurrentRenders）的统计，未设置限制时除 MaxConcurrent 外都为 0
+type RenderMetrics struct {
+	MaxConcurrent int           `json:"max_concurrent"` // 最多同时进行的渲染数，0 表示不限制
+	InFlight      int           `json:"in_flight"`      // 正在进行的渲染数
+	Waiting       int           `json:"waiting"`        // 正在排队的渲染数
+	Renders       int64         `json:"renders"`        // 渲染次数
+	Waits         int64         `json:"waits"`          // 需要排队的渲染次数
+	QueueTime     time.Duration `json:"queue_time"`     // 排队总耗时
+	MaxQueueTime  time.Duration `json:"max_queue_time"` // 最长的一次排队耗时
+}
+
+// AvgQueueTime 需要排队的渲染平均排队耗时
+func (m RenderMetrics) AvgQueueTime() time.Duration {
+	if m.Waits == 0 {
+		return 0
+	}
+	return m.QueueTime / time.Duration(m.Waits)
+}
+
+// RenderMetrics 返回渲染并发限制的统计，可以导出到监控系统观察排队情况
+func (e *Engine) RenderMetrics() RenderMetrics {
+	l := e.limiter
+	if l == nil {
+		return RenderMetrics{}
+	}
+	return RenderMetrics{
+		MaxConcurrent: cap(l.slots),
+		InFlight:      len(l.slots),
+		Waiting:       int(atomic.LoadInt64(&l.waiting)),
+		Renders:       atomic.LoadInt64(&l.renders),
+		Waits:         atomic.LoadInt64(&l.waits),
+		QueueTime:     time.Duration(atomic.LoadInt64(&l.queueTime)),
+		MaxQueueTime:  time.Duration(atomic.LoadInt64(&l.maxQueue)),
+	}
+}
@@ -0,0 +1,125 @@
+package gosql
+
+import (
+	"errors"
+)
+
+// ErrParseLimit 输入超出解析限制（大小、token 数、嵌套深度）
+var ErrParseLimit = errors.New("parse limit exceeded")
+
+// ParseLimits 解析限制，用于解析不可信的输入（例如管理后台中用户编辑的 markdown），
+// 超出限制时返回包装了 ErrParseLimit 的错误，而不是耗尽内存或栈。字段为 0 表示不限制
+type ParseLimits struct {
+	MaxSize   int // 输入的最大字节数
+	MaxTokens int // 单个模板的最大 token 数
+	MaxDepth  int // @if / @for / @define 等块的最大嵌套深度
+}
+
+// DefaultParseLimits ParseTemplate / ParseMarkdown 使用的默认限制
+var DefaultParseLimits = ParseLimits{
+	MaxSize:   8 << 20,
+	MaxTokens: 1 << 20,
+	MaxDepth:  128,
+}
+
+// checkSize 检查输入大小
+func (l ParseLimits) checkSize(content string) error {
+	if l.MaxSize > 0 && len(content) > l.MaxSize {
+		return codeError(CodeParseLimit, "input size %d exceeds %d: %w", len(content), l.MaxSize, ErrParseLimit)
+	}
+	return nil
+}
+
+// recoverParse 把解析过程中的 panic 转换为错误，保证解析入口不会让调用方崩溃
+func recoverParse(err *error) {
+	if r := recover(); r != nil {
+		*err = codeError(CodeSyntax, "parse error: %v", r)
+	}
+}
+
+// TemplateLimits 模板结构限制，用于加载第三方模板包等场景，防止异常模板造成过深的递归。
+// 字段为 0 表示不限制
+type TemplateLimits struct {
+	MaxDefineDepth int // @define 的最大嵌套层数（加载时检查）
+	MaxForDepth    int // @for 的最大嵌套层数（加载时检查）
+	MaxUseDepth    int // @use 链的最大长度（渲染时检查，同时防止模板互相 @use 造成无限递归）
+}
+
+// DefaultTemplateLimits 引擎默认的模板结构限制
+var DefaultTemplateLimits = TemplateLimits{
+	MaxDefineDepth: 16,
+	MaxForDepth:    8,
+	MaxUseDepth:    32,
+}
+
+// checkNodes 检查节点的 @define / @for 嵌套层数
+func (l TemplateLimits) checkNodes(nodes []Node) error {
+	return l.walk(nodes, 0, 0)
+}
+
+// walk 递归检查，defines / fors 为当前所在的 @define / @for 层数
+func (l TemplateLimits) walk(nodes []Node, defines, fors int) error {
+	for _, node := range nodes {
+		var children [][]Node
+		d, f := defines, fors
+		switch n := node.(type) {
+		case *DefineNode:
+			d++
+			if l.MaxDefineDepth > 0 && d > l.MaxDefineDepth {
+				return codeError(CodeTemplateLimit, "define %s: nesting depth exceeds %d", n.Name, l.MaxDefineDepth)
+			}
+			children = append(children, n.Body)
+		case *ForNode:
+			f++
+			if l.MaxForDepth > 0 && f > l.MaxForDepth {
+				return codeError(CodeTemplateLimit, "for %s: nesting depth exceeds %d", n.Expr, l.MaxForDepth)
+			}
+			children = append(children, n.Body)
+		case *IfNode:
+			children = append(children, n.Body)
+			for _, ei := range n.ElseIf {
+				children = append(children, ei.Body)
+			}
+			if n.Else != nil {
+				children = append(children, n.Else.Body)
+			}
+		case *CoverNode:
+			children = append(children, n.Body)
+		case *UseNode:
+			for _, cover := range n.Covers {
+				children = append(children, cover.Body)
+			}
+		case *ConditionalLineNode:
+			children = append(children, n.LineNodes)
+		case *FuncBlockNode:
+			children = append(children, n.Body)
+		case *ForEachNode:
+			f++
+			if l.MaxForDepth > 0 && f > l.MaxForDepth {
+				return codeError(CodeTemplateLimit, "foreach %s: nesting depth exceeds %d", n.List, l.MaxForDepth)
+			}
+			children = append(children, n.Body)
+		case *SwitchNode:
+			for _, c := range n.branches() {
+				children = append(children, c.Body)
+			}
+		case *SetNode:
+			children = append(children, n.Body)
+		case *FlagNode:
+			children = append(children, n.Body)
+			if n.Else != nil {
+				children = append(children, n.Else.Body)
+			}
+		case *TrimNode:
+			children = append(children, n.Body)
+		case *DirectiveNode:
+			children = append(children, n.Body)
+		}
+		for _, body := range children {
+			if err := l.walk(body, d, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package gosql
+
+import (
+	"strings"
+)
+
+// linkTemplates 在模板加载后，为每个模板汇总它（包括通过 @use 引用的模板）在渲染时
+// 可能引用的变量名和调用的函数名：
+//   - methodCalls：渲染时只绑定这些名字对应的参数方法，避免为每个方法都构造 reflect 值并 BindFunc；
+//     模板中存在无法静态分析的表达式时为 nil，表示绑定全部方法
+//   - scopeNames：参数为 Scope 时按这些名字查找变量
+func (e *Engine) linkTemplates() {
+	if e.memo != nil {
+		// 模板变化后缓存的渲染结果可能过期
+		e.memo.reset()
+	}
+	e.programs.reset()
+	for _, ast := range e.compiledAST {
+		l := &templateLink{
+			calls:   make(map[string]bool),
+			names:   make(map[string]bool),
+			visited: make(map[*TemplateAST]bool),
+		}
+		l.collect(e, ast)
+		ast.buildSkeletons()
+		ast.scopeNames = l.names
+		ast.flagged = l.flagged
+		if l.dynamic {
+			ast.methodCalls = nil
+		} else {
+			ast.methodCalls = l.calls
+		}
+	}
+	e.repin()
+}
+
+// templateLink 汇总模板引用时的中间状态
+type templateLink struct {
+	calls   map[string]bool
+	names   map[string]bool
+	dynamic bool
+	flagged bool // 使用了 @flag
+	visited map[*TemplateAST]bool
+}
+
+// collect 收集模板及其 @use / @import 引用的模板中的变量名和函数名
+func (l *templateLink) collect(e *Engine, ast *TemplateAST) {
+	if l.visited[ast] {
+		return
+	}
+	l.visited[ast] = true
+	if ast.refs == nil {
+		l.dynamic = true
+		return
+	}
+	l.dynamic = l.dynamic || ast.refs.dynamic
+	l.flagged = l.flagged || len(ast.refs.flags) > 0
+	for name := range ast.refs.calls {
+		l.calls[name] = true
+		l.names[name] = true
+	}
+	for _, p := range ast.refs.params {
+		l.names[p.Name] = true
+	}
+	for _, paths := range [][]string{ast.refs.uses, ast.refs.imports} {
+		for _, path := range paths {
+			parts := strings.SplitN(path, ".", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			if used, ok := e.compiledAST[parts[0]+"."+parts[1]]; ok {
+				l.collect(e, used)
+			}
+		}
+	}
+}
+
+// linkCovers 返回合并了 cover 中引用的变量名和函数名的模板副本（节点共享）
+func (e *Engine) linkCovers(ast *TemplateAST, covers map[string][]Node) *TemplateAST {
+	l := &templateLink{
+		calls:   make(map[string]bool),
+		names:   make(map[string]bool),
+		visited: make(map[*TemplateAST]bool),
+	}
+	l.collect(e, ast)
+	for _, body := range covers {
+		l.collect(e, &TemplateAST{Nodes: body, refs: analyzeTemplate(body)})
+	}
+	linked := *ast
+	linked.scopeNames = l.names
+	linked.methodCalls = l.calls
+	linked.flagged = l.flagged
+	if l.dynamic {
+		linked.methodCalls = nil
+	}
+	return &linked
+}
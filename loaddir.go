@@ -0,0 +1,137 @@
+package gosql
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPatterns LoadDir / LoadFS 默认加载的文件
+var defaultPatterns = []string{"*.md"}
+
+// LoadDir 递归加载目录下所有匹配 patterns 的 markdown 文件（默认 "*.md"），每个文件按 LoadFile 的规则加载
+// （重复加载时增量更新，不同文件中的同名模板报错）。全部加载完成后校验模板间的引用（同 Validate），
+// 返回加载后的构建情况。
+//
+// pattern 不含 / 时匹配文件名，含 / 时匹配相对 dir 的路径（以 / 分隔），** 匹配任意层目录：
+//
+//	engine.LoadDir("sql")                                // sql 下所有 .md 文件
+//	engine.LoadDir("sql", "user/*.md", "order/**/*.md")
+func (e *Engine) LoadDir(dir string, patterns ...string) (BuildReport, error) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if matchPatterns(patterns, filepath.ToSlash(rel)) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return BuildReport{}, err
+	}
+	return e.loadFiles(nil, files)
+}
+
+// LoadFS 加载 fsys 中所有匹配 patterns 的 markdown 文件（默认 "*.md"，规则同 LoadDir），
+// 适合用 go:embed 把模板打包进二进制：
+//
+//	//go:embed sql
+//	var sqlFS embed.FS
+//
+//	report, err := engine.LoadFS(sqlFS)
+//
+// 文件以 fsys 中的路径（如 "sql/user.md"）标识，include 声明的依赖相对文件所在目录在 fsys 中查找
+func (e *Engine) LoadFS(fsys fs.FS, patterns ...string) (BuildReport, error) {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if matchPatterns(patterns, p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return BuildReport{}, err
+	}
+	return e.loadFiles(fsys, files)
+}
+
+// loadFiles 按路径顺序加载文件（fsys 为 nil 时从操作系统的文件系统读取），全部加载后校验模板间的引用
+func (e *Engine) loadFiles(fsys fs.FS, files []string) (BuildReport, error) {
+	e.mu.Lock()
+	keys, err := e.loadFilesLocked(fsys, files)
+	e.mu.Unlock()
+	e.notifyReload(keys, err)
+	return e.BuildReport(), err
+}
+
+// loadFilesLocked 同 loadFiles，调用方持有写锁，返回变化的模板（已排序）
+func (e *Engine) loadFilesLocked(fsys fs.FS, files []string) ([]string, error) {
+	sort.Strings(files)
+	var keys []string
+	loaded := make(map[string]bool, len(files))
+	for _, file := range files {
+		if loaded[file] {
+			continue
+		}
+		ev, err := e.loadFileTree(fsys, nil, file, nil, loaded)
+		keys = append(keys, ev.Keys()...)
+		if err != nil {
+			return keys, err
+		}
+	}
+	sort.Strings(keys)
+	return keys, e.validateRefs(true)
+}
+
+// matchPatterns 判断相对路径 name（以 / 分隔）是否匹配任意一个 pattern，patterns 为空时使用 defaultPatterns
+func matchPatterns(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		patterns = defaultPatterns
+	}
+	for _, pattern := range patterns {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(target, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob 按路径段匹配，** 段匹配零个或多个目录
+func matchGlob(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlob(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], parts[1:])
+}
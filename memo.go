@@ -0,0 +1,160 @@
+package gosql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync"
+)
+
+// DefaultMemoSize 纯模板渲染结果缓存的默认容量（条数）
+const DefaultMemoSize = 4096
+
+// impureBuiltins 每次调用结果都可能不同的内置函数，标记为 pure 的模板不能使用
+var impureBuiltins = []string{"uuid", "nowUTC", "seq", "render"}
+
+// compilePure 解析模板的 pure: 元数据，标记为 pure 的模板不能调用 impureBuiltins
+func compilePure(tmpl *SQLTemplate, ast *TemplateAST) (bool, error) {
+	raws := tmpl.Meta["pure"]
+	if len(raws) == 0 {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(raws[len(raws)-1])) {
+	case "true":
+	case "false":
+		return false, nil
+	default:
+		return false, codeError(CodeMarkdown, "invalid pure declaration %q, expected true or false", raws[len(raws)-1])
+	}
+	if ast.refs != nil {
+		for _, name := range impureBuiltins {
+			if ast.refs.calls[name] {
+				return false, codeError(CodeMarkdown, "template %s.%s is marked pure but calls %s()", tmpl.Namespace, tmpl.Name, name)
+			}
+		}
+		if len(ast.refs.flags) > 0 {
+			return false, codeError(CodeMarkdown, "template %s.%s is marked pure but uses @flag %q", tmpl.Namespace, tmpl.Name, ast.refs.flags[0])
+		}
+	}
+	return true, nil
+}
+
+// memoizable 判断模板（包括 @use 引用的模板）的渲染结果是否只取决于参数：
+// 模板标记为 pure，没有无法静态分析的表达式，没有使用 @flag，也没有调用 impureBuiltins
+func (ast *TemplateAST) memoizable() bool {
+	if !ast.pure || ast.flagged || ast.methodCalls == nil {
+		return false
+	}
+	for _, name := range impureBuiltins {
+		if ast.methodCalls[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// memoKey 渲染结果缓存的 key：模板路径和参数的哈希
+type memoKey struct {
+	path string
+	args [sha256.Size]byte
+}
+
+// memoEntry 缓存的渲染结果
+type memoEntry struct {
+	key   memoKey
+	query Query
+}
+
+// memoCache 纯模板渲染结果的 LRU 缓存
+type memoCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[memoKey]*list.Element
+	order *list.List // 最近使用的在前
+}
+
+// newMemoCache 创建容量为 size 的缓存
+func newMemoCache(size int) *memoCache {
+	return &memoCache{
+		size:  size,
+		items: make(map[memoKey]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get 返回缓存的结果（参数列表是副本，调用方可以修改）
+func (c *memoCache) get(key memoKey) (Query, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return Query{}, false
+	}
+	c.order.MoveToFront(el)
+	q := el.Value.(*memoEntry).query
+	q.Params = append([]interface{}(nil), q.Params...)
+	return q, true
+}
+
+// put 缓存结果，超出容量时淘汰最久未使用的
+func (c *memoCache) put(key memoKey, q Query) {
+	q.Params = append([]interface{}(nil), q.Params...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*memoEntry).query = q
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&memoEntry{key: key, query: q})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoEntry).key)
+	}
+}
+
+// reset 清空缓存（模板重新加载后调用）
+func (c *memoCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[memoKey]*list.Element)
+	c.order.Init()
+}
+
+// renderMemo 同 renderAt（顶层渲染），模板标记为 pure 且参数可以哈希（见 ArgsHasher）时使用缓存的结果
+func (e *Engine) renderMemo(goctx context.Context, path string, args interface{}, record bool) (Query, *TemplateAST, error) {
+	if e.memo == nil {
+		return e.renderAt(goctx, path, args, record, 0, nil, nil)
+	}
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return e.renderAt(goctx, path, args, record, 0, nil, nil)
+	}
+	key := parts[0] + "." + parts[1]
+	ast, ok := e.compiledAST[key]
+	if !ok || !ast.memoizable() {
+		return e.renderAt(goctx, path, args, record, 0, nil, nil)
+	}
+	hasher := e.hasher
+	if hasher == nil {
+		hasher = defaultArgsHasher
+	}
+	sum, ok := hasher.Hash(args)
+	if !ok {
+		return e.renderAt(goctx, path, args, record, 0, nil, nil)
+	}
+	mk := memoKey{path: path, args: sum}
+	if q, ok := e.memo.get(mk); ok {
+		if record {
+			e.recordUsage(key)
+		}
+		return q, ast, nil
+	}
+	q, ast, err := e.renderAt(goctx, path, args, record, 0, nil, nil)
+	if err == nil {
+		e.memo.put(mk, q)
+	}
+	return q, ast, err
+}
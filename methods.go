@@ -0,0 +1,33 @@
+package gosql
+
+import (
+	"reflect"
+)
+
+// MethodPolicy 参数方法绑定策略：决定 args（结构体）的哪些导出方法可以在模板中调用。
+// t 为方法所属的结构体类型（已去掉指针），name 为方法名。
+// 默认（nil）绑定所有导出方法；对于带有 Delete() 这类有副作用方法的类型，建议只开放白名单
+type MethodPolicy func(t reflect.Type, name string) bool
+
+// NoMethods 不绑定任何方法，模板中只能使用字段和 RegisterFunc 注册的函数
+func NoMethods() MethodPolicy {
+	return func(reflect.Type, string) bool { return false }
+}
+
+// AllowMethods 只绑定白名单中的方法。
+// 名称可以是方法名（如 "FullName"，对所有类型生效），
+// 也可以是 "类型名.方法名"（如 "User.FullName"，只对该类型生效）
+func AllowMethods(names ...string) MethodPolicy {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return func(t reflect.Type, name string) bool {
+		return allowed[name] || allowed[t.Name()+"."+name]
+	}
+}
+
+// allows 判断方法是否允许绑定
+func (p MethodPolicy) allows(t reflect.Type, name string) bool {
+	return p == nil || p(t, name)
+}
@@ -0,0 +1,181 @@
+package gosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// MigrationTable 记录已执行迁移的表
+const MigrationTable = "schema_migrations"
+
+// Migration 迁移：描述中带有 migration: 0005 元数据行的模板，内容必须是静态 SQL（见 GetStatic）
+type Migration struct {
+	Version     string // 版本号（migration 元数据的值）
+	Path        string // 模板路径 namespace.name
+	Description string
+	Dialect     Dialect // 模板代码块声明的方言
+}
+
+// Migrations 返回所有迁移，按版本号排序（数字版本按数值比较）
+func (e *Engine) Migrations() ([]Migration, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var migrations []Migration
+	seen := make(map[string]string)
+	for key, tmpl := range e.store.templates {
+		versions := tmpl.Meta["migration"]
+		if len(versions) == 0 {
+			continue
+		}
+		if len(versions) > 1 {
+			return nil, fmt.Errorf("template %s: multiple migration versions %v", key, versions)
+		}
+		version := versions[0]
+		if other, ok := seen[version]; ok {
+			return nil, codeError(CodeDuplicateTemplate, "migration %s defined in both %s and %s", version, other, key)
+		}
+		seen[version] = key
+		migrations = append(migrations, Migration{Version: version, Path: key, Description: tmpl.Description, Dialect: tmpl.Dialect})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return versionLess(migrations[i].Version, migrations[j].Version)
+	})
+	return migrations, nil
+}
+
+// versionLess 比较版本号：都是数字时按数值比较，否则按字符串比较
+func versionLess(a, b string) bool {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil && na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+// WithDialect 设置数据库的方言，Migrate 按它生成 schema_migrations 的建表语句和参数占位符。
+// 没有设置时使用迁移模板代码块声明的方言（如 ```postgresql），都没有声明时按 MySQL / SQLite 的写法
+func WithDialect(dialect Dialect) ExecutorOption {
+	return func(x *Executor) {
+		x.dialect = dialect
+	}
+}
+
+// Migrate 按版本顺序执行尚未执行的迁移，每个迁移（包括写入 schema_migrations）在一个事务中执行，
+// 返回本次执行的迁移。某个迁移失败时回滚该迁移并停止，之前已执行的迁移保持提交。
+// 写入版本的语句按引擎的 WithPlaceholders 生成占位符，没有设置时按方言（见 WithDialect）Rebind
+func (x *Executor) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := x.engine.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	dialect := x.migrationDialect(migrations)
+	if _, err := x.db.ExecContext(ctx, migrationTableDDL(dialect)); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	applied, err := x.appliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := x.applyMigration(ctx, m, x.migrationInsert(dialect, m.Version)); err != nil {
+			return done, fmt.Errorf("migrate %s (%s): %w", m.Version, m.Path, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// migrationDialect 迁移使用的方言：WithDialect 设置的方言，没有设置时取第一个声明了方言的迁移模板
+func (x *Executor) migrationDialect(migrations []Migration) Dialect {
+	if x.dialect != DialectDefault {
+		return x.dialect
+	}
+	for _, m := range migrations {
+		if m.Dialect != DialectDefault {
+			return m.Dialect
+		}
+	}
+	return DialectDefault
+}
+
+// migrationTableDDL 创建 schema_migrations 的语句（表已存在时不报错）
+func migrationTableDDL(dialect Dialect) string {
+	switch dialect {
+	case DialectSQLServer:
+		// SQL Server 没有 create table if not exists，timestamp 类型是 rowversion
+		return "if object_id(N'" + MigrationTable + "', N'U') is null create table " + MigrationTable +
+			" (version nvarchar(64) primary key, applied_at datetime2 default sysutcdatetime())"
+	case DialectOracle:
+		// Oracle 23c 之前没有 create table if not exists，忽略 ORA-00955（名称已被使用）
+		return "begin execute immediate 'create table " + MigrationTable +
+			" (version varchar2(64) primary key, applied_at timestamp default current_timestamp)'; " +
+			"exception when others then if sqlcode != -955 then raise; end if; end;"
+	}
+	return "create table if not exists " + MigrationTable +
+		" (version varchar(64) primary key, applied_at timestamp default current_timestamp)"
+}
+
+// migrationInsert 写入迁移版本的语句
+func (x *Executor) migrationInsert(dialect Dialect, version string) Query {
+	q := Query{SQL: "insert into " + MigrationTable + " (version) values (?)", Params: []interface{}{version}}
+	if x.engine.placeholders != nil {
+		return bindPlaceholders(q, x.engine.placeholders)
+	}
+	return q.Rebind(dialect)
+}
+
+// appliedMigrations 返回已执行的迁移版本
+func (x *Executor) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := x.db.QueryContext(ctx, "select version from "+MigrationTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration 在事务中执行一个迁移的所有语句，再执行 insert 记录版本
+func (x *Executor) applyMigration(ctx context.Context, m Migration, insert Query) error {
+	if _, err := x.engine.GetStatic(m.Path); err != nil {
+		return err
+	}
+	tmpl, _ := x.engine.store.Get(m.Path)
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := execMigration(ctx, tx, tmpl.Statements, insert); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// execMigration 依次执行迁移语句并写入版本
+func execMigration(ctx context.Context, tx *sql.Tx, statements []string, insert Query) error {
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, insert.SQL, insert.Params...)
+	return err
+}
@@ -0,0 +1,262 @@
+package gosql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Credentials AWS 访问凭证
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // 临时凭证（STS）的会话令牌，长期凭证为空
+}
+
+// S3CredentialsProvider 返回签名使用的凭证，每个请求调用一次（临时凭证可以在这里刷新）
+type S3CredentialsProvider func() (S3Credentials, error)
+
+// StaticS3Credentials 固定的凭证
+func StaticS3Credentials(accessKeyID, secretAccessKey, sessionToken string) S3CredentialsProvider {
+	creds := S3Credentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}
+	return func() (S3Credentials, error) {
+		return creds, nil
+	}
+}
+
+// EnvS3Credentials 从环境变量 AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN 读取凭证
+func EnvS3Credentials() S3CredentialsProvider {
+	return func() (S3Credentials, error) {
+		creds := S3Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return S3Credentials{}, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+		}
+		return creds, nil
+	}
+}
+
+// S3Source 读取 S3 对象。creds 不为 nil 时用 AWS Signature Version 4 给每个请求签名（私有 bucket），
+// 为 nil 时匿名读取（公开对象）。region 为空时使用 us-east-1
+func S3Source(bucket, region string, creds S3CredentialsProvider, keys ...string) *HTTPSource {
+	if region == "" {
+		region = "us-east-1"
+	}
+	host := bucket + ".s3." + region + ".amazonaws.com"
+	urls := make([]string, len(keys))
+	for i, key := range keys {
+		urls[i] = "https://" + host + "/" + escapeObjectKey(key)
+	}
+	src := NewHTTPSource(urls...)
+	if creds != nil {
+		src.Header = SignS3(region, creds)
+	}
+	return src
+}
+
+// SignS3 返回用 AWS Signature Version 4 给请求签名的函数（可以作为 HTTPSource.Header），
+// 请求上已有的头都参与签名，请求体按空内容计算哈希（只用于 GET）
+func SignS3(region string, creds S3CredentialsProvider) func(req *http.Request) error {
+	return func(req *http.Request) error {
+		c, err := creds()
+		if err != nil {
+			return fmt.Errorf("s3 credentials: %w", err)
+		}
+		signS3(req, region, c, time.Now())
+		return nil
+	}
+}
+
+// emptyPayloadHash 空请求体的 SHA-256
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3 按 SigV4 计算签名并设置 Authorization 等请求头
+func signS3(req *http.Request, region string, creds S3Credentials, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, v := range values {
+			params = append(params, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(params)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapeObjectKeyStrict(req.URL.Path),
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+// sha256Hex 内容的 SHA-256（十六进制）
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape 按 SigV4 的规则转义：只保留 A-Z a-z 0-9 - _ . ~
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// escapeObjectKeyStrict 按 SigV4 的规则转义路径的每一段（保留 "/"）
+func escapeObjectKeyStrict(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = awsEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// escapeObjectKey 转义对象 key 的每一段（保留 "/"），结果与签名使用的路径一致
+func escapeObjectKey(key string) string {
+	return escapeObjectKeyStrict(strings.TrimPrefix(key, "/"))
+}
+
+// GCSTokenProvider 返回 Google Cloud 的 OAuth2 访问令牌，每个请求调用一次。
+// 使用 golang.org/x/oauth2 时可以适配 TokenSource：
+//
+//	func() (string, error) {
+//		t, err := ts.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return t.AccessToken, nil
+//	}
+type GCSTokenProvider func() (string, error)
+
+// GCSSource 读取 Google Cloud Storage 对象。token 不为 nil 时以 Authorization: Bearer 访问（私有 bucket），
+// 为 nil 时匿名读取（公开对象）
+func GCSSource(bucket string, token GCSTokenProvider, objects ...string) *HTTPSource {
+	urls := make([]string, len(objects))
+	for i, object := range objects {
+		urls[i] = "https://storage.googleapis.com/" + bucket + "/" + escapeObjectKey(object)
+	}
+	src := NewHTTPSource(urls...)
+	if token != nil {
+		src.Header = func(req *http.Request) error {
+			t, err := token()
+			if err != nil {
+				return fmt.Errorf("gcs token: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+t)
+			return nil
+		}
+	}
+	return src
+}
+
+// gcsMetadataTokenURL GCE / GKE / Cloud Run 元数据服务中默认服务账号的令牌地址
+var gcsMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCSMetadataToken 从元数据服务获取运行环境（GCE、GKE、Cloud Run 等）默认服务账号的访问令牌，
+// 令牌在过期前 1 分钟内复用。client 为 nil 时使用 http.DefaultClient
+func GCSMetadataToken(client *http.Client) GCSTokenProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var mu sync.Mutex
+	var token string
+	var expiry time.Time
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && time.Now().Before(expiry) {
+			return token, nil
+		}
+		req, err := http.NewRequest(http.MethodGet, gcsMetadataTokenURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("metadata token: unexpected status %s", resp.Status)
+		}
+		var body struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return "", fmt.Errorf("metadata token: %w", err)
+		}
+		if body.AccessToken == "" {
+			return "", fmt.Errorf("metadata token: empty access token")
+		}
+		token = body.AccessToken
+		expiry = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+		return token, nil
+	}
+}
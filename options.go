@@ -0,0 +1,223 @@
+package gosql
+
+import (
+	"reflect"
+	"time"
+)
+
+// Option 引擎配置项，用于 New
+type Option func(*Engine)
+
+// WithQueryHashComment 渲染结果末尾追加查询指纹注释（/* qh:xxxx */），
+// 便于 DBA 根据指纹固定执行计划或屏蔽特定形态的查询
+func WithQueryHashComment() Option {
+	return func(e *Engine) {
+		e.hashComment = true
+	}
+}
+
+// WithKeywordCase 渲染后统一 SQL 关键字的大小写（不影响标识符和字符串）
+func WithKeywordCase(c KeywordCase) Option {
+	return func(e *Engine) {
+		e.keywordCase = c
+	}
+}
+
+// WithMethodPolicy 设置参数方法绑定策略，例如 WithMethodPolicy(AllowMethods("User.FullName"))
+// 只开放白名单方法，或 WithMethodPolicy(NoMethods()) 完全禁用方法绑定
+func WithMethodPolicy(p MethodPolicy) Option {
+	return func(e *Engine) {
+		e.methodPolicy = p
+	}
+}
+
+// WithoutMethodBinding 完全关闭参数方法绑定，渲染时不再为参数类型的方法构造 reflect 值。
+// 默认情况下引擎也只会绑定模板（及其 @use 引用的模板）中实际调用到的方法
+func WithoutMethodBinding() Option {
+	return func(e *Engine) {
+		e.noMethods = true
+	}
+}
+
+// WithValueAdapters 注册自定义类型适配器，按注册顺序依次尝试，见 ValueAdapter
+func WithValueAdapters(adapters ...ValueAdapter) Option {
+	return func(e *Engine) {
+		e.adapters = append(e.adapters, adapters...)
+	}
+}
+
+// WithBundleVerifier 要求从 Source（以及模板包）加载的文件都带有签名并通过校验，
+// 只有签名有效的模板才会被加载，例如 WithBundleVerifier(Ed25519Verifier(pub))
+func WithBundleVerifier(v BundleVerifier) Option {
+	return func(e *Engine) {
+		e.verifier = v
+	}
+}
+
+// WithGenerators 替换 uuid() / nowUTC() 内置函数的取值来源，例如测试中
+// WithGenerators(Generators{UUID: SequentialUUIDs(), Now: FixedNow(t)}) 使渲染结果稳定
+func WithGenerators(g Generators) Option {
+	return func(e *Engine) {
+		e.generators = g
+	}
+}
+
+// WithDeterministic 确定性模式，用于 golden 文件测试：uuid() 按 seed 生成固定序列，
+// nowUTC() 总是返回 now，@for 遍历 map 时按键排序，使复杂模板的渲染结果在多次运行之间保持一致
+func WithDeterministic(seed int64, now time.Time) Option {
+	return func(e *Engine) {
+		e.deterministic = true
+		e.generators = Generators{UUID: SeededUUIDs(seed), Now: FixedNow(now)}
+	}
+}
+
+// WithTemplateLimits 设置模板结构限制（@define / @for 嵌套层数、@use 链长度），默认为 DefaultTemplateLimits
+func WithTemplateLimits(l TemplateLimits) Option {
+	return func(e *Engine) {
+		e.limits = l
+	}
+}
+
+// WithoutReferenceValidation 加载时不校验模板间的引用（如 @cover 的目标 define 是否存在），
+// 用于逐步迁移已有的模板，可以配合 AnalyzeDefines 找出有问题的引用
+func WithoutReferenceValidation() Option {
+	return func(e *Engine) {
+		e.skipRefCheck = true
+	}
+}
+
+// WithArgValidation 渲染前校验参数（GetSql、DryRun、Executor 等），不满足时返回 *ValidationError（错误码 GOSQL021）：
+// 模板无条件引用的参数必须存在，结构体参数按字段的 validate 标签校验（required、min=N、max=N、len=N、oneof=a b），
+// 然后依次调用 validators
+func WithArgValidation(validators ...ArgValidator) Option {
+	return func(e *Engine) {
+		e.argCheck = true
+		e.validators = append(e.validators, validators...)
+	}
+}
+
+// WithArgCoercion 渲染前把 map[string]interface{} 参数（通常来自 JSON：数字为 float64、布尔值可能是字符串）
+// 转换为模板声明的类型（描述中的 param: 元数据，如 "param: id int"），转换失败时返回 GOSQL021 错误。
+// 只转换被渲染模板自身声明的参数
+func WithArgCoercion() Option {
+	return func(e *Engine) {
+		e.coerce = true
+	}
+}
+
+// WithWatchInterval 设置 Watch 合并文件变化事件的间隔（收到事件后等待这么久再重新加载），默认 100ms
+func WithWatchInterval(d time.Duration) Option {
+	return func(e *Engine) {
+		e.watchInterval = d
+	}
+}
+
+// WithMemoSize 设置标记为 pure 的模板（描述中的 "pure: true" 元数据）的渲染结果缓存容量，
+// 默认为 DefaultMemoSize，size <= 0 时不缓存
+func WithMemoSize(size int) Option {
+	return func(e *Engine) {
+		if size <= 0 {
+			e.memo = nil
+			return
+		}
+		e.memo = newMemoCache(size)
+	}
+}
+
+// WithArgsHasher 设置 pure 模板渲染结果缓存计算参数哈希的规则，用于注册自定义类型的哈希
+// （默认规则下带未导出字段的结构体无法哈希，包含它的参数不缓存）
+func WithArgsHasher(h *ArgsHasher) Option {
+	return func(e *Engine) {
+		e.hasher = h
+	}
+}
+
+// WithMaxConcurrentRenders 限制同时进行的渲染数（GetSql 以及基于它的 Executor、Select 等），
+// 超出时排队等待，避免突发的大量渲染占满 CPU 影响对延迟敏感的服务；排队情况见 RenderMetrics。
+// n <= 0 时不限制
+func WithMaxConcurrentRenders(n int) Option {
+	return func(e *Engine) {
+		if n <= 0 {
+			e.limiter = nil
+			return
+		}
+		e.limiter = &renderLimiter{slots: make(chan struct{}, n)}
+	}
+}
+
+// WithPlaceholders 设置参数占位符的生成方式，例如 DialectPlaceholders(DialectPostgres) 直接生成 $1、$2…，
+// PrefixPlaceholders("@p") 生成 BigQuery 的 @p1、@p2…，不需要渲染后再 Rebind。默认（nil）为 ?。
+// 子查询（Query / Fragment 参数、render 的结果）中的 ? 拼接时按同样的风格重新编号；
+// Rebind、DedupParams、Flatten 等只识别 ?，对这样渲染的结果不再起作用
+func WithPlaceholders(p Placeholders) Option {
+	return func(e *Engine) {
+		e.placeholders = p
+	}
+}
+
+// MissingVarPolicy 模板引用的变量（@name、@=name）不存在时的处理方式
+type MissingVarPolicy int
+
+const (
+	MissingVarError MissingVarPolicy = iota // 返回错误（GOSQL016），默认
+	MissingVarNull                          // @name 绑定 nil，@=name 输出 NULL
+	MissingVarSkip                          // 跳过所在的行，同 @name?
+)
+
+// WithMissingVarPolicy 设置变量不存在时的处理方式，对所有模板生效，
+// 适合从 MyBatis 等迁移、不方便给每个变量加 ? 的项目。只影响 @name 和 @=name，
+// 表达式（@ expr @、@if 等）中不存在的变量仍按表达式的规则报错
+func WithMissingVarPolicy(p MissingVarPolicy) Option {
+	return func(e *Engine) {
+		e.missingVars = p
+	}
+}
+
+// WithEmptySlicePolicy 设置绑定的切片（@ids、@ expr @）为空时的处理方式，默认什么也不输出，
+// id in (@ids) 会渲染为无效的 id in ()。条件行 @ids? 的空切片仍然跳过整行
+func WithEmptySlicePolicy(p EmptySlicePolicy) Option {
+	return func(e *Engine) {
+		e.emptySlices = p
+	}
+}
+
+// WithInListLimit 设置单个 in (...) 列表的元素个数上限，绑定的切片超过上限时
+// id in (@ids) 拆分为 (id in (...) or id in (...))，not in 用 and 连接，参数顺序不变。
+// 上限可以用 DialectInListLimit 获取，例如 Oracle 为 1000；n <= 0 表示不拆分（默认）
+func WithInListLimit(n int) Option {
+	return func(e *Engine) {
+		e.inListLimit = n
+	}
+}
+
+// WithScalarTypes 注册作为单个参数绑定的切片或数组类型（以示例值给出），例如 uuid.UUID（[16]byte）、
+// net.IP，绑定时不再展开为 ?, ?, ...。[]byte、time.Time 和实现 driver.Valuer 的类型无需注册
+func WithScalarTypes(samples ...interface{}) Option {
+	return func(e *Engine) {
+		for _, v := range samples {
+			e.scalarTypes = append(e.scalarTypes, reflect.TypeOf(v))
+		}
+	}
+}
+
+// WithPostgresArrays 把 col in (@ids) 中的切片作为一个数组参数绑定，渲染为 col = ANY(?)
+// （not in 为 col <> ALL(?)），参数个数不再随切片长度变化，适合很长的列表。
+// wrap 把切片转换为驱动接受的数组参数，lib/pq 为 func(v interface{}) interface{} { return pq.Array(v) }，
+// pgx 可以直接绑定切片，传 nil 即可。只对单个变量启用时改用 gosql.Array 或模板内置函数 array
+func WithPostgresArrays(wrap func(interface{}) interface{}) Option {
+	return func(e *Engine) {
+		if wrap == nil {
+			wrap = func(v interface{}) interface{} { return v }
+		}
+		e.arrays = wrap
+	}
+}
+
+// WithFlagProvider 设置 @flag "name" { } @else { } 的开关来源，开关打开时输出第一个分支，否则输出 @else 分支。
+// FlagProvider 收到 GetSqlContext（以及 Executor 的各个方法）传入的 context，可以按请求的用户、租户灰度发布；
+// 不需要 context 时可以用 gosql.StaticFlags 或 gosql.FlagFunc。未设置时所有开关都关闭
+func WithFlagProvider(p FlagProvider) Option {
+	return func(e *Engine) {
+		e.flags = p
+	}
+}
@@ -0,0 +1,123 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Overlay 返回把 overlay 的模板叠加在 e 之上的新引擎：同名模板（namespace.name）使用 overlay 中的版本，
+// overlay 中没有的模板回退到 e。适合白标部署中每个客户只定制少数查询的场景：
+//
+//	tenant := gosql.New(gosql.WithoutReferenceValidation())
+//	tenant.LoadFile("tenants/acme.md")
+//	acme, err := base.Overlay(tenant)
+//
+// 模板之间的 @use / @import 在合并后的模板中解析，e 中的模板引用被覆盖的模板时同样使用 overlay 的版本
+// （overlay 的模板引用 e 中的模板时，overlay 单独加载会因为找不到引用而失败，需要 WithoutReferenceValidation，
+// 引用在合并时按 e 的配置校验）。
+// 新引擎的配置（选项、OnTemplateLoaded 回调）与 e 相同，注册的函数为 e 和 overlay 的合集（同名时 overlay 优先，
+// 之后注册的函数不会同步）。e 或 overlay 重新加载模板后，新引擎随之更新并触发自己的 OnReload 回调，
+// 所以叠加后的引擎应当长期持有（例如每个租户一个），不再使用时调用 Close
+func (e *Engine) Overlay(overlay *Engine) (*Engine, error) {
+	o := e.derive()
+	for name, fn := range overlay.funcs {
+		o.RegisterFunc(name, fn)
+	}
+	o.layers = []*Engine{e, overlay}
+
+	o.mu.Lock()
+	_, err := o.loadLayers()
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	reload := func([]string, error) {
+		o.mu.Lock()
+		changed, err := o.loadLayers()
+		o.mu.Unlock()
+		o.notifyReload(changed, err)
+	}
+	o.detach = []func(){e.OnReload(reload), overlay.OnReload(reload)}
+	return o, nil
+}
+
+// Close 注销 Overlay 注册在底层引擎上的回调，之后叠加的引擎不再随 e 和 overlay 更新（模板保持不变，仍然可以渲染）。
+// 按需创建、用完丢弃的叠加引擎必须调用 Close，否则底层引擎一直引用它。对不是 Overlay 创建的引擎没有作用
+func (e *Engine) Close() {
+	e.hooksMu.Lock()
+	detach := e.detach
+	e.detach = nil
+	e.hooksMu.Unlock()
+	for _, cancel := range detach {
+		cancel()
+	}
+}
+
+// derive 创建与 e 配置相同、不含模板的引擎
+func (e *Engine) derive() *Engine {
+	d := New()
+	for name, fn := range e.funcs {
+		d.RegisterFunc(name, fn)
+	}
+	d.engineConfig = e.engineConfig
+	// 切片只复制到长度，之后各自追加不会互相影响；渲染结果缓存每个引擎各自一份
+	d.onLoaded = d.onLoaded[:len(d.onLoaded):len(d.onLoaded)]
+	d.adapters = d.adapters[:len(d.adapters):len(d.adapters)]
+	d.scalarTypes = d.scalarTypes[:len(d.scalarTypes):len(d.scalarTypes)]
+	d.validators = d.validators[:len(d.validators):len(d.validators)]
+	if e.memo != nil {
+		d.memo = newMemoCache(e.memo.size)
+	}
+	return d
+}
+
+// loadLayers 按顺序合并各层引擎的模板（后面的覆盖前面的）并重新编译，返回新增、变化或删除的模板（调用方持有写锁）
+func (e *Engine) loadLayers() ([]string, error) {
+	templates := make(map[string]*SQLTemplate)
+	for _, layer := range e.layers {
+		layer.mu.RLock()
+		for key, tmpl := range layer.store.templates {
+			templates[key] = tmpl
+		}
+		layer.mu.RUnlock()
+	}
+
+	var changed []string
+	compiled := make(map[string]*TemplateAST, len(templates))
+	for key, tmpl := range templates {
+		ast, err := e.compileTemplate(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		if old, ok := e.compiledAST[key]; !ok || old.contentHash != ast.contentHash {
+			changed = append(changed, key)
+		}
+		compiled[key] = ast
+	}
+	var removed []string
+	for key := range e.compiledAST {
+		if _, ok := compiled[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	if err := e.commitTemplates(compiled); err != nil {
+		return nil, err
+	}
+
+	changed = append(changed, removed...)
+	for _, key := range removed {
+		delete(e.usage, key)
+	}
+	e.store.templates = templates
+	for key := range compiled {
+		if _, ok := e.usage[key]; !ok {
+			e.usage[key] = new(int64)
+		}
+	}
+	e.parseCache.retain(e.compiledAST)
+	e.linkTemplates()
+	e.loadedAt = time.Now()
+	sort.Strings(changed)
+	return changed, nil
+}
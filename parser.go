@@ -12,7 +12,18 @@ type SQLTemplate struct {
 	Name        string                  // 二级标题（SQL 名称）
 	Description string                  // SQL 描述
 	Content     string                  // SQL 模板内容
+	Statements  []string                // 各个 SQL 代码块的内容（同一标题下可以有多个）
+	Tags        []string                // 标签（描述中的 tags: a, b 元数据行）
+	Meta        map[string][]string     // 描述中的元数据行（key: value），同一 key 可出现多次
+	Dialect     Dialect                 // 代码块语言标记对应的方言（如 ```postgresql）
 	Defines     map[string]*DefineBlock // define 块
+	Data        []DataBlock             // 同一标题下的 csv / yaml 数据代码块（用于 seed 模板）
+}
+
+// DataBlock markdown 中的数据代码块（```csv 或 ```yaml）
+type DataBlock struct {
+	Format  string // csv 或 yaml
+	Content string
 }
 
 // DefineBlock 表示一个 define 代码块
@@ -46,100 +57,312 @@ func (ts *TemplateStore) Set(key string, t *SQLTemplate) {
 }
 
 // ParseMarkdown 解析 markdown 文件内容，提取 SQL 模板
-func ParseMarkdown(content string) ([]*SQLTemplate, error) {
-	var templates []*SQLTemplate
-	scanner := bufio.NewScanner(strings.NewReader(content))
+// 支持 ```sql 以及 ```mysql / ```postgresql 等带方言的代码块，
+// 支持多于三个反引号（或 ~~~）的代码块，以及位于引用块（>）或缩进中的代码块。
+// 同一个二级标题下的所有非 SQL 文本（无论在代码块之前、之间还是之后）都作为描述；
+// 同一标题下有多个 SQL 代码块时，按顺序作为多条语句（以 ; 分隔）合并为一个模板。
+// 对任意输入都不会 panic，输入大小受 DefaultParseLimits.MaxSize 限制
+func ParseMarkdown(content string) (templates []*SQLTemplate, err error) {
+	defer recoverParse(&err)
+	if err := DefaultParseLimits.checkSize(content); err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(normalizeSource(content)))
 
 	var currentNamespace string
 	var currentName string
+	var currentDialect Dialect
 	var currentDesc strings.Builder
 	var sqlContent strings.Builder
-	var inSQLBlock bool
+	var statements []string // 当前标题下已结束的 SQL 代码块
+	var data []DataBlock    // 当前标题下的数据代码块
+	var dataContent strings.Builder
+	var fence *fenceInfo // 当前所在的代码块，nil 表示不在代码块中
 	var lineNum int
 
+	// endStatement 结束当前 SQL 代码块
+	endStatement := func() {
+		if stmt := strings.TrimSpace(sqlContent.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		sqlContent.Reset()
+	}
+
+	// flush 保存当前标题下的 SQL 模板（如果有）
+	flush := func() {
+		endStatement()
+		if currentName != "" && len(statements) > 0 {
+			desc, meta := extractMetadata(currentDesc.String())
+			templates = append(templates, &SQLTemplate{
+				Namespace:   currentNamespace,
+				Name:        currentName,
+				Description: desc,
+				Content:     joinStatements(statements),
+				Statements:  statements,
+				Tags:        splitList(meta["tags"]),
+				Meta:        meta,
+				Dialect:     currentDialect,
+				Defines:     make(map[string]*DefineBlock),
+				Data:        data,
+			})
+		}
+		currentDialect = DialectDefault
+		currentDesc.Reset()
+		statements = nil
+		data = nil
+	}
+
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
 
-		// 检测一级标题（命名空间）
-		if strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "## ") {
-			currentNamespace = strings.TrimSpace(strings.TrimPrefix(line, "# "))
-			currentName = ""
-			currentDesc.Reset()
-			continue
+		// 代码块内部：检测结束，或收集内容
+		// 非 SQL 代码块（包括其起止标记）按普通描述文本处理
+		inFence := fence != nil
+		if inFence {
+			body := stripBlockquote(line, fence.quoteDepth)
+			isSQL := fence.isSQL
+			if fence.isClose(body) {
+				if fence.data != "" && currentName != "" {
+					data = append(data, DataBlock{Format: fence.data, Content: dataContent.String()})
+				}
+				fence = nil
+				if isSQL {
+					endStatement()
+					continue
+				}
+			} else if isSQL {
+				if sqlContent.Len() > 0 {
+					sqlContent.WriteString("\n")
+				}
+				sqlContent.WriteString(trimIndent(body, fence.indent))
+				continue
+			} else if fence.data != "" {
+				// 数据代码块同时保留在描述中
+				dataContent.WriteString(trimIndent(body, fence.indent))
+				dataContent.WriteString("\n")
+			}
 		}
 
-		// 检测二级标题（SQL 名称）
-		if strings.HasPrefix(line, "## ") {
-			// 保存之前的 SQL 模板（如果有）
-			if currentName != "" && sqlContent.Len() > 0 {
-				templates = append(templates, &SQLTemplate{
-					Namespace:   currentNamespace,
-					Name:        currentName,
-					Description: strings.TrimSpace(currentDesc.String()),
-					Content:     strings.TrimSpace(sqlContent.String()),
-					Defines:     make(map[string]*DefineBlock),
-				})
+		if !inFence {
+			// 检测一级标题（命名空间）
+			if strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "## ") {
+				flush()
+				currentNamespace = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+				currentName = ""
+				continue
 			}
 
-			currentName = strings.TrimSpace(strings.TrimPrefix(line, "## "))
-			currentDesc.Reset()
-			sqlContent.Reset()
-			inSQLBlock = false
-			continue
-		}
+			// 检测二级标题（SQL 名称）
+			if strings.HasPrefix(line, "## ") {
+				// 保存之前的 SQL 模板（如果有）
+				flush()
+				currentName = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+				continue
+			}
 
-		// 检测 SQL 代码块开始
-		if strings.HasPrefix(strings.TrimSpace(line), "```sql") {
-			if currentNamespace == "" {
-				return nil, fmt.Errorf("line %d: SQL block found without namespace (missing # heading)", lineNum)
+			// 检测代码块开始
+			if f := parseFenceOpen(line); f != nil {
+				fence = f
+				dataContent.Reset()
+				if f.isSQL {
+					if currentNamespace == "" {
+						return nil, codeError(CodeMarkdown, "line %d: SQL block found without namespace (missing # heading)", lineNum)
+					}
+					if currentName == "" {
+						return nil, codeError(CodeMarkdown, "line %d: SQL block found without name (missing ## heading)", lineNum)
+					}
+					if len(statements) == 0 {
+						currentDialect = f.dialect
+					} else if f.dialect != DialectDefault && f.dialect != currentDialect {
+						return nil, codeError(CodeMarkdown, "line %d: SQL block dialect %q conflicts with %q in template %s", lineNum, f.dialect, currentDialect, currentName)
+					}
+					continue
+				}
 			}
-			if currentName == "" {
-				return nil, fmt.Errorf("line %d: SQL block found without name (missing ## heading)", lineNum)
+		}
+
+		// 收集描述（二级标题下、SQL 代码块之外的所有文本）
+		if currentName != "" {
+			if currentDesc.Len() > 0 {
+				currentDesc.WriteString("\n")
 			}
-			inSQLBlock = true
-			continue
+			currentDesc.WriteString(line)
 		}
+	}
+
+	// 保存最后一个 SQL 模板
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan error: %w", err)
+	}
+
+	return templates, nil
+}
 
-		// 检测代码块结束
-		if strings.TrimSpace(line) == "```" && inSQLBlock {
-			inSQLBlock = false
+// metadataKeys 描述中可识别的元数据 key
+// 形如 "tags: a, b" 的行会从描述中移除，记录到模板的 Meta 中
+var metadataKeys = map[string]bool{
+	"tags":      true,
+	"assert":    true,
+	"migration": true,
+	"seed":      true,
+	"param":     true,
+	"pure":      true,
+}
+
+// extractMetadata 从描述中提取元数据行，返回剩余的描述文本和元数据
+func extractMetadata(desc string) (string, map[string][]string) {
+	meta := make(map[string][]string)
+	var sb strings.Builder
+	for _, line := range strings.Split(desc, "\n") {
+		if key, value, ok := parseMetadataLine(line); ok {
+			meta[key] = append(meta[key], value)
 			continue
 		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(line)
+	}
+	return strings.TrimSpace(sb.String()), meta
+}
 
-		// 收集 SQL 内容
-		if inSQLBlock {
-			if sqlContent.Len() > 0 {
-				sqlContent.WriteString("\n")
-			}
-			sqlContent.WriteString(line)
-		} else if currentName != "" && !inSQLBlock {
-			// 收集描述（在二级标题之后、SQL块之前）
-			if sqlContent.Len() == 0 {
-				if currentDesc.Len() > 0 {
-					currentDesc.WriteString("\n")
-				}
-				currentDesc.WriteString(line)
+// parseMetadataLine 解析元数据行（key: value），key 必须是已知的元数据 key
+func parseMetadataLine(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	// 兼容写在列表项中的元数据：- tags: a, b
+	trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "- "))
+	idx := strings.Index(trimmed, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	key = strings.ToLower(strings.TrimSpace(trimmed[:idx]))
+	if !metadataKeys[key] {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(trimmed[idx+1:]), true
+}
+
+// splitList 将逗号分隔的元数据值拆分为列表
+func splitList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
 			}
 		}
 	}
+	return items
+}
 
-	// 保存最后一个 SQL 模板
-	if currentName != "" && sqlContent.Len() > 0 {
-		templates = append(templates, &SQLTemplate{
-			Namespace:   currentNamespace,
-			Name:        currentName,
-			Description: strings.TrimSpace(currentDesc.String()),
-			Content:     strings.TrimSpace(sqlContent.String()),
-			Defines:     make(map[string]*DefineBlock),
-		})
+// joinStatements 将多个 SQL 代码块按顺序合并为一个模板内容，语句之间以 ; 分隔
+func joinStatements(statements []string) string {
+	if len(statements) == 1 {
+		return statements[0]
 	}
+	var sb strings.Builder
+	for i, stmt := range statements {
+		if i < len(statements)-1 {
+			sb.WriteString(strings.TrimRight(stmt, "; \t\n"))
+			sb.WriteString(";\n")
+		} else {
+			sb.WriteString(stmt)
+		}
+	}
+	return sb.String()
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan error: %w", err)
+// fenceInfo 代码块信息
+type fenceInfo struct {
+	char       byte    // ` 或 ~
+	length     int     // 开始标记的长度（结束标记不能短于它）
+	indent     int     // 开始标记的缩进（内容行会去掉同样多的前导空格）
+	quoteDepth int     // 所在引用块（>）的层数
+	isSQL      bool    // 是否为 SQL 模板代码块
+	dialect    Dialect // SQL 方言
+	data       string  // 数据代码块的格式（csv / yaml），其它代码块为空
+}
+
+// parseFenceOpen 解析代码块开始标记，不是代码块开始时返回 nil
+func parseFenceOpen(line string) *fenceInfo {
+	depth := 0
+	for {
+		rest := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(rest, ">") {
+			break
+		}
+		line = strings.TrimPrefix(rest[1:], " ")
+		depth++
 	}
 
-	return templates, nil
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	rest := line[indent:]
+	if rest == "" || (rest[0] != '`' && rest[0] != '~') {
+		return nil
+	}
+	ch := rest[0]
+	n := 0
+	for n < len(rest) && rest[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return nil
+	}
+	info := strings.TrimSpace(rest[n:])
+	if ch == '`' && strings.ContainsRune(info, '`') {
+		return nil
+	}
+
+	f := &fenceInfo{char: ch, length: n, indent: indent, quoteDepth: depth}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		f.dialect, f.isSQL = DialectForFence(fields[0])
+		switch strings.ToLower(fields[0]) {
+		case "csv":
+			f.data = "csv"
+		case "yaml", "yml":
+			f.data = "yaml"
+		}
+	}
+	return f
+}
+
+// isClose 判断（已去掉引用标记的）行是否为该代码块的结束标记
+func (f *fenceInfo) isClose(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < f.length {
+		return false
+	}
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] != f.char {
+			return false
+		}
+	}
+	return true
+}
+
+// stripBlockquote 去掉行首最多 depth 层引用标记（>）
+func stripBlockquote(line string, depth int) string {
+	for i := 0; i < depth; i++ {
+		rest := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(rest, ">") {
+			break
+		}
+		line = strings.TrimPrefix(rest[1:], " ")
+	}
+	return line
+}
+
+// trimIndent 去掉行首最多 n 个空格
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return line[i:]
 }
 
 // LoadMarkdown 加载 markdown 内容到模板存储
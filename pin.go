@@ -0,0 +1,272 @@
+package gosql
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// pinnedTemplate 常驻模板（Engine.Pin）预先准备的数据，模板重新加载后重建
+type pinnedTemplate struct {
+	ast      *TemplateAST
+	static   map[string]Query    // 不含动态节点的模板 / define（key 为 define 名，整个模板为 ""）的渲染结果
+	exprs    map[string]fastExpr // 模板（包括 @use 引用的模板）中可以快速求值的表达式
+	contexts sync.Pool           // 复用的执行上下文
+}
+
+// Pin 把模板（"namespace.name"）标记为常驻：预先渲染不含动态节点的模板和 define，
+// 预先解析表达式（只由变量、字段、字面量、比较和逻辑运算组成的表达式直接求值，不经过解释器），
+// 并为每个模板保留执行上下文的对象池，以内存换取每次渲染最少的工作，
+// 适合调用最频繁的少数查询。模板不存在或表达式有语法错误时返回错误（之前的模板仍然有效）；
+// 模板重新加载后自动重建，被删除的模板再次加载时恢复常驻
+func (e *Engine) Pin(paths ...string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	built := make(map[string]*pinnedTemplate, len(paths))
+	for _, path := range paths {
+		if strings.Count(path, ".") != 1 {
+			return codeError(CodeInvalidPath, "invalid pin path: %s, expected format: namespace.name", path)
+		}
+		ast, ok := e.compiledAST[path]
+		if !ok {
+			return e.templateNotFound(path)
+		}
+		p, err := e.buildPinned(ast, true)
+		if err != nil {
+			return err
+		}
+		built[path] = p
+	}
+	if e.pins == nil {
+		e.pins = make(map[string]*pinnedTemplate)
+	}
+	for path, p := range built {
+		e.pins[path] = p
+	}
+	return nil
+}
+
+// Unpin 取消模板的常驻标记
+func (e *Engine) Unpin(paths ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, path := range paths {
+		delete(e.pins, path)
+	}
+}
+
+// Pinned 返回常驻的模板（已排序）
+func (e *Engine) Pinned() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	paths := make([]string, 0, len(e.pins))
+	for path := range e.pins {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// repin 模板重新加载后重建常驻模板的数据（调用方持有写锁），表达式有语法错误时只是不快速求值
+func (e *Engine) repin() {
+	for path := range e.pins {
+		if ast, ok := e.compiledAST[path]; ok {
+			e.pins[path], _ = e.buildPinned(ast, false)
+		} else {
+			e.pins[path] = nil
+		}
+	}
+}
+
+// buildPinned 为模板准备常驻数据，strict 为 true 时表达式的语法错误返回错误
+func (e *Engine) buildPinned(ast *TemplateAST, strict bool) (*pinnedTemplate, error) {
+	p := &pinnedTemplate{
+		ast:    ast,
+		static: make(map[string]Query),
+		exprs:  make(map[string]fastExpr),
+	}
+	if sql, ok := staticText(ast.Nodes); ok {
+		p.static[""] = e.finishQuery(Query{SQL: sql})
+	}
+	walkNodes(ast.Nodes, func(node Node) {
+		if d, ok := node.(*DefineNode); ok {
+			if sql, ok := staticText(d.Body); ok && findDefine(ast.Nodes, d.Name) == d {
+				p.static[d.Name] = e.finishQuery(Query{SQL: sql})
+			}
+		}
+	})
+
+	visited := make(map[*TemplateAST]bool)
+	var collect func(ast *TemplateAST) error
+	collect = func(ast *TemplateAST) error {
+		if visited[ast] {
+			return nil
+		}
+		visited[ast] = true
+		var err error
+		walkNodes(ast.Nodes, func(node Node) {
+			for _, expr := range nodeExprs(node) {
+				if _, ok := p.exprs[expr]; ok || err != nil {
+					continue
+				}
+				fn, perr := compileExpr(expr)
+				if perr != nil {
+					if strict {
+						err = codeError(CodeSyntax, "template %s.%s: invalid expression %q: %v", ast.Namespace, ast.Name, expr, perr)
+					}
+					continue
+				}
+				p.exprs[expr] = fn
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if ast.refs != nil {
+			for _, path := range ast.refs.uses {
+				parts := strings.SplitN(path, ".", 3)
+				if len(parts) < 2 {
+					continue
+				}
+				if used, ok := e.compiledAST[parts[0]+"."+parts[1]]; ok {
+					if err := collect(used); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if err := collect(ast); err != nil {
+		return nil, err
+	}
+	for expr, fn := range p.exprs {
+		if fn == nil {
+			delete(p.exprs, expr)
+		}
+	}
+	return p, nil
+}
+
+// staticText 节点都是文本时返回拼接的文本
+func staticText(nodes []Node) (string, bool) {
+	var sb strings.Builder
+	for _, node := range nodes {
+		text, ok := node.(*TextNode)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(text.Text)
+	}
+	return sb.String(), true
+}
+
+// nodeExprs 返回节点自身（不包括子节点）求值的表达式
+func nodeExprs(node Node) []string {
+	switch n := node.(type) {
+	case *VarExprNode:
+		return []string{n.Expr}
+	case *RawExprNode:
+		return []string{n.Expr}
+	case *RowsNode:
+		return []string{n.Expr}
+	case *UseNode:
+		return n.Args
+	case *ConditionalLineNode:
+		return []string{n.Condition}
+	case *ForEachNode:
+		return []string{n.List}
+	case *SwitchNode:
+		exprs := []string{n.Expr}
+		for _, c := range n.Cases {
+			exprs = append(exprs, c.Values...)
+		}
+		return exprs
+	case *IfNode:
+		exprs := []string{n.Condition}
+		for _, ei := range n.ElseIf {
+			exprs = append(exprs, ei.Condition)
+		}
+		return exprs
+	}
+	return nil
+}
+
+// pinned 返回模板的常驻数据（模板没有常驻或数据已过期时返回 nil）
+func (e *Engine) pinned(key string, ast *TemplateAST) *pinnedTemplate {
+	if p := e.pins[key]; p != nil && p.ast == ast {
+		return p
+	}
+	return nil
+}
+
+// getContext 从对象池取出执行上下文
+func (p *pinnedTemplate) getContext(engine *Engine, args interface{}) *executionContext {
+	ctx, _ := p.contexts.Get().(*executionContext)
+	if ctx == nil {
+		ctx = &executionContext{
+			scope:  make(map[string]interface{}, len(p.ast.scopeNames)+len(engine.funcs)),
+			covers: make(map[string][]Node),
+		}
+	}
+	ctx.pinned = p
+	ctx.init(engine, args, p.ast)
+	return ctx
+}
+
+// putContext 清空执行上下文后放回对象池
+func (p *pinnedTemplate) putContext(ctx *executionContext) {
+	scope, covers := ctx.scope, ctx.covers
+	for k := range scope {
+		delete(scope, k)
+	}
+	for k := range covers {
+		delete(covers, k)
+	}
+	*ctx = executionContext{scope: scope, covers: covers}
+	p.contexts.Put(ctx)
+}
+
+// renderPinned 同 renderAt，使用常驻模板预先准备的数据
+func (e *Engine) renderPinned(goctx context.Context, p *pinnedTemplate, defineName, key string, args interface{}, depth int) (Query, *TemplateAST, error) {
+	if q, ok := p.static[defineName]; ok {
+		return q, p.ast, nil
+	}
+	if defineName != "" && findDefine(p.ast.Nodes, defineName) == nil {
+		return Query{}, nil, defineNotFound(defineName, key, p.ast.Nodes)
+	}
+
+	ctx := p.getContext(e, args)
+	defer p.putContext(ctx)
+	ctx.goctx = goctx
+	ctx.depth = depth
+	if depth == 0 {
+		ctx.placeholders = e.placeholders
+	}
+	if defineName == "" {
+		ctx.preallocate(&p.ast.sizes)
+	}
+	err := ctx.err
+	if err == nil {
+		err = ctx.executeTemplate(p.ast, key, defineName)
+	}
+	if err == nil {
+		err = ctx.err
+	}
+	if err != nil {
+		return Query{}, nil, err
+	}
+	ctx.endSkip()
+	query := Query{SQL: ctx.sql.take()}
+	if err := ctx.emptyStatement(query.SQL); err != nil {
+		return Query{}, nil, err
+	}
+	if len(ctx.args) > 0 {
+		query.Params = ctx.args
+	}
+	if defineName == "" {
+		p.ast.sizes.observe(len(query.SQL), len(query.Params))
+	}
+	return e.finishQuery(query), p.ast, nil
+}
@@ -0,0 +1,86 @@
+package gosql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Placeholders 参数占位符的生成方式（WithPlaceholders），默认输出 ?。
+// 渲染时每绑定一个参数调用一次，n 为该参数在 Params 中的序号（从 1 开始）
+type Placeholders interface {
+	Placeholder(n int) string
+}
+
+// PlaceholderFunc 把函数转换为 Placeholders
+type PlaceholderFunc func(n int) string
+
+// Placeholder 实现 Placeholders
+func (f PlaceholderFunc) Placeholder(n int) string {
+	return f(n)
+}
+
+// DialectPlaceholders 返回方言的占位符（同 Rebind）：PostgreSQL 为 $1、$2…，SQL Server 为 @p1、@p2…，
+// Oracle 为 :1、:2…，其他方言为 ?
+func DialectPlaceholders(dialect Dialect) Placeholders {
+	return PlaceholderFunc(func(n int) string {
+		return placeholder(dialect, n)
+	})
+}
+
+// PrefixPlaceholders 返回 prefix 加序号的占位符，例如 PrefixPlaceholders("@p") 生成 BigQuery 的 @p1、@p2…，
+// PrefixPlaceholders(":p") 生成命名参数 :p1、:p2…（参数名与序号对应，绑定时自行命名）
+func PrefixPlaceholders(prefix string) Placeholders {
+	return PlaceholderFunc(func(n int) string {
+		return prefix + strconv.Itoa(n)
+	})
+}
+
+// bindArg 输出占位符并绑定一个参数
+func (ctx *executionContext) bindArg(v interface{}) {
+	ctx.argPos = append(ctx.argPos, ctx.sql.Len())
+	ctx.writePlaceholder()
+	ctx.args = append(ctx.args, v)
+	ctx.traceParam()
+}
+
+// writeArgs 输出一段已经包含占位符的 SQL 并追加其中的参数（参数的位置记为这段 SQL 的开头）
+func (ctx *executionContext) writeArgs(sql string, args []interface{}) {
+	start := ctx.sql.Len()
+	ctx.sql.WriteString(sql)
+	ctx.args = append(ctx.args, args...)
+	for range args {
+		ctx.argPos = append(ctx.argPos, start)
+	}
+}
+
+// writePlaceholder 输出下一个参数（追加到 ctx.args 之前调用）的占位符
+func (ctx *executionContext) writePlaceholder() {
+	if ctx.placeholders == nil {
+		ctx.sql.WriteString("?")
+		return
+	}
+	ctx.sql.WriteString(ctx.placeholders.Placeholder(ctx.argBase + len(ctx.args) + 1))
+}
+
+// writeQuery 拼接子查询：SQL 原样输出，其中的 ? 按当前的占位符风格重新生成，参数按顺序追加
+func (ctx *executionContext) writeQuery(q Query) {
+	start := ctx.sql.Len()
+	if ctx.placeholders == nil || !strings.Contains(q.SQL, "?") {
+		ctx.sql.WriteString(q.SQL)
+	} else {
+		n := ctx.argBase + len(ctx.args)
+		for _, t := range scanSQL(q.SQL) {
+			if t.kind == sqlSymbol && t.text == "?" {
+				n++
+				ctx.sql.WriteString(ctx.placeholders.Placeholder(n))
+				continue
+			}
+			ctx.sql.WriteString(t.text)
+		}
+	}
+	for _, p := range q.Params {
+		ctx.args = append(ctx.args, p)
+		ctx.argPos = append(ctx.argPos, start)
+		ctx.traceParam()
+	}
+}
@@ -0,0 +1,92 @@
+package gosql
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Rebind 把 ? 占位符转换为方言的占位符：PostgreSQL 为 $1、$2…，SQL Server 为 @p1、@p2…，
+// Oracle 为 :1、:2…；MySQL、SQLite 和未指定方言时保持 ?。
+// 字符串、引号标识符和注释中的 ? 不会被替换；占位符按出现顺序编号，与 Params 一一对应，参数不变
+func (q Query) Rebind(dialect Dialect) Query {
+	if placeholder(dialect, 1) == "?" {
+		return q
+	}
+	return bindPlaceholders(q, DialectPlaceholders(dialect))
+}
+
+// bindPlaceholders 把 q 中的 ? 按 p 重新生成（规则同 Rebind）
+func bindPlaceholders(q Query, p Placeholders) Query {
+	if !strings.Contains(q.SQL, "?") {
+		return q
+	}
+	var sb strings.Builder
+	sb.Grow(len(q.SQL) + len(q.Params)*2)
+	n := 0
+	for _, t := range scanSQL(q.SQL) {
+		if t.kind == sqlSymbol && t.text == "?" {
+			n++
+			sb.WriteString(p.Placeholder(n))
+			continue
+		}
+		sb.WriteString(t.text)
+	}
+	q.SQL = sb.String()
+	return q
+}
+
+// DedupParams 同 Rebind，并让值相同的参数共用同一个编号（只用于 PostgreSQL、SQL Server、Oracle
+// 这类编号占位符的方言），例如 "a = ? or b = ?" 和参数 [1, 1] 在 PostgreSQL 下转换为 "a = $1 or b = $1" 和 [1]。
+// 不可比较的参数（如 []byte）不合并；? 占位符的方言返回原查询
+func (q Query) DedupParams(dialect Dialect) Query {
+	if placeholder(dialect, 1) == "?" || !strings.Contains(q.SQL, "?") {
+		return q
+	}
+	var sb strings.Builder
+	params := make([]interface{}, 0, len(q.Params))
+	index := make(map[interface{}]int, len(q.Params)) // 参数值 -> 编号
+	i := 0
+	for _, t := range scanSQL(q.SQL) {
+		if t.kind != sqlSymbol || t.text != "?" {
+			sb.WriteString(t.text)
+			continue
+		}
+		if i >= len(q.Params) {
+			// 占位符比参数多：保持原样编号
+			params = append(params, nil)
+			sb.WriteString(placeholder(dialect, len(params)))
+			continue
+		}
+		v := q.Params[i]
+		i++
+		comparable := v == nil || reflect.TypeOf(v).Comparable()
+		if comparable {
+			if n, ok := index[v]; ok {
+				sb.WriteString(placeholder(dialect, n))
+				continue
+			}
+		}
+		params = append(params, v)
+		if comparable {
+			index[v] = len(params)
+		}
+		sb.WriteString(placeholder(dialect, len(params)))
+	}
+	q.SQL = sb.String()
+	q.Params = append(params, q.Params[i:]...)
+	return q
+}
+
+// placeholder 返回方言中第 n 个（从 1 开始）参数的占位符
+func placeholder(dialect Dialect, n int) string {
+	switch dialect {
+	case DialectPostgres:
+		return "$" + strconv.Itoa(n)
+	case DialectSQLServer:
+		return "@p" + strconv.Itoa(n)
+	case DialectOracle:
+		return ":" + strconv.Itoa(n)
+	}
+	return "?"
+}
@@ -0,0 +1,319 @@
+package gosql
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReloadEvent 描述一次文件（重新）加载引起的模板变化，key 为 namespace.name
+type ReloadEvent struct {
+	File    string   // 触发变化的文件
+	Added   []string // 新增的模板
+	Updated []string // 内容或描述发生变化的模板
+	Removed []string // 从文件中删除的模板
+
+	Included []ReloadEvent // 通过 include 先行加载的文件（按加载顺序）
+}
+
+// Keys 返回所有发生变化的模板（已排序）
+func (ev ReloadEvent) Keys() []string {
+	keys := make([]string, 0, len(ev.Added)+len(ev.Updated)+len(ev.Removed))
+	keys = append(keys, ev.Added...)
+	keys = append(keys, ev.Updated...)
+	keys = append(keys, ev.Removed...)
+	for _, inc := range ev.Included {
+		keys = append(keys, inc.Keys()...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Empty 文件内容变化是否没有影响任何模板
+func (ev ReloadEvent) Empty() bool {
+	if len(ev.Added) > 0 || len(ev.Updated) > 0 || len(ev.Removed) > 0 {
+		return false
+	}
+	for _, inc := range ev.Included {
+		if !inc.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFile 加载（或重新加载）一个 markdown 文件。
+// 引擎会记录文件与模板的对应关系，再次加载同一文件时只重新解析该文件中变化的模板，
+// 文件中已删除的模板会被移除，其它文件的模板保持不变。
+// 文件通过 include 声明的依赖（相对该文件所在目录）会先于它加载
+func (e *Engine) LoadFile(path string) (ReloadEvent, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	e.mu.Lock()
+	ev, err := e.loadFileTree(nil, nil, path, nil, make(map[string]bool))
+	e.mu.Unlock()
+	e.notifyReload(ev.Keys(), err)
+	return ev, err
+}
+
+// loadFileTree 先加载 file 的 include 依赖再加载 file 本身。
+// fsys 为 nil 时从操作系统的文件系统读取（file 为绝对路径），否则从 fsys 读取（file 为 fs.FS 中的路径），
+// pre 中有的文件使用预先读取的内容（见 prefetchFiles）。
+// stack 为正在加载的文件（用于检测循环 include），loaded 记录本次已加载的文件，
+// 被多个文件 include 的文件只加载一次
+func (e *Engine) loadFileTree(fsys fs.FS, pre map[string]fileContent, file string, stack []string, loaded map[string]bool) (ReloadEvent, error) {
+	ev := ReloadEvent{File: file}
+	for _, f := range stack {
+		if f == file {
+			return ev, codeError(CodeCycle, "include cycle: %s -> %s", strings.Join(stack, " -> "), file)
+		}
+	}
+	content, includes, err := readFile(fsys, pre, file)
+	if err != nil {
+		return ev, err
+	}
+	for _, inc := range includes {
+		if loaded[inc] {
+			continue
+		}
+		incEv, err := e.loadFileTree(fsys, pre, inc, append(stack, file), loaded)
+		ev.Included = append(ev.Included, incEv)
+		if err != nil {
+			return ev, fmt.Errorf("%s: include %s: %w", file, inc, err)
+		}
+	}
+	loaded[file] = true
+	own, err := e.loadFile(file, string(content))
+	if err != nil {
+		return ev, err
+	}
+	own.Included = ev.Included
+	e.includes[file] = includes
+	return own, nil
+}
+
+// fileContent 预先读取的文件内容或读取错误
+type fileContent struct {
+	content []byte
+	err     error
+}
+
+// readFile 读取文件内容并解析它的 include 依赖：pre 中有该文件时使用预先读取的结果，
+// 否则 fsys 为 nil 时从操作系统的文件系统读取（file 为绝对路径），不为 nil 时从 fsys 读取
+func readFile(fsys fs.FS, pre map[string]fileContent, file string) ([]byte, []string, error) {
+	var content []byte
+	var err error
+	if c, ok := pre[file]; ok {
+		content, err = c.content, c.err
+	} else if fsys == nil {
+		content, err = os.ReadFile(file)
+	} else {
+		content, err = fs.ReadFile(fsys, file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if fsys == nil {
+		return content, resolveIncludes(file, markdownIncludes(string(content))), nil
+	}
+	return content, resolveFSIncludes(file, markdownIncludes(string(content))), nil
+}
+
+// prefetchFiles 从操作系统的文件系统读取 files 以及它们（递归）include 的文件（不需要持有锁），
+// 之后持有写锁加载时不再读取磁盘
+func prefetchFiles(files []string) map[string]fileContent {
+	pre := make(map[string]fileContent, len(files))
+	var read func(file string)
+	read = func(file string) {
+		if _, ok := pre[file]; ok {
+			return
+		}
+		content, includes, err := readFile(nil, nil, file)
+		pre[file] = fileContent{content: content, err: err}
+		for _, inc := range includes {
+			read(inc)
+		}
+	}
+	for _, file := range files {
+		read(file)
+	}
+	return pre
+}
+
+// resolveIncludes 把 include 路径解析为绝对路径（相对路径以 file 所在目录为基准）
+func resolveIncludes(file string, includes []string) []string {
+	paths := make([]string, 0, len(includes))
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(file), inc)
+		}
+		paths = append(paths, filepath.Clean(inc))
+	}
+	return paths
+}
+
+// resolveFSIncludes 把 include 路径解析为 fs.FS 中的路径（相对 file 所在目录）
+func resolveFSIncludes(file string, includes []string) []string {
+	paths := make([]string, 0, len(includes))
+	for _, inc := range includes {
+		paths = append(paths, path.Join(path.Dir(file), inc))
+	}
+	return paths
+}
+
+// Includes 返回通过 LoadFile 加载的文件声明的 include 依赖（绝对路径，按声明顺序）
+func (e *Engine) Includes() map[string][]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	includes := make(map[string][]string, len(e.includes))
+	for file, deps := range e.includes {
+		includes[file] = append([]string(nil), deps...)
+	}
+	return includes
+}
+
+// loadFile 用文件内容替换该文件之前加载的模板，返回变化的模板
+func (e *Engine) loadFile(file, content string) (ReloadEvent, error) {
+	ev := ReloadEvent{File: file}
+	templates, err := ParseMarkdown(content)
+	if err != nil {
+		return ev, fmt.Errorf("%s: %w", file, err)
+	}
+
+	// 先在暂存的副本上解析和校验所有变化的模板，全部成功后再写入，避免文件有错误时留下一半新一半旧的状态
+	owned := make(map[string]bool, len(e.files[file]))
+	for _, key := range e.files[file] {
+		owned[key] = true
+	}
+	seen := make(map[string]bool, len(templates))
+	compiled := make(map[string]*TemplateAST)
+	var keys []string
+	for _, tmpl := range templates {
+		key := tmpl.Namespace + "." + tmpl.Name
+		if seen[key] {
+			return ev, codeError(CodeDuplicateTemplate, "%s: duplicate template %s", file, key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+		if other, ok := e.fileOf[key]; ok && other != file {
+			return ev, codeError(CodeDuplicateTemplate, "%s: template %s already defined in %s", file, key, other)
+		}
+
+		old, exists := e.store.Get(key)
+		if exists && owned[key] && reflect.DeepEqual(old, tmpl) {
+			continue
+		}
+		ast, err := e.compileTemplate(tmpl)
+		if err != nil {
+			return ev, fmt.Errorf("%s: template %s: %w", file, key, err)
+		}
+		compiled[key] = ast
+		if exists {
+			ev.Updated = append(ev.Updated, key)
+		} else {
+			ev.Added = append(ev.Added, key)
+		}
+	}
+
+	staged := make(map[string]*TemplateAST, len(e.compiledAST)+len(compiled))
+	for key, ast := range e.compiledAST {
+		staged[key] = ast
+	}
+	for key, ast := range compiled {
+		staged[key] = ast
+	}
+	for _, key := range e.files[file] {
+		if !seen[key] {
+			delete(staged, key)
+			ev.Removed = append(ev.Removed, key)
+		}
+	}
+	if err := e.commitTemplates(staged); err != nil {
+		return ev, fmt.Errorf("%s: %w", file, err)
+	}
+
+	for _, tmpl := range templates {
+		key := tmpl.Namespace + "." + tmpl.Name
+		if _, ok := compiled[key]; ok {
+			e.store.Set(key, tmpl)
+			if _, ok := e.usage[key]; !ok {
+				e.usage[key] = new(int64)
+			}
+		}
+		e.fileOf[key] = file
+	}
+	for _, key := range ev.Removed {
+		delete(e.store.templates, key)
+		delete(e.usage, key)
+		delete(e.fileOf, key)
+	}
+	e.files[file] = keys
+	e.parseCache.retain(e.compiledAST)
+	e.linkTemplates()
+	e.loadedAt = time.Now()
+
+	sort.Strings(ev.Added)
+	sort.Strings(ev.Updated)
+	sort.Strings(ev.Removed)
+	return ev, nil
+}
+
+// Files 返回通过 LoadFile 加载的文件及其包含的模板
+func (e *Engine) Files() map[string][]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	files := make(map[string][]string, len(e.files))
+	for file, keys := range e.files {
+		files[file] = append([]string(nil), keys...)
+	}
+	return files
+}
+
+// reloadHook OnReload 注册的回调（以指针区分，便于注销）
+type reloadHook struct {
+	fn func(changed []string, err error)
+}
+
+// OnReload 注册模板变化的回调，用于让应用层缓存（预编译语句、结果缓存等）精确失效。
+// 每次 LoadMarkdown / LoadFile 之后调用：changed 为新增、修改或删除的模板 key（已排序），
+// 加载失败时 err 不为 nil（此时 changed 为空）。
+// 没有任何模板变化且没有错误时不会触发回调。返回的 cancel 注销回调，生命周期比引擎短的对象应当在不再使用时调用
+func (e *Engine) OnReload(fn func(changed []string, err error)) (cancel func()) {
+	hook := &reloadHook{fn: fn}
+	e.hooksMu.Lock()
+	e.onReload = append(e.onReload, hook)
+	e.hooksMu.Unlock()
+	return func() {
+		e.hooksMu.Lock()
+		defer e.hooksMu.Unlock()
+		for i, h := range e.onReload {
+			if h == hook {
+				e.onReload = append(e.onReload[:i:i], e.onReload[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notifyReload 通知所有 OnReload 回调
+func (e *Engine) notifyReload(changed []string, err error) {
+	if len(changed) == 0 && err == nil {
+		return
+	}
+	if err != nil {
+		changed = nil
+	}
+	e.hooksMu.Lock()
+	hooks := e.onReload
+	e.hooksMu.Unlock()
+	for _, h := range hooks {
+		h.fn(changed, err)
+	}
+}
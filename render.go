@@ -0,0 +1,49 @@
+package gosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRenderDepth render 嵌套调用的最大深度（防止模板互相 render 造成无限递归）
+const maxRenderDepth = 32
+
+// renderFunc 内置函数 render(path, args...)：在表达式中渲染另一个模板（或 define），返回 Query。
+// 不传 args 时使用当前模板的变量；传多个 args 时按 Scopes(args...) 组合（前面的优先）。
+// 在 @= ... @ 中直接输出时，Query 的 SQL 原样拼接、参数按顺序追加；
+// 也可以只取其中一部分，例如 @= render("user.columns").SQL @
+func (ctx *executionContext) renderFunc() interface{} {
+	return func(path string, args ...interface{}) Query {
+		if ctx.err != nil {
+			return Query{}
+		}
+		if ctx.depth >= maxRenderDepth {
+			ctx.fail(codeError(CodeTemplateLimit, "render %s: max render depth %d exceeded", path, maxRenderDepth))
+			return Query{}
+		}
+		var scope interface{}
+		switch len(args) {
+		case 0:
+			scope = mapScope(ctx.scope)
+		case 1:
+			scope = args[0]
+		default:
+			scope = Scopes(args...)
+		}
+		query, _, err := ctx.engine.renderAt(ctx.goctx, path, scope, true, ctx.depth+1, nil, ctx.trace)
+		if err != nil {
+			ctx.fail(fmt.Errorf("render %s: %w", path, err))
+			return Query{}
+		}
+		return query
+	}
+}
+
+// executeQueryValue 在 @= @ 中输出 Query：拼接 SQL 并追加参数
+func (ctx *executionContext) executeQueryValue(q Query, conditional bool) {
+	if conditional && strings.TrimSpace(q.SQL) == "" {
+		ctx.skipCurrentLine()
+		return
+	}
+	ctx.writeQuery(q)
+}
@@ -0,0 +1,106 @@
+package gosql
+
+import "reflect"
+
+// RenderContext 渲染过程中提供给扩展的上下文：自定义指令（CustomNode）、代码块函数，
+// 以及第一个参数为 *RenderContext 的注册函数都可以通过它输出 SQL、绑定参数和查找变量。
+// 只在调用期间有效，不能保存到调用结束之后使用
+type RenderContext struct {
+	ctx  *executionContext
+	node *DirectiveNode // 正在执行的自定义指令，注册函数中为 nil
+}
+
+// renderContextType *RenderContext 的类型，用于识别接受渲染上下文的注册函数
+var renderContextType = reflect.TypeOf((*RenderContext)(nil))
+
+// WriteSQL 原样输出 SQL 文本
+func (r *RenderContext) WriteSQL(sql string) {
+	r.ctx.sql.WriteString(sql)
+}
+
+// BindParam 输出占位符并追加参数，规则与 @var 相同（切片展开为多个占位符，Query / Fragment 作为子查询拼接）。
+// 占位符与模板中的 @var 一致（按 WithPlaceholders 的风格生成），扩展中不要自己拼 $1、:1
+func (r *RenderContext) BindParam(value interface{}) {
+	r.ctx.appendArg(value)
+}
+
+// Lookup 查找当前作用域中的变量（包括循环变量和 define 的形参）
+func (r *RenderContext) Lookup(name string) (interface{}, bool) {
+	if value, ok := r.ctx.scope[name]; ok {
+		return value, true
+	}
+	// Scope 参数只展开了模板中静态引用的名字，扩展使用的名字在这里按需查找
+	if s, ok := r.ctx.scopeObj.(Scope); ok {
+		if value, ok := s.Lookup(name); ok {
+			return r.ctx.engine.scopeValue(value), true
+		}
+	}
+	return nil, false
+}
+
+// Eval 在当前作用域中对表达式求值
+func (r *RenderContext) Eval(expr string) (interface{}, error) {
+	return r.ctx.evalExpr(expr)
+}
+
+// Dialect 返回正在执行的模板的方言（@use 引用的模板中为被引用模板的方言）
+func (r *RenderContext) Dialect() Dialect {
+	if r.ctx.tmpl == nil {
+		return DialectDefault
+	}
+	return r.ctx.tmpl.Dialect
+}
+
+// TemplatePath 返回正在执行的模板（namespace.name），@use 引用的模板中为被引用的模板
+func (r *RenderContext) TemplatePath() string {
+	if r.ctx.tmpl == nil {
+		return ""
+	}
+	return r.ctx.tmpl.Namespace + "." + r.ctx.tmpl.Name
+}
+
+// Body 在当前位置执行自定义指令的 { } 块（没有块或不在指令中时什么也不做）
+func (r *RenderContext) Body() error {
+	if r.node == nil {
+		return nil
+	}
+	return r.ctx.executeNodes(r.node.Body)
+}
+
+// CaptureBody 执行自定义指令的 { } 块，返回它输出的 SQL 和参数（不写入当前输出），用于对块内容做处理后再输出
+func (r *RenderContext) CaptureBody() (string, []interface{}, error) {
+	if r.node == nil {
+		return "", nil, nil
+	}
+	return r.ctx.captureNodes(r.node.Body)
+}
+
+// acceptsRenderContext 函数的第一个参数是否为 *RenderContext
+func acceptsRenderContext(fn interface{}) bool {
+	t := reflect.TypeOf(fn)
+	return t != nil && t.Kind() == reflect.Func && t.NumIn() > 0 && t.In(0) == renderContextType
+}
+
+// bindRenderContext 把第一个参数为 *RenderContext 的函数包装为绑定了当前执行上下文、不带该参数的函数，
+// 模板中按去掉第一个参数后的签名调用
+func (ctx *executionContext) bindRenderContext(fn interface{}) interface{} {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	in := make([]reflect.Type, ft.NumIn()-1)
+	for i := range in {
+		in[i] = ft.In(i + 1)
+	}
+	out := make([]reflect.Type, ft.NumOut())
+	for i := range out {
+		out[i] = ft.Out(i)
+	}
+	rc := reflect.ValueOf(&RenderContext{ctx: ctx})
+	wrapped := reflect.MakeFunc(reflect.FuncOf(in, out, ft.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{rc}, args...)
+		if ft.IsVariadic() {
+			return fv.CallSlice(args)
+		}
+		return fv.Call(args)
+	})
+	return wrapped.Interface()
+}
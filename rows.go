@@ -0,0 +1,111 @@
+package gosql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// rowsPattern 批量插入的写法 @rows(a, b)：变量名后的括号中全部是列名
+var rowsPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\(\s*([A-Za-z_][A-Za-z0-9_]*(?:\s*,\s*[A-Za-z_][A-Za-z0-9_]*)*)\s*\)$`)
+
+// parseRows 识别 @rows(a, b) 形式的表达式，不匹配时返回 nil
+func parseRows(expr string) *RowsNode {
+	m := rowsPattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return nil
+	}
+	columns := strings.Split(m[2], ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	return &RowsNode{Name: m[1], Columns: columns, Expr: expr}
+}
+
+// executeRows 执行批量插入节点：变量是切片时每个元素输出一组 (?, ?)，以 ", " 连接，参数按行、列的顺序追加；
+// 变量不存在或是函数时按普通表达式（函数调用）执行
+func (ctx *executionContext) executeRows(n *RowsNode) error {
+	value, ok := ctx.scope[n.Name]
+	rv := reflect.ValueOf(value)
+	if !ok || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return ctx.executeVarExprNode(&VarExprNode{Expr: n.Expr})
+	}
+	if rv.Len() == 0 {
+		return fmt.Errorf("%s: no rows to insert", n.Expr)
+	}
+	ctx.tracef("rows", "%s: %d rows", n.Expr, rv.Len())
+
+	var columns rowColumns
+	for i := 0; i < rv.Len(); i++ {
+		row := rv.Index(i)
+		for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+			if row.IsNil() {
+				return fmt.Errorf("%s: row %d is nil", n.Expr, i)
+			}
+			row = row.Elem()
+		}
+		if i > 0 {
+			ctx.sql.WriteString(", ")
+		}
+		ctx.sql.WriteString("(")
+		for j, column := range n.Columns {
+			v, err := columns.value(row, column)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %w", n.Expr, i, err)
+			}
+			if j > 0 {
+				ctx.sql.WriteString(", ")
+			}
+			ctx.bindArg(ctx.engine.paramValue(v))
+		}
+		ctx.sql.WriteString(")")
+	}
+	return nil
+}
+
+// rowColumns 按行的类型缓存结构体字段的匹配结果
+type rowColumns struct {
+	typ    reflect.Type
+	fields map[string][]int
+}
+
+// value 取一行中某列的值：map 按键（键为字符串）查找，结构体按 db 标签、字段名、忽略大小写的字段名、
+// snake_case 的顺序匹配字段（同 Select 扫描结果的规则）
+func (c *rowColumns) value(row reflect.Value, column string) (interface{}, error) {
+	switch row.Kind() {
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported row type %s", row.Type())
+		}
+		v := row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+		if !v.IsValid() {
+			return nil, fmt.Errorf("missing column %s", column)
+		}
+		return v.Interface(), nil
+	case reflect.Struct:
+		if c.typ != row.Type() {
+			c.typ = row.Type()
+			c.fields = make(map[string][]int)
+		}
+		index, ok := c.fields[column]
+		if !ok {
+			fields, groups := structFields(row.Type())
+			m := &fieldMatcher{fields: fields, groups: groups[:1], used: make(map[int]bool)}
+			if f := m.find(column, 0); f != nil {
+				index = f.index
+			}
+			c.fields[column] = index
+		}
+		if index == nil {
+			return nil, fmt.Errorf("missing column %s in %s", column, row.Type())
+		}
+		v, err := row.FieldByIndexErr(index)
+		if err != nil {
+			// 嵌入的 nil 指针
+			return nil, nil
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported row type %s", row.Type())
+}
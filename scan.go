@@ -0,0 +1,440 @@
+package gosql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ColumnMapper 在匹配结构体字段之前改写列名，例如去掉 u_ 前缀、把 snake_case 转为 CamelCase
+type ColumnMapper func(column string) string
+
+// FieldConverter 把数据库返回的值转换为字段类型的值（用于自定义类型、枚举等）
+type FieldConverter func(src interface{}) (interface{}, error)
+
+// StripPrefix 去掉列名的前缀（如 "u_"），没有该前缀的列保持不变
+func StripPrefix(prefix string) ColumnMapper {
+	return func(column string) string {
+		return strings.TrimPrefix(column, prefix)
+	}
+}
+
+// SnakeToCamel 把 snake_case 列名转换为 CamelCase（user_name -> UserName）
+func SnakeToCamel() ColumnMapper {
+	return func(column string) string {
+		var sb strings.Builder
+		upper := true
+		for _, r := range column {
+			if r == '_' {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	}
+}
+
+// Scanner 把查询结果扫描到结构体、切片、map 或基本类型中。
+// 列与字段的匹配规则（按顺序）：`db` 标签、字段名、忽略大小写的字段名、字段名的 snake_case 形式；
+// 匹配之前列名会依次经过注册的 ColumnMapper 改写。没有匹配字段的列会被忽略
+type Scanner struct {
+	mu         sync.RWMutex
+	mappers    []ColumnMapper
+	converters map[reflect.Type]FieldConverter
+	plans      map[scanPlanKey][]*scanField // (类型, 列) -> 字段映射缓存
+}
+
+// scanPlanKey 字段映射缓存的 key
+type scanPlanKey struct {
+	t       reflect.Type
+	columns string
+}
+
+// scanField 列对应的字段（index 为 FieldByIndex 的路径，nil 表示忽略该列）
+type scanField struct {
+	index []int
+	typ   reflect.Type
+}
+
+// NewScanner 创建扫描器
+func NewScanner() *Scanner {
+	return &Scanner{
+		converters: make(map[reflect.Type]FieldConverter),
+		plans:      make(map[scanPlanKey][]*scanField),
+	}
+}
+
+// MapColumns 追加列名映射规则（按注册顺序依次应用）
+func (s *Scanner) MapColumns(mappers ...ColumnMapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mappers = append(s.mappers, mappers...)
+	s.plans = make(map[scanPlanKey][]*scanField)
+}
+
+// RegisterConverter 注册字段类型的转换函数：目标字段为 t 类型时，先把数据库值读为 interface{}，再用 fn 转换
+func (s *Scanner) RegisterConverter(t reflect.Type, fn FieldConverter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 写时复制，扫描时可以不加锁地读取
+	converters := make(map[reflect.Type]FieldConverter, len(s.converters)+1)
+	for k, v := range s.converters {
+		converters[k] = v
+	}
+	converters[t] = fn
+	s.converters = converters
+}
+
+// ScanAll 把所有行扫描到 dest 中，dest 为切片指针（元素可以是结构体、结构体指针、map[string]interface{} 或基本类型）
+func (s *Scanner) ScanAll(rows *sql.Rows, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	slice.Set(slice.Slice(0, 0))
+	for rows.Next() {
+		elem := reflect.New(elemType).Elem()
+		if err := s.scanRow(rows, elem); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return rows.Err()
+}
+
+// ScanOne 把第一行扫描到 dest 中（结构体指针、map 指针或基本类型指针），没有数据时返回 sql.ErrNoRows
+func (s *Scanner) ScanOne(rows *sql.Rows, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("scan: dest must be a non-nil pointer, got %T", dest)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return s.scanRow(rows, rv.Elem())
+}
+
+// scanRow 扫描当前行到 v（可寻址）
+func (s *Scanner) scanRow(rows *sql.Rows, v reflect.Value) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	// 指针元素：分配后扫描到指向的值
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(columns)))
+		}
+		for i, col := range columns {
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			var mv reflect.Value
+			if value == nil {
+				mv = reflect.Zero(v.Type().Elem())
+			} else {
+				mv = reflect.ValueOf(value)
+			}
+			v.SetMapIndex(reflect.ValueOf(col).Convert(v.Type().Key()), mv)
+		}
+		return nil
+
+	case v.Kind() == reflect.Struct && !isScannerType(v):
+		return s.scanStruct(rows, columns, v)
+
+	default:
+		// 基本类型（或实现了 sql.Scanner 的类型）：只取第一列
+		if len(columns) != 1 {
+			return fmt.Errorf("scan: expected 1 column for %s, got %d", v.Type(), len(columns))
+		}
+		return s.scanInto(rows, []reflect.Value{v}, []*scanField{{typ: v.Type()}})
+	}
+}
+
+// scanStruct 按列名把当前行扫描到结构体
+func (s *Scanner) scanStruct(rows *sql.Rows, columns []string, v reflect.Value) error {
+	plan := s.plan(v.Type(), columns)
+	targets := make([]reflect.Value, len(columns))
+	for i, f := range plan {
+		if f != nil {
+			targets[i] = fieldByIndexAlloc(v, f.index)
+		}
+	}
+	return s.scanInto(rows, targets, plan)
+}
+
+// scanInto 把当前行扫描到 targets（无效的 target 表示忽略该列）
+func (s *Scanner) scanInto(rows *sql.Rows, targets []reflect.Value, plan []*scanField) error {
+	s.mu.RLock()
+	converters := s.converters
+	s.mu.RUnlock()
+
+	ptrs := make([]interface{}, len(targets))
+	raws := make([]interface{}, len(targets))
+	for i, target := range targets {
+		switch {
+		case !target.IsValid():
+			ptrs[i] = new(interface{})
+		case converters[target.Type()] != nil:
+			ptrs[i] = &raws[i]
+		default:
+			ptrs[i] = target.Addr().Interface()
+		}
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return err
+	}
+	for i, target := range targets {
+		if !target.IsValid() {
+			continue
+		}
+		convert := converters[target.Type()]
+		if convert == nil {
+			continue
+		}
+		value, err := convert(raws[i])
+		if err != nil {
+			return fmt.Errorf("scan: convert column %d to %s: %w", i, plan[i].typ, err)
+		}
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(target.Type()) {
+			if !rv.Type().ConvertibleTo(target.Type()) {
+				return fmt.Errorf("scan: converter returned %s, want %s", rv.Type(), target.Type())
+			}
+			rv = rv.Convert(target.Type())
+		}
+		target.Set(rv)
+	}
+	return nil
+}
+
+// plan 计算（并缓存）列到结构体字段的映射
+func (s *Scanner) plan(t reflect.Type, columns []string) []*scanField {
+	key := scanPlanKey{t: t, columns: strings.Join(columns, "\x00")}
+	s.mu.RLock()
+	plan, ok := s.plans[key]
+	mappers := s.mappers
+	s.mu.RUnlock()
+	if ok {
+		return plan
+	}
+
+	fields, groups := structFields(t)
+	matcher := &fieldMatcher{fields: fields, groups: groups, used: make(map[int]bool)}
+	plan = make([]*scanField, len(columns))
+	for i, col := range columns {
+		name := col
+		for _, m := range mappers {
+			name = m(name)
+		}
+		plan[i] = matcher.match(name)
+	}
+
+	s.mu.Lock()
+	s.plans[key] = plan
+	s.mu.Unlock()
+	return plan
+}
+
+// structField 可扫描的结构体字段
+type structField struct {
+	name  string
+	tag   string
+	index []int
+	typ   reflect.Type
+	group int // 所属的结构体：0 为顶层（包括嵌入结构体），其它为嵌套结构体字段（见 fieldGroup）
+}
+
+// fieldGroup 嵌套结构体字段（如 Row.User），其字段可以用前缀限定的列名匹配（u.id、user_id）
+type fieldGroup struct {
+	prefixes []string // 可用的列名前缀：db 标签、字段名、字段名的 snake_case
+}
+
+// structFields 列出结构体的导出字段（展开嵌入结构体，外层字段优先），
+// 非嵌入的结构体字段（不包括 time.Time、sql.Null* 等）作为嵌套结构体单独分组
+func structFields(t reflect.Type) ([]structField, []fieldGroup) {
+	var fields []structField
+	groups := []fieldGroup{{}}
+	var walk func(t reflect.Type, index []int, group int)
+	walk = func(t reflect.Type, index []int, group int) {
+		var embedded, nested []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := strings.Split(f.Tag.Get("db"), ",")[0]
+			if tag == "-" {
+				continue
+			}
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			isStruct := ft.Kind() == reflect.Struct && !isScannerType(reflect.New(ft).Elem())
+			if f.Anonymous && tag == "" && isStruct {
+				embedded = append(embedded, f)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if isStruct {
+				nested = append(nested, f)
+				continue
+			}
+			fields = append(fields, structField{
+				name:  f.Name,
+				tag:   tag,
+				index: append(index[:len(index):len(index)], i),
+				typ:   f.Type,
+				group: group,
+			})
+		}
+		for _, f := range embedded {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			walk(ft, append(index[:len(index):len(index)], f.Index...), group)
+		}
+		for _, f := range nested {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			prefixes := []string{f.Name, toSnakeCase(f.Name)}
+			if tag := strings.Split(f.Tag.Get("db"), ",")[0]; tag != "" {
+				prefixes = append([]string{tag}, prefixes...)
+			}
+			groups = append(groups, fieldGroup{prefixes: prefixes})
+			walk(ft, append(index[:len(index):len(index)], f.Index...), len(groups)-1)
+		}
+	}
+	walk(t, nil, 0)
+	return fields, groups
+}
+
+// fieldMatcher 为一行的列依次分配字段，已分配的字段不再重复使用
+type fieldMatcher struct {
+	fields []structField
+	groups []fieldGroup
+	used   map[int]bool
+}
+
+// match 查找列对应的字段：
+//  1. 顶层字段
+//  2. 前缀限定的列名（u.id、u_id、user_id）匹配对应的嵌套结构体字段
+//  3. 没有前缀的列按顺序分配给第一个还有空闲匹配字段的嵌套结构体，
+//     因此 select u.*, o.* 返回的两个 id 列会依次扫描到 Row.User.ID 和 Row.Order.ID
+func (m *fieldMatcher) match(column string) *scanField {
+	if f := m.find(column, 0); f != nil {
+		return f
+	}
+	lower := strings.ToLower(column)
+	for g := 1; g < len(m.groups); g++ {
+		for _, prefix := range m.groups[g].prefixes {
+			p := strings.ToLower(prefix)
+			if len(lower) > len(p)+1 && strings.HasPrefix(lower, p) && (lower[len(p)] == '.' || lower[len(p)] == '_') {
+				if f := m.find(column[len(p)+1:], g); f != nil {
+					return f
+				}
+			}
+		}
+	}
+	for g := 1; g < len(m.groups); g++ {
+		if f := m.find(column, g); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// find 在指定分组中按 db 标签、字段名、忽略大小写的字段名、snake_case 的顺序查找未使用的字段
+func (m *fieldMatcher) find(column string, group int) *scanField {
+	matchers := []func(f structField) bool{
+		func(f structField) bool { return f.tag != "" && f.tag == column },
+		func(f structField) bool { return f.tag == "" && f.name == column },
+		func(f structField) bool { return f.tag == "" && strings.EqualFold(f.name, column) },
+		func(f structField) bool { return f.tag == "" && toSnakeCase(f.name) == strings.ToLower(column) },
+	}
+	for _, match := range matchers {
+		for i, f := range m.fields {
+			if f.group == group && !m.used[i] && match(f) {
+				m.used[i] = true
+				return &scanField{index: f.index, typ: f.typ}
+			}
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc 按路径取字段，遇到 nil 的嵌入指针时自动分配
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// isScannerType 判断类型是否自己实现了 sql.Scanner（如 sql.NullString、time.Time 由驱动直接赋值）
+func isScannerType(v reflect.Value) bool {
+	if v.CanAddr() {
+		if _, ok := v.Addr().Interface().(sql.Scanner); ok {
+			return true
+		}
+	}
+	return v.Type().PkgPath() == "time" && v.Type().Name() == "Time"
+}
+
+// toSnakeCase 把 CamelCase 转为 snake_case（UserID -> user_id）
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+package gosql
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Scope 自定义变量解析：GetSql 的 args 实现了 Scope 时，引擎不再展开 map/结构体，
+// 而是按模板（包括 @use 引用的模板）中引用到的变量名和函数名逐个调用 Lookup。
+// 适用于延迟加载的配置、按请求解析的变量等不方便预先构造成 map 的场景
+type Scope interface {
+	Lookup(name string) (interface{}, bool)
+}
+
+// ScopeFunc 函数形式的 Scope
+type ScopeFunc func(name string) (interface{}, bool)
+
+// Lookup 实现 Scope
+func (f ScopeFunc) Lookup(name string) (interface{}, bool) {
+	return f(name)
+}
+
+// expandScope 按模板引用到的名字从 Scope 中取值放入执行环境
+func (ctx *executionContext) expandScope(s Scope) error {
+	chained, _ := s.(*ChainedScope)
+	for name := range ctx.names {
+		if _, exists := ctx.scope[name]; exists {
+			continue
+		}
+		if chained != nil {
+			value, ok, err := chained.lookupStrict(name)
+			if err != nil {
+				return err
+			}
+			if ok {
+				ctx.scope[name] = ctx.engine.scopeValue(value)
+			}
+			continue
+		}
+		if value, ok := s.Lookup(name); ok {
+			ctx.scope[name] = ctx.engine.scopeValue(value)
+		}
+	}
+	return nil
+}
+
+// ChainedScope 按优先级组合多个 scope，见 Scopes
+type ChainedScope struct {
+	scopes []Scope
+	strict bool
+}
+
+// Scopes 按优先级组合多个 scope（前面的优先），例如 Scopes(args, session, globals)：
+// 请求参数覆盖会话上下文，会话上下文覆盖全局变量。
+// 每一层可以是 Scope、map（key 为 string）或结构体（指针），nil 会被忽略
+func Scopes(layers ...interface{}) *ChainedScope {
+	c := &ChainedScope{}
+	for _, layer := range layers {
+		if s := toScope(layer); s != nil {
+			c.scopes = append(c.scopes, s)
+		}
+	}
+	return c
+}
+
+// Strict 返回严格模式的副本：同一个变量在多层中取值不同时，渲染返回错误而不是静默使用高优先级的值
+func (c *ChainedScope) Strict() *ChainedScope {
+	return &ChainedScope{scopes: c.scopes, strict: true}
+}
+
+// Lookup 实现 Scope，返回第一个包含该变量的 scope 中的值
+func (c *ChainedScope) Lookup(name string) (interface{}, bool) {
+	for _, s := range c.scopes {
+		if v, ok := s.Lookup(name); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookupStrict 查找变量，严格模式下检查各层取值是否冲突
+func (c *ChainedScope) lookupStrict(name string) (interface{}, bool, error) {
+	if !c.strict {
+		v, ok := c.Lookup(name)
+		return v, ok, nil
+	}
+	var value interface{}
+	found := -1
+	for i, s := range c.scopes {
+		v, ok := s.Lookup(name)
+		if !ok {
+			continue
+		}
+		if found < 0 {
+			value, found = v, i
+			continue
+		}
+		if !reflect.DeepEqual(value, v) {
+			return nil, false, codeError(CodeScopeConflict, "scope conflict: %s is %v in scope %d but %v in scope %d", name, value, found, v, i)
+		}
+	}
+	return value, found >= 0, nil
+}
+
+// toScope 把 map、结构体等值转换为 Scope
+func toScope(v interface{}) Scope {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(Scope); ok {
+		return s
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return mapScope(m)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return valueScope{rv}
+		}
+	case reflect.Ptr:
+		if !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+			return valueScope{rv}
+		}
+	case reflect.Struct:
+		// 复制为指针，使指针接收器的方法也可以使用
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return valueScope{ptr}
+	}
+	return nil
+}
+
+// mapScope map[string]interface{} 形式的 Scope
+type mapScope map[string]interface{}
+
+// Lookup 实现 Scope
+func (m mapScope) Lookup(name string) (interface{}, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// valueScope 基于反射的 Scope：map 按 key 查找，结构体按字段（同时支持 Id 和 id）和方法查找
+type valueScope struct {
+	rv reflect.Value
+}
+
+// Lookup 实现 Scope
+func (s valueScope) Lookup(name string) (interface{}, bool) {
+	if s.rv.Kind() == reflect.Map {
+		v := s.rv.MapIndex(reflect.ValueOf(name).Convert(s.rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+	if name == "" {
+		return nil, false
+	}
+	exported := strings.ToUpper(name[:1]) + name[1:]
+	for _, n := range []string{name, exported} {
+		if f := s.rv.Elem().FieldByName(n); f.IsValid() && f.CanInterface() {
+			return f.Interface(), true
+		}
+		if m := s.rv.MethodByName(n); m.IsValid() {
+			return m.Interface(), true
+		}
+	}
+	return nil, false
+}
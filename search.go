@@ -0,0 +1,85 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// SearchResult 模板搜索结果
+type SearchResult struct {
+	Template *TemplateInfo `json:"template"`
+	Score    int           `json:"score"` // 相关度，越大越相关
+}
+
+// 各字段命中时的权重
+const (
+	searchWeightExactName = 20 // 名称或路径完全匹配
+	searchWeightName      = 8
+	searchWeightTag       = 6
+	searchWeightDesc      = 3
+	searchWeightSQL       = 1
+)
+
+// Search 在模板名称、路径、标签、描述和 SQL 文本中搜索
+// query 按空白拆分为多个关键字（不区分大小写），模板需要命中全部关键字；
+// 结果按相关度从高到低排序，相关度相同按路径排序
+func (e *Engine) Search(query string) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []SearchResult
+	for _, info := range e.Templates() {
+		if score := scoreTemplate(info, terms); score > 0 {
+			results = append(results, SearchResult{Template: info, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Template.Path < results[j].Template.Path
+	})
+	return results
+}
+
+// scoreTemplate 计算模板对关键字的相关度，有关键字未命中时返回 0
+func scoreTemplate(info *TemplateInfo, terms []string) int {
+	name := strings.ToLower(info.Name)
+	path := strings.ToLower(info.Path)
+	desc := strings.ToLower(info.Description)
+	sql := strings.ToLower(info.SQL)
+
+	total := 0
+	for _, term := range terms {
+		score := 0
+		if name == term || path == term {
+			score += searchWeightExactName
+		} else if strings.Contains(path, term) {
+			score += searchWeightName
+		}
+		for _, tag := range info.Tags {
+			if strings.Contains(strings.ToLower(tag), term) {
+				score += searchWeightTag
+				break
+			}
+		}
+		if strings.Contains(desc, term) {
+			score += searchWeightDesc
+		}
+		if n := strings.Count(sql, term); n > 0 {
+			// SQL 中多次出现略微加分，但不超过描述的权重
+			if n > searchWeightDesc {
+				n = searchWeightDesc
+			}
+			score += searchWeightSQL * n
+		}
+		if score == 0 {
+			return 0
+		}
+		total += score
+	}
+	return total
+}
@@ -0,0 +1,224 @@
+package gosql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Seeds 返回命名空间中的 seed 模板（描述中带有 seed: true 的模板）路径，按模板名排序，
+// 需要控制执行顺序时可以用 01_users、02_orders 这样的模板名
+func (e *Engine) Seeds(namespace string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var paths []string
+	for key, tmpl := range e.store.templates {
+		if tmpl.Namespace != namespace {
+			continue
+		}
+		if values := tmpl.Meta["seed"]; len(values) > 0 && strings.EqualFold(values[len(values)-1], "true") {
+			paths = append(paths, key)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SeedRows 解析 seed 模板中 csv / yaml 数据代码块的所有行（多个代码块按顺序合并）。
+// csv 的第一行为列名；yaml 为 "- key: value" 形式的列表
+func (e *Engine) SeedRows(path string) ([]map[string]interface{}, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	tmpl, ok := e.store.Get(path)
+	if !ok {
+		return nil, e.templateNotFound(path)
+	}
+	var rows []map[string]interface{}
+	for i, block := range tmpl.Data {
+		var parsed []map[string]interface{}
+		var err error
+		switch block.Format {
+		case "csv":
+			parsed, err = parseCSVRows(block.Content)
+		case "yaml":
+			parsed, err = parseYAMLRows(block.Content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("template %s: data block %d: %w", path, i+1, err)
+		}
+		rows = append(rows, parsed...)
+	}
+	return rows, nil
+}
+
+// parseCSVRows 解析 csv 数据，第一行为列名，值为字符串
+func parseCSVRows(content string) ([]map[string]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	header := records[0]
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, col := range header {
+			row[strings.TrimSpace(col)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseYAMLRows 解析 yaml 数据的一个子集：由扁平映射组成的列表
+//
+//   - id: 1
+//     name: alice
+//   - id: 2
+//     name: "bob"
+func parseYAMLRows(content string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	var row map[string]interface{}
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			row = make(map[string]interface{})
+			rows = append(rows, row)
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if trimmed == "" {
+				continue
+			}
+		}
+		if row == nil {
+			return nil, fmt.Errorf("line %d: expected list item \"- key: value\"", i+1)
+		}
+		idx := strings.Index(trimmed, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+		row[strings.TrimSpace(trimmed[:idx])] = yamlScalar(strings.TrimSpace(trimmed[idx+1:]))
+	}
+	return rows, nil
+}
+
+// yamlScalar 解析 yaml 标量：引号字符串、null、bool、整数、浮点数，其它按字符串处理
+func yamlScalar(s string) interface{} {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"') {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	switch strings.ToLower(s) {
+	case "", "null", "~":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// execer 可以执行 SQL 的连接（*sql.DB 或 *sql.Tx）
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// ExecBatch 用 argsList 中的每一项分别渲染模板并执行，全部在一个事务中完成，返回影响的总行数。
+// 渲染结果 SQL 相同的语句共用同一个预编译语句
+func (x *Executor) ExecBatch(ctx context.Context, path string, argsList []interface{}) (int64, error) {
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := x.execBatch(ctx, tx, path, argsList)
+	if err != nil {
+		tx.Rollback()
+		return n, err
+	}
+	return n, tx.Commit()
+}
+
+// execBatch 在 conn 上批量执行模板
+func (x *Executor) execBatch(ctx context.Context, conn execer, path string, argsList []interface{}) (int64, error) {
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+	var total int64
+	for i, args := range argsList {
+		q, err := x.engine.GetSqlContext(ctx, path, args)
+		if err != nil {
+			return total, fmt.Errorf("%s: row %d: %w", path, i+1, err)
+		}
+		stmt, ok := stmts[q.SQL]
+		if !ok {
+			if stmt, err = conn.PrepareContext(ctx, q.SQL); err != nil {
+				return total, fmt.Errorf("%s: row %d: %w", path, i+1, err)
+			}
+			stmts[q.SQL] = stmt
+		}
+		res, err := stmt.ExecContext(ctx, q.Params...)
+		if err != nil {
+			return total, fmt.Errorf("%s: row %d: %w", path, i+1, err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// Seed 执行命名空间中的所有 seed 模板：每个模板按其数据代码块中的每一行渲染并执行，
+// 全部在一个事务中完成，返回影响的总行数
+func (x *Executor) Seed(ctx context.Context, namespace string) (int64, error) {
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := x.SeedTx(ctx, tx, namespace)
+	if err != nil {
+		tx.Rollback()
+		return n, err
+	}
+	return n, tx.Commit()
+}
+
+// SeedTx 同 Seed，在调用方提供的事务中执行（不会提交或回滚）
+func (x *Executor) SeedTx(ctx context.Context, tx *sql.Tx, namespace string) (int64, error) {
+	var total int64
+	for _, path := range x.engine.Seeds(namespace) {
+		rows, err := x.engine.SeedRows(path)
+		if err != nil {
+			return total, err
+		}
+		argsList := make([]interface{}, len(rows))
+		for i, row := range rows {
+			argsList[i] = row
+		}
+		n, err := x.execBatch(ctx, tx, path, argsList)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("seed %w", err)
+		}
+	}
+	return total, nil
+}
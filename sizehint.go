@@ -0,0 +1,72 @@
+package gosql
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	sizeSamples   = 64 // 参与统计的最近渲染次数
+	sizeRecompute = 16 // 预热之后每渲染多少次重新计算一次 p95
+)
+
+// sizeHints 模板最近渲染出的 SQL 长度和参数个数。渲染前按它们的 p95 预分配 SQL 缓冲和参数切片，
+// 让大多数渲染（包括长度有波动的报表查询）在写入过程中不需要重新分配。
+// 并发渲染时无锁记录：样本和结果都原子读写，偶尔丢失或覆盖一个样本不影响结果
+type sizeHints struct {
+	n         uint32
+	sql       [sizeSamples]int32
+	params    [sizeSamples]int32
+	sqlP95    int32
+	paramsP95 int32
+}
+
+// hint 返回 SQL 长度和参数个数的预分配大小（还没有渲染过时为 0）
+func (h *sizeHints) hint() (sql, params int) {
+	return int(atomic.LoadInt32(&h.sqlP95)), int(atomic.LoadInt32(&h.paramsP95))
+}
+
+// observe 记录一次渲染的结果，前 sizeRecompute 次以及之后每 sizeRecompute 次重新计算 p95
+func (h *sizeHints) observe(sql, params int) {
+	n := atomic.AddUint32(&h.n, 1)
+	i := (n - 1) % sizeSamples
+	atomic.StoreInt32(&h.sql[i], clampInt32(sql))
+	atomic.StoreInt32(&h.params[i], clampInt32(params))
+	if n > sizeRecompute && n%sizeRecompute != 0 {
+		return
+	}
+	count := int(n)
+	if count > sizeSamples {
+		count = sizeSamples
+	}
+	atomic.StoreInt32(&h.sqlP95, percentile95(&h.sql, count))
+	atomic.StoreInt32(&h.paramsP95, percentile95(&h.params, count))
+}
+
+// percentile95 返回前 count 个样本的 p95
+func percentile95(samples *[sizeSamples]int32, count int) int32 {
+	var sorted [sizeSamples]int32
+	for i := 0; i < count; i++ {
+		sorted[i] = atomic.LoadInt32(&samples[i])
+	}
+	s := sorted[:count]
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s[(count*95+99)/100-1]
+}
+
+// preallocate 按模板渲染结果的 p95 预分配 SQL 缓冲和参数切片
+func (ctx *executionContext) preallocate(h *sizeHints) {
+	sql, params := h.hint()
+	ctx.sql.Grow(sql)
+	if params > 0 && ctx.args == nil {
+		ctx.args = make([]interface{}, 0, params)
+	}
+}
+
+// clampInt32 把长度限制在 int32 范围内
+func clampInt32(n int) int32 {
+	if n > 1<<31-1 {
+		return 1<<31 - 1
+	}
+	return int32(n)
+}
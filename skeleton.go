@@ -0,0 +1,97 @@
+package gosql
+
+import "strings"
+
+// skeleton 节点列表编译后的骨架：所有静态文本按顺序拼接为一整段，动态节点作为插槽记录它在文本中的位置。
+// 渲染时按顺序复制文本、在插槽处执行动态节点，不再逐个节点分派；没有插槽的骨架不需要执行上下文
+type skeleton struct {
+	text  string
+	slots []skeletonSlot
+}
+
+// skeletonSlot 骨架中的动态插槽
+type skeletonSlot struct {
+	offset int  // 插槽在静态文本中的位置（执行节点前先输出 text[上一个插槽:offset]）
+	node   Node // 动态节点
+}
+
+// compileSkeleton 把节点列表编译为骨架，相邻的文本节点合并
+func compileSkeleton(nodes []Node) *skeleton {
+	var sb strings.Builder
+	s := &skeleton{}
+	for _, node := range nodes {
+		if text, ok := node.(*TextNode); ok {
+			sb.WriteString(text.Text)
+			continue
+		}
+		s.slots = append(s.slots, skeletonSlot{offset: sb.Len(), node: node})
+	}
+	s.text = sb.String()
+	return s
+}
+
+// static 骨架没有动态插槽时返回静态文本
+func (s *skeleton) static() (string, bool) {
+	return s.text, len(s.slots) == 0
+}
+
+// buildSkeletons 为模板和其中的每个 define（按 findDefine 的查找顺序，同名时取第一个）编译骨架，
+// 在 @import 展开和加载回调之后调用
+func (ast *TemplateAST) buildSkeletons() {
+	ast.skeleton = compileSkeleton(ast.Nodes)
+	ast.defineSkeletons = nil
+	walkNodes(ast.Nodes, func(node Node) {
+		d, ok := node.(*DefineNode)
+		if !ok {
+			return
+		}
+		if _, ok := ast.defineSkeletons[d.Name]; ok || findDefine(ast.Nodes, d.Name) != d {
+			return
+		}
+		if ast.defineSkeletons == nil {
+			ast.defineSkeletons = make(map[string]*skeleton)
+		}
+		ast.defineSkeletons[d.Name] = compileSkeleton(d.Body)
+	})
+}
+
+// skeletonFor 返回模板（defineName 为空）或 define 的骨架，没有编译过时返回 nil
+func (ast *TemplateAST) skeletonFor(defineName string) *skeleton {
+	if defineName == "" {
+		return ast.skeleton
+	}
+	return ast.defineSkeletons[defineName]
+}
+
+// executeSkeleton 按骨架执行：复制静态文本，在插槽处执行动态节点
+func (ctx *executionContext) executeSkeleton(s *skeleton) error {
+	if ctx.sql.Len() == 0 {
+		ctx.sql.Grow(len(s.text))
+	}
+	prev := 0
+	for _, slot := range s.slots {
+		ctx.writeText(s.text[prev:slot.offset])
+		prev = slot.offset
+		if err := ctx.executeNode(slot.node); err != nil {
+			return err
+		}
+	}
+	ctx.writeText(s.text[prev:])
+	return nil
+}
+
+// executeTemplate 执行模板（defineName 不为空时只执行该 define 块），有骨架时按骨架执行
+func (ctx *executionContext) executeTemplate(ast *TemplateAST, key, defineName string) error {
+	if s := ast.skeletonFor(defineName); s != nil {
+		return ctx.executeSkeleton(s)
+	}
+	nodes := ast.Nodes
+	if defineName != "" {
+		define := findDefine(nodes, defineName)
+		if define == nil {
+			return defineNotFound(defineName, key, nodes)
+		}
+		nodes = define.Body
+	}
+	return ctx.executeNodes(nodes)
+}
@@ -0,0 +1,232 @@
+package gosql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NamedContent 模板来源中的一个 markdown 文件，Name 用于标识文件（相当于 LoadFile 的路径）
+type NamedContent struct {
+	Name      string
+	Content   string
+	Signature []byte // 内容的签名，引擎配置了 WithBundleVerifier 时必须提供
+}
+
+// Source 模板来源：从远程服务、对象存储等位置读取 markdown 文件，
+// 便于集中管理的 SQL 模板在不重新部署服务的情况下下发
+type Source interface {
+	Load() ([]NamedContent, error)
+}
+
+// SourceFunc 把函数适配为 Source
+type SourceFunc func() ([]NamedContent, error)
+
+// Load 实现 Source
+func (f SourceFunc) Load() ([]NamedContent, error) {
+	return f()
+}
+
+// HTTPSource 通过 HTTP(S) GET 读取模板文件，按 ETag 缓存：
+// 服务端返回 304 Not Modified 时复用上一次的内容，不重复下载
+type HTTPSource struct {
+	URLs   []string
+	Client *http.Client // 为 nil 时使用 http.DefaultClient
+	// Header 在每个请求发送前调用，可用于添加鉴权头等（为 nil 时不修改请求）
+	Header func(req *http.Request) error
+	// Signatures 为 true 时同时读取每个文件的签名（URL 加 ".sig" 后缀，内容为 base64 编码），
+	// 用于配合 WithBundleVerifier
+	Signatures bool
+
+	mu    sync.Mutex
+	cache map[string]httpEntry // URL -> 上一次的响应
+}
+
+// httpEntry 缓存的 HTTP 响应
+type httpEntry struct {
+	etag    string
+	content string
+}
+
+// NewHTTPSource 创建从给定 URL 读取模板的来源
+func NewHTTPSource(urls ...string) *HTTPSource {
+	return &HTTPSource{URLs: urls}
+}
+
+// Load 实现 Source，任意一个 URL 读取失败时返回错误
+func (s *HTTPSource) Load() ([]NamedContent, error) {
+	contents := make([]NamedContent, 0, len(s.URLs))
+	for _, u := range s.URLs {
+		content, err := s.fetch(u)
+		if err != nil {
+			return nil, err
+		}
+		nc := NamedContent{Name: u, Content: content}
+		if s.Signatures {
+			sig, err := s.fetch(u + ".sig")
+			if err != nil {
+				return nil, err
+			}
+			if nc.Signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(sig)); err != nil {
+				return nil, fmt.Errorf("%s.sig: %w", u, err)
+			}
+		}
+		contents = append(contents, nc)
+	}
+	return contents, nil
+}
+
+// fetch 读取单个 URL，带上缓存的 ETag
+func (s *HTTPSource) fetch(u string) (string, error) {
+	s.mu.Lock()
+	cached, hasCache := s.cache[u]
+	s.mu.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCache && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	if s.Header != nil {
+		if err := s.Header(req); err != nil {
+			return "", err
+		}
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCache:
+		return cached.content, nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%s: unexpected status %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", u, err)
+	}
+	s.mu.Lock()
+	if s.cache == nil {
+		s.cache = make(map[string]httpEntry)
+	}
+	s.cache[u] = httpEntry{etag: resp.Header.Get("ETag"), content: string(body)}
+	s.mu.Unlock()
+	return string(body), nil
+}
+
+// LoadSource 从来源读取所有文件并加载，每个文件按 LoadFile 的规则增量更新（以 Name 作为文件标识）。
+// 读取来源时不持有引擎的锁，渲染不会等待网络请求
+func (e *Engine) LoadSource(src Source) ([]ReloadEvent, error) {
+	contents, err := e.fetchSource(src)
+	if err != nil {
+		e.notifyReload(nil, err)
+		return nil, err
+	}
+	e.mu.Lock()
+	events, _, err := e.loadContents(contents, nil)
+	e.mu.Unlock()
+	e.notifyReload(eventKeys(events), err)
+	return events, err
+}
+
+// eventKeys 返回多个事件中所有变化的模板（已排序）
+func eventKeys(events []ReloadEvent) []string {
+	var keys []string
+	for _, ev := range events {
+		keys = append(keys, ev.Keys()...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fetchSource 读取来源中的文件并校验签名（不需要持有锁）。全部校验通过后再加载，避免只加载了一部分文件
+func (e *Engine) fetchSource(src Source) ([]NamedContent, error) {
+	contents, err := src.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.verifyContents(contents); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// loadContents 加载从来源读取的文件（调用方持有写锁），prev 为上一次从该来源加载的文件：
+// 不再出现的文件中的模板会被移除。返回变化事件和本次加载的文件
+func (e *Engine) loadContents(contents []NamedContent, prev []string) ([]ReloadEvent, []string, error) {
+	var events []ReloadEvent
+	names := make([]string, 0, len(contents))
+	seen := make(map[string]bool, len(contents))
+	for _, c := range contents {
+		ev, err := e.loadFile(c.Name, c.Content)
+		if err != nil {
+			return events, prev, err
+		}
+		seen[c.Name] = true
+		names = append(names, c.Name)
+		if !ev.Empty() {
+			events = append(events, ev)
+		}
+	}
+	for _, name := range prev {
+		if seen[name] {
+			continue
+		}
+		ev, err := e.loadFile(name, "")
+		if err != nil {
+			return events, prev, err
+		}
+		delete(e.files, name)
+		if !ev.Empty() {
+			events = append(events, ev)
+		}
+	}
+	return events, names, nil
+}
+
+// RefreshSource 立即加载一次来源，然后每隔 interval 重新加载，直到 ctx 取消。
+// 来源中删除的文件对应的模板会被移除；模板变化通过 OnReload 通知，加载失败时保留之前的模板并调用 onError（可以为 nil）。
+// 读取来源时不持有锁，只在替换模板时持有引擎的写锁，正在进行的渲染完成后才替换模板，刷新可以与渲染并发
+func (e *Engine) RefreshSource(ctx context.Context, src Source, interval time.Duration, onError func(error)) {
+	var names []string
+	refresh := func() {
+		contents, err := e.fetchSource(src)
+		var events []ReloadEvent
+		if err == nil {
+			e.mu.Lock()
+			events, names, err = e.loadContents(contents, names)
+			e.mu.Unlock()
+		}
+		e.notifyReload(eventKeys(events), err)
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package gosql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sqlTokenKind 渲染后 SQL 的词法单元类型
+type sqlTokenKind int
+
+const (
+	sqlWord         sqlTokenKind = iota // 关键字、标识符
+	sqlNumber                           // 数字
+	sqlString                           // '...' 字符串
+	sqlQuotedIdent                      // "..." 或 `...` 标识符
+	sqlSpace                            // 空白
+	sqlLineComment                      // -- 注释（不含换行）
+	sqlBlockComment                     // /* */ 注释
+	sqlSymbol                           // 其它符号（单个字符）
+)
+
+// sqlToken 渲染后 SQL 的词法单元
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// scanSQL 将（渲染后的）SQL 切分为词法单元，用于格式化、关键字大小写转换等后处理
+// 字符串、引号标识符和注释会作为整体保留，不会被后处理修改
+func scanSQL(s string) []sqlToken {
+	src := []rune(s)
+	var tokens []sqlToken
+	i := 0
+	for i < len(src) {
+		start := i
+		ch := src[i]
+		kind := sqlSymbol
+		switch {
+		case unicode.IsSpace(ch):
+			kind = sqlSpace
+			for i < len(src) && unicode.IsSpace(src[i]) {
+				i++
+			}
+		case ch == '-' && i+1 < len(src) && src[i+1] == '-':
+			kind = sqlLineComment
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			kind = sqlBlockComment
+			i += 2
+			for i < len(src) && !(src[i] == '*' && i+1 < len(src) && src[i+1] == '/') {
+				i++
+			}
+			i += 2
+			if i > len(src) {
+				i = len(src)
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			kind = sqlString
+			if ch != '\'' {
+				kind = sqlQuotedIdent
+			}
+			i++
+			for i < len(src) {
+				if src[i] == ch {
+					// 连续两个引号表示转义
+					if i+1 < len(src) && src[i+1] == ch {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+		case unicode.IsLetter(ch) || ch == '_':
+			kind = sqlWord
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_' || src[i] == '$') {
+				i++
+			}
+		case unicode.IsDigit(ch):
+			kind = sqlNumber
+			for i < len(src) && (unicode.IsDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, sqlToken{kind: kind, text: string(src[start:i])})
+	}
+	return tokens
+}
+
+// joinSQLTokens 拼接词法单元
+func joinSQLTokens(tokens []sqlToken) string {
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// sqlKeywords 常见 SQL 关键字（大写）
+var sqlKeywords = toSet(`
+ADD ALL ALTER AND ANY AS ASC BETWEEN BY CASE CAST CHECK COLUMN CONFLICT CONSTRAINT CREATE CROSS
+CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP DATABASE DEFAULT DELETE DESC DISTINCT DO DROP DUPLICATE
+ELSE END ESCAPE EXCEPT EXISTS FALSE FETCH FIRST FOR FOREIGN FROM FULL GROUP HAVING IF ILIKE IN INDEX
+INNER INSERT INTERSECT INTERVAL INTO IS JOIN KEY LEFT LIKE LIMIT NATURAL NEXT NOT NOTHING NULL NULLS
+OFFSET ON ONLY OR ORDER OUTER OVER PARTITION PRIMARY RECURSIVE REFERENCES RETURNING RIGHT ROW ROWS
+SELECT SET TABLE THEN TOP TRUE TRUNCATE UNION UNIQUE UPDATE USING VALUES VIEW WHEN WHERE WINDOW WITH
+`)
+
+// toSet 将空白分隔的单词列表转为集合
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// isSQLKeyword 判断单词是否为 SQL 关键字（不区分大小写）
+func isSQLKeyword(word string) bool {
+	return sqlKeywords[strings.ToUpper(word)]
+}
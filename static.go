@@ -0,0 +1,41 @@
+package gosql
+
+import (
+	"strings"
+)
+
+// GetStatic 返回不含任何动态节点的模板文本（path 为 "namespace.name" 或 "namespace.name.define"），
+// 用于与查询放在一起的 DDL、迁移脚本等绝不能参数化的片段。
+// 模板中出现 @var、@if、@use 等任何动态语法时返回错误，而不是静默地输出 ? 占位符
+func (e *Engine) GetStatic(path string) (string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return "", codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", path)
+	}
+	key := parts[0] + "." + parts[1]
+	ast, ok := e.compiledAST[key]
+	if !ok {
+		return "", e.templateNotFound(key)
+	}
+	nodes := ast.Nodes
+	if len(parts) > 2 {
+		define := findDefine(nodes, parts[2])
+		if define == nil {
+			return "", defineNotFound(parts[2], key, nodes)
+		}
+		nodes = define.Body
+	}
+	e.recordUsage(key)
+
+	var sb strings.Builder
+	for _, node := range nodes {
+		text, ok := node.(*TextNode)
+		if !ok {
+			return "", codeError(CodeNotStatic, "template %s is not static: contains %s node", path, node.nodeType())
+		}
+		sb.WriteString(text.Text)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,39 @@
+package gosql
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// recordUsage 记录模板被渲染（包括被 @use 引用）的次数
+func (e *Engine) recordUsage(key string) {
+	if counter, ok := e.usage[key]; ok {
+		atomic.AddInt64(counter, 1)
+	}
+}
+
+// Usage 返回进程启动（或模板加载）以来每个模板被渲染的次数，key 为 namespace.name
+// 通过 @use 被其它模板引用也计入次数
+func (e *Engine) Usage() map[string]int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	stats := make(map[string]int64, len(e.usage))
+	for key, counter := range e.usage {
+		stats[key] = atomic.LoadInt64(counter)
+	}
+	return stats
+}
+
+// Unused 返回自加载以来从未被渲染过的模板路径（已排序），用于发现废弃模板
+func (e *Engine) Unused() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var unused []string
+	for key, counter := range e.usage {
+		if atomic.LoadInt64(counter) == 0 {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
@@ -0,0 +1,143 @@
+package gosql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// DefaultStmtCacheSize Executor 缓存的预编译语句的默认个数
+const DefaultStmtCacheSize = 256
+
+// ExecutorOption Executor 的配置项
+type ExecutorOption func(*Executor)
+
+// WithStmtCacheSize 设置缓存的预编译语句个数（默认 DefaultStmtCacheSize），超出时关闭最久未使用的语句。
+// in (...) 展开、条件行等会让同一模板渲染出很多不同的 SQL，缓存不设上限会在数据库端累积预编译语句。
+// n <= 0 时不缓存，每次执行后关闭语句
+func WithStmtCacheSize(n int) ExecutorOption {
+	return func(x *Executor) {
+		x.stmts.size = n
+	}
+}
+
+// stmtCache 按 SQL 文本缓存预编译语句的 LRU 缓存
+type stmtCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List // 最近使用的在前
+}
+
+// cachedStmt 缓存的预编译语句
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // 正在使用它的调用数
+	evicted bool // 已移出缓存，最后一个使用者 release 时关闭
+}
+
+// newStmtCache 创建容量为 size 的缓存
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// acquire 返回 SQL 对应的预编译语句（没有缓存时预编译），用完后调用 release
+func (c *stmtCache) acquire(ctx context.Context, db *sql.DB, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if el, ok := c.items[query]; ok {
+		c.order.MoveToFront(el)
+		s := el.Value.(*cachedStmt)
+		s.refs++
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if el, ok := c.items[query]; ok {
+		// 并发预编译了同一条 SQL，保留先缓存的
+		c.order.MoveToFront(el)
+		s := el.Value.(*cachedStmt)
+		s.refs++
+		c.mu.Unlock()
+		stmt.Close()
+		return s, nil
+	}
+	s := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	if c.size <= 0 {
+		s.evicted = true
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.items[query] = c.order.PushFront(s)
+	var idle []*sql.Stmt
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		old := oldest.Value.(*cachedStmt)
+		delete(c.items, old.query)
+		if old.evicted = true; old.refs == 0 {
+			idle = append(idle, old.stmt)
+		}
+	}
+	c.mu.Unlock()
+	closeStmts(idle)
+	return s, nil
+}
+
+// release 结束使用 acquire 返回的语句，语句已移出缓存且没有其它使用者时关闭
+func (c *stmtCache) release(s *cachedStmt) {
+	c.mu.Lock()
+	s.refs--
+	idle := s.evicted && s.refs == 0
+	c.mu.Unlock()
+	if idle {
+		s.stmt.Close()
+	}
+}
+
+// reset 清空缓存（模板重新加载后、Executor.Close 时调用），关闭没有在使用的语句，
+// 正在使用的语句在 release 时关闭
+func (c *stmtCache) reset() error {
+	c.mu.Lock()
+	var idle []*sql.Stmt
+	for _, el := range c.items {
+		s := el.Value.(*cachedStmt)
+		if s.evicted = true; s.refs == 0 {
+			idle = append(idle, s.stmt)
+		}
+	}
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.mu.Unlock()
+	return closeStmts(idle)
+}
+
+// len 返回缓存的语句个数
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// closeStmts 关闭语句，返回第一个错误
+func closeStmts(stmts []*sql.Stmt) error {
+	var firstErr error
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
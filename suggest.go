@@ -0,0 +1,115 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions 错误信息中最多给出的候选数
+const maxSuggestions = 3
+
+// suggest 返回与 name 最接近的候选（编辑距离不超过名字长度的三分之一，至少为 1），按距离排序
+func suggest(name string, candidates []string) []string {
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// didYouMean 返回附加在错误信息后的提示，例如 ` (did you mean "user.byId"?)`，没有接近的候选时返回空串
+func didYouMean(name string, candidates []string) string {
+	names := suggest(name, candidates)
+	if len(names) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = fmt.Sprintf("%q", n)
+	}
+	return " (did you mean " + strings.Join(quoted, " or ") + "?)"
+}
+
+// editDistance 计算两个字符串的编辑距离（按 rune，相邻字符交换算一次编辑）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] 为 ra[:i] 与 rb[:j] 的距离，只保留最近三行
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// templateNotFound 返回模板不存在的错误，附带名字接近的模板
+func (e *Engine) templateNotFound(key string) error {
+	keys := make([]string, 0, len(e.compiledAST))
+	for k := range e.compiledAST {
+		keys = append(keys, k)
+	}
+	return codeError(CodeTemplateNotFound, "template not found: %s%s", key, didYouMean(key, keys))
+}
+
+// defineNotFound 返回 define 不存在的错误，附带模板中名字接近的 define
+func defineNotFound(name, key string, nodes []Node) error {
+	var names []string
+	walkDefines(nodes, nil, func(parents []string, n *DefineNode) bool {
+		names = append(names, n.Name)
+		return true
+	})
+	return codeError(CodeDefineNotFound, "define not found: %s in template %s%s", name, key, didYouMean(name, names))
+}
+
+// variableNotFound 返回变量不存在的错误，附带 scope 中名字接近的变量
+func (ctx *executionContext) variableNotFound(name string) error {
+	names := make([]string, 0, len(ctx.scope))
+	for n := range ctx.scope {
+		names = append(names, n)
+	}
+	return codeError(CodeVariableNotFound, "variable not found: %s%s", name, didYouMean(name, names))
+}
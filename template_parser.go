@@ -2,6 +2,7 @@ package gosql
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +10,8 @@ import (
 type TemplateParser struct {
 	tokens []Token
 	pos    int
+	limits ParseLimits
+	depth  int // 当前块的嵌套深度
 }
 
 // NewTemplateParser 创建模板解析器
@@ -33,6 +36,12 @@ func (p *TemplateParser) Parse() (*TemplateAST, error) {
 
 // parseNodes 解析节点列表
 func (p *TemplateParser) parseNodes() ([]Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		return nil, codeError(CodeParseLimit, "line %d: nesting depth exceeds %d: %w", p.peek().Line, p.limits.MaxDepth, ErrParseLimit)
+	}
+
 	var nodes []Node
 
 	for !p.isAtEnd() && !p.check(TOKEN_RBRACE) && !p.check(TOKEN_ELSE_IF) && !p.check(TOKEN_ELSE) {
@@ -70,6 +79,9 @@ func (p *TemplateParser) parseNode() (Node, error) {
 
 	case TOKEN_VAR_EXPR:
 		p.advance()
+		if rows := parseRows(token.Value); rows != nil {
+			return rows, nil
+		}
 		return &VarExprNode{Expr: token.Value, Conditional: false}, nil
 
 	case TOKEN_VAR_EXPR_COND:
@@ -105,6 +117,19 @@ func (p *TemplateParser) parseNode() (Node, error) {
 	case TOKEN_USE:
 		return p.parseUse()
 
+	case TOKEN_IMPORT:
+		p.advance()
+		return &ImportNode{Path: token.Value}, nil
+
+	case TOKEN_INCLUDE:
+		// @include path 等同于不带 cover 的 @use path {}
+		p.advance()
+		path, args, err := parseUseArgs(token.Value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", token.Line, err)
+		}
+		return &UseNode{Path: path, Args: args}, nil
+
 	case TOKEN_DEFINE:
 		return p.parseDefine()
 
@@ -114,6 +139,21 @@ func (p *TemplateParser) parseNode() (Node, error) {
 	case TOKEN_FUNC_BLOCK:
 		return p.parseFuncBlock()
 
+	case TOKEN_SET:
+		return p.parseSet()
+
+	case TOKEN_FOREACH:
+		return p.parseForEach()
+
+	case TOKEN_SWITCH:
+		return p.parseSwitch()
+
+	case TOKEN_FLAG:
+		return p.parseFlag()
+
+	case TOKEN_DIRECTIVE:
+		return p.parseDirective()
+
 	case TOKEN_LBRACE:
 		// 跳过孤立的 {
 		p.advance()
@@ -199,7 +239,7 @@ func (p *TemplateParser) parseIf() (Node, error) {
 
 	// 期望最后的 }
 	if !p.match(TOKEN_RBRACE) {
-		return nil, fmt.Errorf("line %d: expected '}' to close if statement", p.peek().Line)
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close if statement", p.peek().Line)
 	}
 
 	return ifNode, nil
@@ -223,7 +263,7 @@ func (p *TemplateParser) parseFor() (Node, error) {
 
 	// 期望 }
 	if !p.match(TOKEN_RBRACE) {
-		return nil, fmt.Errorf("line %d: expected '}' to close for statement", p.peek().Line)
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close for statement", p.peek().Line)
 	}
 
 	return &ForNode{
@@ -232,10 +272,212 @@ func (p *TemplateParser) parseFor() (Node, error) {
 	}, nil
 }
 
+// parseSet 解析 set 块 @set { }
+func (p *TemplateParser) parseSet() (Node, error) {
+	token := p.advance() // 消费 SET token
+
+	// 期望 {
+	if !p.match(TOKEN_LBRACE) {
+		return nil, fmt.Errorf("line %d: expected '{' after @set", token.Line)
+	}
+
+	body, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// 期望 }
+	if !p.match(TOKEN_RBRACE) {
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close set block", p.peek().Line)
+	}
+
+	return &SetNode{Body: body}, nil
+}
+
+// parseFlag 解析 @flag "name" { } @else { }
+func (p *TemplateParser) parseFlag() (Node, error) {
+	token := p.advance() // 消费 FLAG token
+
+	name, err := strconv.Unquote(token.Value)
+	if err != nil || name == "" {
+		return nil, codeError(CodeSyntax, "line %d: @flag expects a quoted flag name, got %q", token.Line, token.Value)
+	}
+
+	// 期望 {
+	if !p.match(TOKEN_LBRACE) {
+		return nil, fmt.Errorf("line %d: expected '{' after @flag name", token.Line)
+	}
+
+	body, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	node := &FlagNode{Name: name, Body: body}
+
+	if p.check(TOKEN_ELSE_IF) {
+		return nil, codeError(CodeSyntax, "line %d: @flag does not support else if", p.peek().Line)
+	}
+	if p.match(TOKEN_ELSE) {
+		if !p.match(TOKEN_LBRACE) {
+			return nil, fmt.Errorf("line %d: expected '{' after else", p.peek().Line)
+		}
+		elseBody, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		node.Else = &ElseNode{Body: elseBody}
+	}
+
+	// 期望 }
+	if !p.match(TOKEN_RBRACE) {
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close @flag block", p.peek().Line)
+	}
+
+	return node, nil
+}
+
+// parseDirective 解析 RegisterDirective 注册的指令，参数交给注册的解析函数
+func (p *TemplateParser) parseDirective() (Node, error) {
+	token := p.advance() // 消费 DIRECTIVE token
+
+	name, args, _ := strings.Cut(token.Value, "(")
+	node := &DirectiveNode{Name: name, Args: strings.TrimSuffix(args, ")")}
+	if p.match(TOKEN_LBRACE) {
+		body, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		if !p.match(TOKEN_RBRACE) {
+			return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close @%s block", p.peek().Line, name)
+		}
+		node.Body = body
+		node.Block = true
+	}
+
+	parse := lookupDirective(name)
+	if parse == nil {
+		return nil, codeError(CodeSyntax, "line %d: unknown directive @%s", token.Line, name)
+	}
+	custom, err := parse(node.Args, node.Block)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: @%s: %w", token.Line, name, err)
+	}
+	if custom == nil {
+		return nil, codeError(CodeSyntax, "line %d: @%s: directive parser returned nil", token.Line, name)
+	}
+	node.Custom = custom
+	return node, nil
+}
+
+// parseForEach 解析 foreach 语句
+func (p *TemplateParser) parseForEach() (Node, error) {
+	token := p.advance() // 消费 FOREACH token
+
+	node, err := parseForEachHeader(token.Value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", token.Line, err)
+	}
+
+	// 期望 {
+	if !p.match(TOKEN_LBRACE) {
+		return nil, fmt.Errorf("line %d: expected '{' after foreach", token.Line)
+	}
+
+	body, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// 期望 }
+	if !p.match(TOKEN_RBRACE) {
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close foreach statement", p.peek().Line)
+	}
+
+	node.Body = body
+	return node, nil
+}
+
+// parseSwitch 解析 switch 语句，块内只能是 @case / @default 分支（以及分支之间的空白）
+func (p *TemplateParser) parseSwitch() (Node, error) {
+	token := p.advance() // 消费 SWITCH token
+	if strings.TrimSpace(token.Value) == "" {
+		return nil, codeError(CodeSyntax, "line %d: expected expression after switch", token.Line)
+	}
+	node := &SwitchNode{Expr: token.Value}
+
+	// 期望 {
+	if !p.match(TOKEN_LBRACE) {
+		return nil, fmt.Errorf("line %d: expected '{' after switch expression", token.Line)
+	}
+
+	for !p.isAtEnd() && !p.check(TOKEN_RBRACE) {
+		branch := p.peek()
+		switch {
+		case branch.Type == TOKEN_TEXT && strings.TrimSpace(branch.Value) == "":
+			p.advance()
+			continue
+		case branch.Type == TOKEN_CASE:
+			if node.Default != nil {
+				return nil, codeError(CodeSyntax, "line %d: case after default in switch", branch.Line)
+			}
+		case branch.Type == TOKEN_DEFAULT:
+			if node.Default != nil {
+				return nil, codeError(CodeSyntax, "line %d: multiple defaults in switch", branch.Line)
+			}
+		default:
+			return nil, codeError(CodeSyntax, "line %d, column %d: only @case and @default are allowed in switch\n%s",
+				branch.Line, branch.Column, branch.Context)
+		}
+		p.advance()
+
+		c := &CaseNode{}
+		if branch.Type == TOKEN_CASE {
+			for _, value := range splitArgs(branch.Value) {
+				if value = strings.TrimSpace(value); value == "" {
+					return nil, codeError(CodeSyntax, "line %d: empty case value", branch.Line)
+				}
+				c.Values = append(c.Values, value)
+				v, _ := constExpr(value)
+				c.consts = append(c.consts, v)
+			}
+		}
+
+		// 期望 {
+		if !p.match(TOKEN_LBRACE) {
+			return nil, fmt.Errorf("line %d: expected '{' after %s", branch.Line, strings.ToLower(branch.Type.String()))
+		}
+		body, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		// 期望 }
+		if !p.match(TOKEN_RBRACE) {
+			return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close %s", p.peek().Line, strings.ToLower(branch.Type.String()))
+		}
+		c.Body = body
+
+		if branch.Type == TOKEN_CASE {
+			node.Cases = append(node.Cases, c)
+		} else {
+			node.Default = c
+		}
+	}
+
+	// 期望最后的 }
+	if !p.match(TOKEN_RBRACE) {
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close switch statement", p.peek().Line)
+	}
+
+	return node, nil
+}
+
 // parseUse 解析 use 语句
 func (p *TemplateParser) parseUse() (Node, error) {
 	token := p.advance() // 消费 USE token
-	path := token.Value
+	path, args, err := parseUseArgs(token.Value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", token.Line, err)
+	}
 
 	// 期望 {
 	if !p.match(TOKEN_LBRACE) {
@@ -244,6 +486,7 @@ func (p *TemplateParser) parseUse() (Node, error) {
 
 	useNode := &UseNode{
 		Path: path,
+		Args: args,
 	}
 
 	// 解析 cover 块
@@ -267,7 +510,7 @@ func (p *TemplateParser) parseUse() (Node, error) {
 
 	// 期望 }
 	if !p.match(TOKEN_RBRACE) {
-		return nil, fmt.Errorf("line %d: expected '}' to close use statement", p.peek().Line)
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close use statement", p.peek().Line)
 	}
 
 	return useNode, nil
@@ -276,7 +519,10 @@ func (p *TemplateParser) parseUse() (Node, error) {
 // parseDefine 解析 define 语句
 func (p *TemplateParser) parseDefine() (Node, error) {
 	token := p.advance() // 消费 DEFINE token
-	name := token.Value
+	name, params, err := parseDefineParams(token.Value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", token.Line, err)
+	}
 
 	// 期望 {
 	if !p.match(TOKEN_LBRACE) {
@@ -291,12 +537,13 @@ func (p *TemplateParser) parseDefine() (Node, error) {
 
 	// 期望 }
 	if !p.match(TOKEN_RBRACE) {
-		return nil, fmt.Errorf("line %d: expected '}' to close define statement", p.peek().Line)
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close define statement", p.peek().Line)
 	}
 
 	return &DefineNode{
-		Name: name,
-		Body: body,
+		Name:   name,
+		Params: params,
+		Body:   body,
 	}, nil
 }
 
@@ -318,7 +565,7 @@ func (p *TemplateParser) parseCover() (Node, error) {
 
 	// 期望 }
 	if !p.match(TOKEN_RBRACE) {
-		return nil, fmt.Errorf("line %d: expected '}' to close cover statement", p.peek().Line)
+		return nil, codeError(CodeUnclosedBrace, "line %d: expected '}' to close cover statement", p.peek().Line)
 	}
 
 	return &CoverNode{
@@ -332,22 +579,25 @@ func (p *TemplateParser) parseFuncBlock() (Node, error) {
 	token := p.advance() // 消费 FUNC_BLOCK token
 
 	// token.Value 格式为 "funcExpr|blockContent"
-	parts := strings.SplitN(token.Value, "|", 2)
-	funcExpr := parts[0]
-	blockContent := ""
-	if len(parts) > 1 {
-		blockContent = parts[1]
+	funcExpr, blockContent := splitFuncBlock(token.Value)
+	// @trim(prefix=..., suffix=...) 是内置的 trim 块，其它参数形式仍然调用名为 trim 的函数
+	trim, isTrim, err := parseTrimArgs(funcExpr)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", token.Line, err)
 	}
 
 	// 解析块内容为节点
 	var bodyNodes []Node
 	if blockContent != "" {
 		lexer := NewLexer(blockContent)
+		lexer.MaxTokens = p.limits.MaxTokens
 		tokens, err := lexer.Tokenize()
 		if err != nil {
 			return nil, fmt.Errorf("line %d: error parsing func block: %w", token.Line, err)
 		}
 		subParser := NewTemplateParser(tokens)
+		subParser.limits = p.limits
+		subParser.depth = p.depth
 		ast, err := subParser.Parse()
 		if err != nil {
 			return nil, fmt.Errorf("line %d: error parsing func block: %w", token.Line, err)
@@ -355,12 +605,32 @@ func (p *TemplateParser) parseFuncBlock() (Node, error) {
 		bodyNodes = ast.Nodes
 	}
 
+	if isTrim {
+		trim.Body = bodyNodes
+		return trim, nil
+	}
 	return &FuncBlockNode{
 		FuncExpr: funcExpr,
 		Body:     bodyNodes,
 	}, nil
 }
 
+// splitFuncBlock 拆分 FUNC_BLOCK token 的值 "funcExpr|blockContent"（funcExpr 的字符串中可以包含 |）
+func splitFuncBlock(value string) (string, string) {
+	var state literalState
+	runes := []rune(value)
+	for i, ch := range runes {
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if !state.feed(ch, next, true) && ch == '|' {
+			return string(runes[:i]), string(runes[i+1:])
+		}
+	}
+	return value, ""
+}
+
 // 辅助方法
 
 func (p *TemplateParser) peek() Token {
@@ -392,15 +662,29 @@ func (p *TemplateParser) isAtEnd() bool {
 	return p.peek().Type == TOKEN_EOF
 }
 
-// ParseTemplate 便捷函数：从 SQL 模板内容解析为 AST
+// ParseTemplate 便捷函数：从 SQL 模板内容解析为 AST。
+// 对任意输入都不会 panic，并按 DefaultParseLimits 限制输入大小、token 数和嵌套深度
 func ParseTemplate(content string) (*TemplateAST, error) {
+	return ParseTemplateWithLimits(content, DefaultParseLimits)
+}
+
+// ParseTemplateWithLimits 按给定的限制解析 SQL 模板
+func ParseTemplateWithLimits(content string, limits ParseLimits) (ast *TemplateAST, err error) {
+	defer recoverParse(&err)
+	if err := limits.checkSize(content); err != nil {
+		return nil, err
+	}
+
 	lexer := NewLexer(content)
+	lexer.MaxTokens = limits.MaxTokens
 	tokens, err := lexer.Tokenize()
 	if err != nil {
-		return nil, err
+		return nil, withCode(CodeSyntax, err)
 	}
 
 	parser := NewTemplateParser(tokens)
-	return parser.Parse()
+	parser.limits = limits
+	ast, err = parser.Parse()
+	return ast, withCode(CodeSyntax, err)
 }
 
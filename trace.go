@@ -0,0 +1,59 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+)
+
+// TraceEvent 渲染过程中的一步，用于排查模板为什么渲染出某条 SQL
+type TraceEvent struct {
+	Kind   string `json:"kind"`   // if、for、use、define、cover、skip、param
+	Detail string `json:"detail"` // 例如 "if status != 0: true"、"?1 = 42 (int)"
+	Depth  int    `json:"depth"`  // @use 和 define 的嵌套层级
+}
+
+// tracer 收集一次渲染的 TraceEvent
+type tracer struct {
+	events []TraceEvent
+	depth  int
+}
+
+// Trace 渲染模板并返回渲染过程：每个条件的取值、循环次数、@use / define / cover 的展开、
+// 被跳过的条件行以及每个绑定的参数。与 DryRun 一样不计入模板的渲染次数
+func (e *Engine) Trace(path string, args interface{}) (Query, []TraceEvent, error) {
+	t := &tracer{}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	query, _, err := e.renderAt(context.Background(), path, args, false, 0, nil, t)
+	return query, t.events, err
+}
+
+// tracef 记录一步（未开启跟踪时不做任何事）
+func (ctx *executionContext) tracef(kind, format string, args ...interface{}) {
+	if ctx.trace == nil {
+		return
+	}
+	ctx.trace.events = append(ctx.trace.events, TraceEvent{
+		Kind:   kind,
+		Detail: fmt.Sprintf(format, args...),
+		Depth:  ctx.trace.depth,
+	})
+}
+
+// traceIn 进入下一层嵌套，返回的函数用于退出
+func (ctx *executionContext) traceIn() func() {
+	if ctx.trace == nil {
+		return func() {}
+	}
+	ctx.trace.depth++
+	return func() { ctx.trace.depth-- }
+}
+
+// traceParam 记录刚绑定的参数
+func (ctx *executionContext) traceParam() {
+	if ctx.trace == nil {
+		return
+	}
+	value := ctx.args[len(ctx.args)-1]
+	ctx.tracef("param", "?%d = %v (%T)", len(ctx.args), value, value)
+}
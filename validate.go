@@ -0,0 +1,186 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate 校验所有已加载模板间的引用：@use 引用的模板和 define 必须存在，@cover 的目标必须是被引用模板中的 define，
+// 模板（或 define）之间不能循环 @use。
+// 模板分散在多个文件中时，每次 LoadMarkdown 只能校验已经加载的部分（引用还没有加载的模板不报错），
+// 全部加载完成后调用 Validate，让改名后没有同步修改的引用在启动时报错，而不是等到请求走到那个分支时才报错。
+// 使用 WithoutReferenceValidation 时不做校验
+func (e *Engine) Validate() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.validateRefs(true)
+}
+
+// validateRefs 加载后校验模板间的引用。final 为 false 时（每次加载后）跳过被引用的模板还没有加载的 @use，
+// 等它加载后或调用 Validate 时再校验
+func (e *Engine) validateRefs(final bool) error {
+	if e.skipRefCheck {
+		return nil
+	}
+	if errs := e.refErrors(final, 1); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// refErrors 返回模板间引用的错误（按模板排序），limit 大于 0 时最多返回 limit 个
+func (e *Engine) refErrors(final bool, limit int) []error {
+	keys := make([]string, 0, len(e.compiledAST))
+	for key := range e.compiledAST {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		ast := e.compiledAST[key]
+		nodes := ast.Nodes
+		if ast.source != nil {
+			nodes = ast.source
+		}
+		walkNodes(nodes, func(node Node) {
+			use, ok := node.(*UseNode)
+			if !ok || limit > 0 && len(errs) >= limit {
+				return
+			}
+			if err := e.validateUse(use, final); err != nil {
+				errs = append(errs, fmt.Errorf("template %s: @use %s: %w", key, use.Path, err))
+			}
+		})
+	}
+	if limit <= 0 || len(errs) < limit {
+		errs = append(errs, e.useCycles()...)
+	}
+	if limit > 0 && len(errs) > limit {
+		errs = errs[:limit]
+	}
+	return errs
+}
+
+// useCycles 返回 @use 引用图中的环（每个环一个错误，按模板排序），
+// 模板互相 @use 时加载就报错，而不是渲染时直到超出 MaxUseDepth 才报错
+func (e *Engine) useCycles() []error {
+	graph := e.useGraph()
+	nodes := make([]string, 0, len(graph))
+	for node := range graph {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	const visiting, done = 1, 2
+	state := make(map[string]int, len(graph))
+	var stack []string
+	var errs []error
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, next := range graph[node] {
+			switch state[next] {
+			case 0:
+				visit(next)
+			case visiting:
+				i := len(stack) - 1
+				for stack[i] != next {
+					i--
+				}
+				cycle := append(append([]string(nil), stack[i:]...), next)
+				errs = append(errs, codeError(CodeCycle, "@use cycle: %s", strings.Join(cycle, " -> ")))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = done
+	}
+	for _, node := range nodes {
+		if state[node] == 0 {
+			visit(node)
+		}
+	}
+	return errs
+}
+
+// useGraph 返回 @use 引用图：节点为模板（namespace.name）和 define（namespace.name.define），
+// 边指向节点内容（包括 cover 和展开的 @import）中 @use 的路径，不存在的目标不计入
+func (e *Engine) useGraph() map[string][]string {
+	graph := make(map[string][]string, len(e.compiledAST))
+	for key, ast := range e.compiledAST {
+		graph[key] = e.useTargets(ast.Nodes)
+		walkNodes(ast.Nodes, func(node Node) {
+			if d, ok := node.(*DefineNode); ok && findDefine(ast.Nodes, d.Name) == d {
+				graph[key+"."+d.Name] = e.useTargets(d.Body)
+			}
+		})
+	}
+	return graph
+}
+
+// useTargets 返回节点中 @use 引用的、存在的模板或 define 的路径
+func (e *Engine) useTargets(nodes []Node) []string {
+	var targets []string
+	walkNodes(nodes, func(node Node) {
+		use, ok := node.(*UseNode)
+		if !ok {
+			return
+		}
+		parts := strings.SplitN(use.Path, ".", 3)
+		if len(parts) < 2 {
+			return
+		}
+		used, ok := e.compiledAST[parts[0]+"."+parts[1]]
+		if !ok || len(parts) == 3 && findDefine(used.Nodes, parts[2]) == nil {
+			return
+		}
+		targets = append(targets, use.Path)
+	})
+	return targets
+}
+
+// validateUse 校验 @use 引用的模板、define 和 @cover 的目标是否存在
+func (e *Engine) validateUse(use *UseNode, final bool) error {
+	parts := strings.Split(use.Path, ".")
+	if len(parts) < 2 {
+		return codeError(CodeInvalidPath, "invalid path: %s, expected format: namespace.name", use.Path)
+	}
+	target := parts[0] + "." + parts[1]
+	used, ok := e.compiledAST[target]
+	if !ok {
+		if final {
+			return e.templateNotFound(target)
+		}
+		return nil
+	}
+	if len(parts) > 2 {
+		define := findDefine(used.Nodes, parts[2])
+		if define == nil {
+			return defineNotFound(parts[2], target, used.Nodes)
+		}
+		if err := checkDefineArgs(define, use); err != nil {
+			return err
+		}
+	}
+	if used.refs == nil {
+		return nil
+	}
+	for _, cover := range use.Covers {
+		if !hasDefine(used.refs.defines, cover.Name) {
+			return codeError(CodeDefineNotFound, "cover target not found: %s in template %s%s", cover.Name, target, didYouMean(cover.Name, coverNames(used.refs.defines)))
+		}
+	}
+	return nil
+}
+
+// coverNames 返回 @cover 可以使用的名字：define 的完整路径和最后一级名称
+func coverNames(defines []string) []string {
+	names := append([]string(nil), defines...)
+	for _, d := range defines {
+		if i := strings.LastIndex(d, "."); i >= 0 {
+			names = append(names, d[i+1:])
+		}
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+package gosql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+// ValueAdapter 自定义类型适配器，让领域类型（自定义枚举、decimal.Decimal、uuid.UUID 等）
+// 在展开参数、条件判断和绑定参数时表现一致，通过 WithValueAdapters 注册。
+// 每个方法的 ok 返回 false 表示不处理该值，交给下一个适配器或默认规则
+type ValueAdapter interface {
+	// ScopeValue 把参数值转换为模板中使用的值（用于表达式和比较），展开 args 时调用
+	ScopeValue(v interface{}) (value interface{}, ok bool)
+	// Truthy 判断值是否为 "真"（条件行 @x?、@if 等）
+	Truthy(v interface{}) (truthy bool, ok bool)
+	// Param 把值转换为绑定到 SQL 的参数
+	Param(v interface{}) (param interface{}, ok bool)
+}
+
+// scopeValue 把参数值转换为放入 scope 的值：先交给注册的 ValueAdapter，然后
+// sql.NullString / sql.NullInt64 / sql.Null[T] 等可空类型展开为底层值（无效时为 nil），
+// 指向基础类型的指针解引用（nil 指针为 nil），
+// 这样条件行、比较和真值判断都按底层值进行，而不是把它们当作总为真的结构体
+func (e *Engine) scopeValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	for _, a := range e.adapters {
+		if value, ok := a.ScopeValue(v); ok {
+			return value
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		if isNullType(rv.Type().Elem()) {
+			return e.scopeValue(rv.Elem().Interface())
+		}
+		switch rv.Elem().Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface, reflect.Ptr:
+			return v
+		}
+		return rv.Elem().Interface()
+	case reflect.Struct:
+		if isNullType(rv.Type()) {
+			value, err := v.(driver.Valuer).Value()
+			if err != nil {
+				return v
+			}
+			return value
+		}
+	}
+	return v
+}
+
+// truthy 由注册的 ValueAdapter 判断值是否为 "真"，ok 为 false 表示没有适配器处理
+func (e *Engine) truthy(v interface{}) (truthy bool, ok bool) {
+	for _, a := range e.adapters {
+		if truthy, ok := a.Truthy(v); ok {
+			return truthy, true
+		}
+	}
+	return false, false
+}
+
+// paramValue 由注册的 ValueAdapter 把值转换为绑定参数
+func (e *Engine) paramValue(v interface{}) interface{} {
+	for _, a := range e.adapters {
+		if param, ok := a.Param(v); ok {
+			return param
+		}
+	}
+	return v
+}
+
+// sliceLen 返回要展开为多个参数的切片或数组的长度，其他值返回 -1：Query / Fragment 等子查询、
+// []byte、time.Time、实现 driver.Valuer 的类型（如 pq.StringArray）以及 WithScalarTypes 注册的类型
+// 都作为单个参数绑定
+func (e *Engine) sliceLen(v interface{}) int {
+	if _, ok := subQuery(v); ok {
+		return -1
+	}
+	switch v.(type) {
+	case nil, []byte, time.Time, driver.Valuer:
+		return -1
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return -1
+	}
+	for _, t := range e.scalarTypes {
+		if rv.Type() == t {
+			return -1
+		}
+	}
+	return rv.Len()
+}
+
+// isNullType 判断是否为 sql.Null* 风格的可空类型（实现 driver.Valuer 且带有 Valid bool 字段）
+func isNullType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || !t.Implements(valuerType) {
+		return false
+	}
+	f, ok := t.FieldByName("Valid")
+	return ok && f.Type.Kind() == reflect.Bool
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
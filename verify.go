@@ -0,0 +1,63 @@
+package gosql
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrInvalidSignature 模板文件签名校验失败
+var ErrInvalidSignature = errors.New("invalid template signature")
+
+// BundleVerifier 校验外部提供的模板文件（远程来源、模板包）的签名，
+// 返回错误时整批文件都不会被加载
+type BundleVerifier interface {
+	Verify(name string, content, signature []byte) error
+}
+
+// BundleVerifierFunc 把函数适配为 BundleVerifier
+type BundleVerifierFunc func(name string, content, signature []byte) error
+
+// Verify 实现 BundleVerifier
+func (f BundleVerifierFunc) Verify(name string, content, signature []byte) error {
+	return f(name, content, signature)
+}
+
+// Ed25519Verifier 使用 ed25519 公钥校验签名（签名为对文件内容的 ed25519 签名）
+func Ed25519Verifier(pub ed25519.PublicKey) BundleVerifier {
+	return BundleVerifierFunc(func(name string, content, signature []byte) error {
+		if !ed25519.Verify(pub, content, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// HMACVerifier 使用共享密钥校验 HMAC-SHA256 签名
+func HMACVerifier(key []byte) BundleVerifier {
+	return BundleVerifierFunc(func(name string, content, signature []byte) error {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(content)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// verifyContents 用引擎配置的校验器校验所有文件，未配置校验器时直接通过
+func (e *Engine) verifyContents(contents []NamedContent) error {
+	if e.verifier == nil {
+		return nil
+	}
+	for _, c := range contents {
+		if len(c.Signature) == 0 {
+			return codeError(CodeInvalidSignature, "%s: missing signature: %w", c.Name, ErrInvalidSignature)
+		}
+		if err := e.verifier.Verify(c.Name, []byte(c.Content), c.Signature); err != nil {
+			return codeError(CodeInvalidSignature, "%s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package gosql
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultWatchInterval Watch 默认的事件合并间隔
+const defaultWatchInterval = 100 * time.Millisecond
+
+// fileStamp 文件的修改时间和大小，用于判断文件是否变化
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch 加载目录下所有 .md 文件（同 LoadDir），然后通过 fsnotify 监听目录（包括子目录）中的文件变化，
+// 收到事件后等待一个合并间隔（见 WithWatchInterval，编辑器保存时产生的多个事件只触发一次加载），
+// 再比较文件的修改时间和大小找出变化的文件：新增或修改的文件按 LoadFile 的规则重新加载，删除的文件中的模板被移除，变化通过 OnReload 通知。
+// 一批文件在暂存的副本上加载，全部校验通过后才替换引擎的模板；文件在获取引擎的写锁之前读取，解析和替换模板时持有写锁：
+// 正在进行的 GetSql 使用旧模板完成，之后的调用使用新模板，不会看到加载了一半的状态；
+// 文件有错误时保留该文件之前的模板并调用 onError（可以为 nil），文件再次修改时重试。
+// 调用返回的 stop 停止监听
+func (e *Engine) Watch(dir string, onError func(error)) (stop func(), err error) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watchTree(watcher, dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	if _, err := e.LoadDir(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	stamps, err := scanStamps(dir)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	interval := e.watchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		var pending <-chan time.Time
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if ev.Has(fsnotify.Create) {
+					// 新建的子目录也需要监听
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						if err := watchTree(watcher, ev.Name); err != nil {
+							report(err)
+						}
+					}
+				}
+				if pending == nil {
+					pending = time.After(interval)
+				}
+				continue
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				report(err)
+				continue
+			case <-pending:
+				pending = nil
+			}
+
+			next, err := scanStamps(dir)
+			if err != nil {
+				report(err)
+				continue
+			}
+			var changed, removed []string
+			for file, stamp := range next {
+				if old, ok := stamps[file]; !ok || !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+					changed = append(changed, file)
+				}
+			}
+			for file := range stamps {
+				if _, ok := next[file]; !ok {
+					removed = append(removed, file)
+				}
+			}
+			stamps = next
+			if len(changed) == 0 && len(removed) == 0 {
+				continue
+			}
+			keys, err := e.reloadFiles(changed, removed)
+			e.notifyReload(keys, err)
+			if err != nil {
+				report(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { watcher.Close() }) }, nil
+}
+
+// watchTree 监听目录及其所有子目录（fsnotify 不会递归监听）
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(p)
+	})
+}
+
+// reloadFiles 重新加载变化的文件、移除已删除文件中的模板。
+// 文件内容（包括 include 的文件）在获取写锁之前读取，写锁内只解析和替换模板；
+// 每个文件独立加载，一个文件出错不影响其它文件；所有文件都在暂存的副本上加载，
+// 最后的引用校验（有文件出错时也会进行）失败时丢弃整批变化、恢复加载前的模板。返回变化的模板（已排序）和所有错误
+func (e *Engine) reloadFiles(changed, removed []string) ([]string, error) {
+	sort.Strings(changed)
+	sort.Strings(removed)
+	pre := prefetchFiles(changed)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	prev := e.stageState()
+	var keys []string
+	var errs []error
+	loaded := make(map[string]bool, len(changed))
+	for _, file := range changed {
+		if loaded[file] {
+			continue
+		}
+		ev, err := e.loadFileTree(nil, pre, file, nil, loaded)
+		keys = append(keys, ev.Keys()...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, file := range removed {
+		ev, err := e.loadFile(file, "")
+		keys = append(keys, ev.Keys()...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(e.files, file)
+		delete(e.includes, file)
+	}
+	if err := e.validateRefs(true); err != nil {
+		e.restoreState(prev)
+		return nil, errors.Join(append(errs, err)...)
+	}
+	sort.Strings(keys)
+	return keys, errors.Join(errs...)
+}
+
+// templateState 加载文件时会修改的引擎状态
+type templateState struct {
+	templates   map[string]*SQLTemplate
+	compiledAST map[string]*TemplateAST
+	usage       map[string]*int64
+	files       map[string][]string
+	fileOf      map[string]string
+	includes    map[string][]string
+	loadedAt    time.Time
+}
+
+// stageState 把引擎的模板状态换成副本（之后的加载只修改副本），返回原来的状态（调用方持有写锁）
+func (e *Engine) stageState() templateState {
+	prev := templateState{
+		templates:   e.store.templates,
+		compiledAST: e.compiledAST,
+		usage:       e.usage,
+		files:       e.files,
+		fileOf:      e.fileOf,
+		includes:    e.includes,
+		loadedAt:    e.loadedAt,
+	}
+	e.store.templates = cloneMap(prev.templates)
+	e.compiledAST = cloneMap(prev.compiledAST)
+	e.usage = cloneMap(prev.usage)
+	e.files = cloneMap(prev.files)
+	e.fileOf = cloneMap(prev.fileOf)
+	e.includes = cloneMap(prev.includes)
+	return prev
+}
+
+// restoreState 丢弃副本上的修改，恢复 stageState 之前的状态（调用方持有写锁）
+func (e *Engine) restoreState(prev templateState) {
+	e.store.templates = prev.templates
+	e.compiledAST = prev.compiledAST
+	e.usage = prev.usage
+	e.files = prev.files
+	e.fileOf = prev.fileOf
+	e.includes = prev.includes
+	e.loadedAt = prev.loadedAt
+	e.parseCache.retain(e.compiledAST)
+	// 副本加载时按新的模板集合重新汇总了引用信息和常驻模板，按恢复后的模板重新计算
+	e.linkTemplates()
+}
+
+// cloneMap 浅复制 map
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	clone := make(map[K]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// scanStamps 返回目录下所有 .md 文件（绝对路径）的修改时间和大小
+func scanStamps(dir string) (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !matchPatterns(nil, filepath.ToSlash(p)) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stamps[p] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return stamps, err
+}